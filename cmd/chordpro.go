@@ -25,13 +25,49 @@ const (
 	FrontmatterOverwrite = "overwrite"
 )
 
+const (
+	MultiError = "error"
+	MultiSplit = "split"
+	MultiAll   = "all"
+)
+
+// FormatHTML is the name of the default output format.
+const FormatHTML = "html"
+
+// FormatStandalone is the name of the html format written as a complete document.
+const FormatStandalone = "standalone"
+
+// FormatTemplate is the name of the html format written by templates.
+const FormatTemplate = "template"
+
 type Options struct {
-	Input       string // source file / folder
-	Output      string // destination file / folder
-	Overwrite   string // overwrite mode: "none", "old" or "all"
-	Frontmatter string // front matter mode: "none", "preserve", "overwrite"
-	Recursive   bool   // recursively transforms every chord file found in the input folder
-	Index       bool   // recursively creates "_index.md" files for folders (only for recursive mode)
+	Input       string  // source file / folder
+	Output      string  // destination file / folder
+	Overwrite   string  // overwrite mode: "none", "old" or "all"
+	Frontmatter string  // front matter mode: "none", "preserve", "overwrite"
+	Multi       string  // multi songs mode: "error", "split", "all"
+	Format      string  // output format: one of chordpro.FormatterNames()
+	From        string  // input format: one of chordpro.ImporterNames()
+	Width       int     // wrap width of the text formats (0 means no wrap)
+	Columns     int     // columns of the pages of the pdf format (0 means the default)
+	FontSize    float64 // font size of the pdf format (0 means the default)
+	Font        string  // TrueType font file embedded in the pdf format
+	BoldFont    string  // TrueType font file of the chords and titles of the pdf format
+	Template    string  // folder of the user templates of the html output
+	Standalone  bool    // html output as a complete document
+	Theme       string  // theme of the standalone html output
+	CSS         string  // custom stylesheet file of the standalone html output
+	Title       string  // title of the songbook
+	Addr        string  // address of the preview server
+	Recursive   bool    // recursively transforms every chord file found in the input folder
+	Index       bool    // recursively creates "_index.md" files for folders (only for recursive mode)
+	Watch       bool    // keeps transforming the changed files (only for recursive mode)
+	Jobs        int     // number of files transformed concurrently, 0 for the number of CPUs
+	Force       bool    // transforms all the files, whatever the overwrite mode
+	DryRun      bool    // prints what would be done for each file, without writing
+	Yes         bool    // removes the files without asking for confirmation
+	JSON        bool    // prints the lint diagnostics as json
+	FailOn      string  // lowest severity of the lint diagnostics that fails
 	Hugo        bool
 }
 
@@ -53,6 +89,15 @@ const (
 	modeFrontmatterOverwrite
 )
 
+// internal multi songs values
+type multiMode int
+
+const (
+	modeMultiError multiMode = iota
+	modeMultiSplit
+	modeMultiAll
+)
+
 // Error messages
 var (
 	// ErrInvalidOverwrite is returned when overwrite string is not valid.
@@ -61,6 +106,9 @@ var (
 	// ErrInvalidFrontmatter is returned when frontmatter string is not valid.
 	ErrInvalidFrontmatter = errors.New("invalid frontmatter")
 
+	// ErrInvalidMulti is returned when multi string is not valid.
+	ErrInvalidMulti = errors.New("invalid multi")
+
 	// ErrMissingInput is returned when input file is not specified.
 	ErrMissingInput = errors.New("missing input path")
 
@@ -74,15 +122,21 @@ var (
 	// and overwrite option was not given.
 	ErrOutputFileExists = errors.New("output file already exists")
 
-	// ErrOutputFileNewer is returned where output file already exists
-	// and is newer then input file.
-	ErrOutputFileNewer = errors.New("output file newer than chordpro input file")
+	// ErrOutputFileUnchanged is returned where output file is up to date
+	// with the chordpro input file, the options and the tool version.
+	ErrOutputFileUnchanged = errors.New("output file up to date")
 
 	// ErrZeroSongs is returned when chordpro file does not contain songs.
 	ErrZeroSongs = errors.New("no song found")
 
 	// ErrMultipleSongs is returned when chordpro file contains two or more songs.
 	ErrMultipleSongs = errors.New("multiple songs found")
+
+	// ErrStandaloneFormat is returned when the standalone mode is given for a format other than html.
+	ErrStandaloneFormat = errors.New("standalone mode is supported only by the html format")
+
+	// ErrTemplateFormat is returned when the templates are given for a format other than html.
+	ErrTemplateFormat = errors.New("templates are supported only by the html format")
 )
 
 // parseOverwrite function parses a string into overwriteMode.
@@ -113,11 +167,27 @@ func parseFrontmatter(s string) (frontmatterMode, error) {
 	return modeFrontmatterNone, ErrInvalidFrontmatter
 }
 
+// parseMulti function parses a string into multiMode.
+// An empty string is the default "error" mode.
+// It returns an error in case of unknown input string.
+func parseMulti(s string) (multiMode, error) {
+	switch strings.ToLower(s) {
+	case "", MultiError:
+		return modeMultiError, nil
+	case MultiSplit:
+		return modeMultiSplit, nil
+	case MultiAll:
+		return modeMultiAll, nil
+	}
+	return modeMultiError, ErrInvalidMulti
+}
+
 // checkFiles function checks if input and output are valid files
 // for the given overwrite mode.
+// In the "old" overwrite mode the output file is not checked:
+// the build manifest decides if it is out of date, see buildJob.
 func checkFiles(fin, fout string, overwrite overwriteMode) error {
 	var inFileinfo os.FileInfo
-	var outFileinfo os.FileInfo
 	var err error
 
 	// check input file
@@ -132,24 +202,14 @@ func checkFiles(fin, fout string, overwrite overwriteMode) error {
 		return ErrInputFileNotRegular
 	}
 
-	if (fout == "") || (overwrite == modeOverwriteAll) {
+	if (fout == "") || (overwrite != modeOverwriteNone) {
 		// don't check output file
 		return nil
 	}
 
 	// check if output file already exists
-	outFileinfo, err = os.Stat(fout)
-	if !os.IsNotExist(err) {
-		// file esists
-		if overwrite == modeOverwriteNone {
-			return ErrOutputFileExists
-		}
-
-		// case modeOverwriteOld
-		// check time
-		if outFileinfo.ModTime().After(inFileinfo.ModTime()) {
-			return ErrOutputFileNewer
-		}
+	if _, err = os.Stat(fout); !os.IsNotExist(err) {
+		return ErrOutputFileExists
 	}
 
 	return nil
@@ -189,6 +249,16 @@ func checkDirs(fin, fout string) error {
 	return nil
 }
 
+// isChordProFile function reports whether the path has
+// one of the extensions of the chordpro files.
+func isChordProFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".cho", ".chopro", ".chordpro":
+		return true
+	}
+	return false
+}
+
 // createFileAll creates or truncates the named file
 // along with any necessary parents directory.
 func createFileAll(pathname string) (*os.File, error) {
@@ -215,12 +285,104 @@ func toUtf8(iso8859_1_buf []byte) string {
 	return string(buf)
 }
 
+// newFormatter function returns the formatter of the format
+// given in the options, configured with the other options.
+// An empty format selects the default html format.
+// In standalone mode, the html formatter is wrapped
+// to write a complete document.
+func newFormatter(opts *Options) (chordpro.Formatter, error) {
+	if strings.EqualFold(opts.Format, FormatStandalone) {
+		o := *opts
+		o.Format = FormatHTML
+		o.Standalone = true
+		opts = &o
+	}
+	f, err := newBodyFormatter(opts)
+	if err != nil || !opts.Standalone {
+		return f, err
+	}
+	if f.MimeType() != "text/html" {
+		return nil, fmt.Errorf("%w: %q", ErrStandaloneFormat, opts.Format)
+	}
+	if _, err := chordpro.ThemeCSS(opts.Theme); err != nil {
+		return nil, fmt.Errorf("%w: %q (valid themes: %s)", err, opts.Theme, strings.Join(chordpro.ThemeNames(), ", "))
+	}
+
+	sf := &chordpro.StandaloneFormatter{Body: f, Theme: opts.Theme}
+	if opts.CSS != "" {
+		data, err := ioutil.ReadFile(opts.CSS)
+		if err != nil {
+			return nil, err
+		}
+		sf.CSS = string(data)
+	}
+	return sf, nil
+}
+
+// newBodyFormatter function returns the formatter of the format
+// given in the options, without the standalone wrapper.
+func newBodyFormatter(opts *Options) (chordpro.Formatter, error) {
+	name := opts.Format
+	if name == "" {
+		name = FormatHTML
+	}
+	if opts.Template != "" {
+		// the user templates replace the html format
+		switch strings.ToLower(name) {
+		case FormatHTML, FormatTemplate:
+		default:
+			return nil, fmt.Errorf("%w: %q", ErrTemplateFormat, name)
+		}
+		tf := &chordpro.TemplateFormatter{Dir: opts.Template}
+		if err := tf.Load(); err != nil {
+			return nil, err
+		}
+		return tf, nil
+	}
+	f, err := chordpro.NewFormatter(name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q (valid formats: %s)", err, name, strings.Join(chordpro.FormatterNames(), ", "))
+	}
+
+	switch tf := f.(type) {
+	case *chordpro.TextFormatter:
+		tf.Width = opts.Width
+	case *chordpro.MarkdownFormatter:
+		tf.Width = opts.Width
+	case *chordpro.PdfFormatter:
+		if opts.Columns > 0 {
+			tf.Columns = opts.Columns
+		}
+		if opts.FontSize > 0 {
+			tf.FontSize = opts.FontSize
+		}
+		tf.FontFile = opts.Font
+		tf.BoldFontFile = opts.BoldFont
+	}
+	return f, nil
+}
+
+// hasFrontmatter function reports whether the output of the formatter
+// can begin with a front matter.
+func hasFrontmatter(formatter chordpro.Formatter) bool {
+	switch formatter.(type) {
+	case *chordpro.StandaloneFormatter, *chordpro.SongbookFormatter:
+		// a complete document can't begin with a front matter
+		return false
+	}
+	switch formatter.MimeType() {
+	case "text/html", "text/markdown":
+		return true
+	}
+	return false
+}
+
 // transform function parse a chordpro.Songs object from io.Reader and output the resut to io.Writer.
 // It returns an error if the number of songs is not exactly one.
 // If the flag songFrontmatter is true, the first part of the result is the front matter created from the song metadata.
 // Then it prints the given prefix.
-// At last it prints the formatted song.
-func transform(r io.Reader, w io.Writer, prefix string, songFrontmatter bool) error {
+// At last it prints the song formatted by the formatter.
+func transform(r io.Reader, w io.Writer, formatter chordpro.Formatter, prefix string, songFrontmatter bool) error {
 
 	// retrieve from reader
 	data, err := ioutil.ReadAll(r)
@@ -238,36 +400,47 @@ func transform(r io.Reader, w io.Writer, prefix string, songFrontmatter bool) er
 		return ErrMultipleSongs
 	}
 
-	// format the first song, discard the others
-	formatter := chordpro.NewHtmlDivFormatter(w)
-	song := songs[0]
+	return writeSongs(w, formatter, songs, prefix, songFrontmatter)
+}
 
+// writeSongs function prints the front matter of the first song, if songFrontmatter is true,
+// then the given prefix, and at last the songs formatted by the formatter in sequence.
+func writeSongs(w io.Writer, formatter chordpro.Formatter, songs chordpro.Songs, prefix string, songFrontmatter bool) error {
 	if songFrontmatter {
-		appendFrontMatter(w, song)
+		appendFrontMatter(w, songs[0])
 	}
 	w.Write([]byte(prefix))
-	formatter.FormatBody(song)
 
-	return nil
+	if len(songs) == 1 {
+		return formatter.FormatSong(w, songs[0])
+	}
+	return formatter.FormatSongs(w, songs)
 }
 
-// trasformFile dunction transforms the ChordPro source file
-// into the HTML destination file.
-func trasformFile(srcFile, dstFile string, overwrite overwriteMode, frontmatter frontmatterMode) error {
-	var saveFrontMatter string
-
-	err := checkFiles(srcFile, dstFile, overwrite)
-	if err != nil {
-		return err
+// readSongs function parses the songs of the chordpro source
+// and checks their number for the multi songs mode.
+// It returns ErrZeroSongs if the source does not contain songs,
+// and ErrMultipleSongs if it contains two or more songs in the "error" mode.
+func readSongs(src string, multi multiMode) (chordpro.Songs, error) {
+	songs := chordpro.ParseText(src)
+	if len(songs) == 0 {
+		return nil, ErrZeroSongs
 	}
-
-	// reader
-	fin, err := os.Open(srcFile)
-	if err != nil {
-		return err
+	if len(songs) > 1 && multi == modeMultiError {
+		return nil, ErrMultipleSongs
 	}
+	return songs, nil
+}
+
+// writeSongsFile function writes the songs to the destination file,
+// or to the standard output if no destination file is given.
+// With the preserve front matter mode, the front matter of the existing
+// destination file is kept; otherwise, if the frontmatter mode is not none,
+// the front matter is created from the metadata of the first song.
+func writeSongsFile(dstFile string, songs chordpro.Songs, frontmatter frontmatterMode, formatter chordpro.Formatter) error {
+	var saveFrontMatter string
+	var err error
 
-	// writer
 	fout := os.Stdout
 	if dstFile != "" {
 		if frontmatter == modeFrontmatterPreserve {
@@ -283,12 +456,81 @@ func trasformFile(srcFile, dstFile string, overwrite overwriteMode, frontmatter
 	writer := bufio.NewWriter(fout)
 
 	songFrontmatter := (frontmatter != modeFrontmatterNone) && (saveFrontMatter == "")
-	err = transform(fin, writer, saveFrontMatter, songFrontmatter)
-	writer.Flush()
-
+	err = writeSongs(writer, formatter, songs, saveFrontMatter, songFrontmatter)
+	if err2 := writer.Flush(); err == nil {
+		err = err2
+	}
 	return err
 }
 
+// splitSongs function writes each song to its own destination file,
+// named after the destination file of the source by splitFileName.
+// Every song is written even if another fails,
+// and the first error is returned.
+func splitSongs(srcFile, dstFile string, songs chordpro.Songs, overwrite overwriteMode, frontmatter frontmatterMode, formatter chordpro.Formatter) error {
+	var firstErr error
+
+	names := splitFileNames(dstFile, formatter.Extension(), songs)
+	for j, s := range songs {
+		err := checkFiles(srcFile, names[j], overwrite)
+		if err == nil {
+			err = writeSongsFile(names[j], chordpro.Songs{s}, frontmatter, formatter)
+		}
+		if err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("%s: %w", names[j], err)
+		}
+	}
+	return firstErr
+}
+
+// isSongbook function reports whether the formatter writes
+// all the songs in a single document, whatever the multi songs mode.
+func isSongbook(formatter chordpro.Formatter) bool {
+	_, ok := formatter.(*chordpro.SongbookFormatter)
+	return ok
+}
+
+// trasformFile dunction transforms the ChordPro source file
+// into the destination file, using the given formatter.
+// A source file with many songs is handled by the multi songs mode:
+// it is an error, each song is written to its own file,
+// or all the songs are written in sequence.
+func trasformFile(srcFile, dstFile string, overwrite overwriteMode, frontmatter frontmatterMode, multi multiMode, formatter chordpro.Formatter) error {
+
+	if !hasFrontmatter(formatter) {
+		frontmatter = modeFrontmatterNone
+	}
+	if isSongbook(formatter) {
+		multi = modeMultiAll
+	}
+
+	// check the input file only:
+	// the output files depend on the songs in split mode
+	err := checkFiles(srcFile, "", overwrite)
+	if err != nil {
+		return err
+	}
+
+	data, err := ioutil.ReadFile(srcFile)
+	if err != nil {
+		return err
+	}
+	songs, err := readSongs(toUtf8(data), multi)
+	if err != nil {
+		return err
+	}
+
+	if len(songs) > 1 && multi == modeMultiSplit && dstFile != "" {
+		return splitSongs(srcFile, dstFile, songs, overwrite, frontmatter, formatter)
+	}
+
+	err = checkFiles(srcFile, dstFile, overwrite)
+	if err != nil {
+		return err
+	}
+	return writeSongsFile(dstFile, songs, frontmatter, formatter)
+}
+
 // createIndexMD function recursively creates an "_index.md" file
 // in each sub folder of root folder, if not already exists.
 // The frontmatter of the created "_index.md" file contains only the "title" key
@@ -334,43 +576,86 @@ func Run(opts *Options) error {
 		return err
 	}
 
-	if !opts.Recursive {
-		// single file mode
-		return trasformFile(opts.Input, opts.Output, overwrite, frontmatter)
+	multi, err := parseMulti(opts.Multi)
+	if err != nil {
+		return err
 	}
 
-	err = checkDirs(opts.Input, opts.Output)
+	formatter, err := newFormatter(opts)
 	if err != nil {
 		return err
 	}
 
-	// recursively transforms all chordpro files under the input dir
-	err = filepath.Walk(opts.Input,
-		func(path string, info os.FileInfo, err error) error {
-			if err != nil {
+	if !opts.Recursive {
+		// single file mode
+		outputs := func() []string {
+			return outputFiles(opts.Input, opts.Output, multi, formatter)
+		}
+		if opts.DryRun {
+			if err := checkFiles(opts.Input, "", overwrite); err != nil {
 				return err
 			}
+			plans := planFile(opts, outputs, overwrite, func(overwrite overwriteMode) []*planEntry {
+				return planTransform(opts.Input, opts.Output, overwrite, frontmatter, multi, formatter, false)
+			})
+			for _, e := range plans {
+				fmt.Println(e)
+			}
+			return nil
+		}
+		return buildFile(opts, outputs, overwrite, func(overwrite overwriteMode) error {
+			return trasformFile(opts.Input, opts.Output, overwrite, frontmatter, multi, formatter)
+		})
+	}
 
-			relpath, _ := filepath.Rel(opts.Input, path)
+	err = checkDirs(opts.Input, opts.Output)
+	if err != nil {
+		return err
+	}
 
-			dstpath := filepath.Join(opts.Output, relpath) + ".html"
+	if opts.DryRun {
+		return planFolder(os.Stdout, opts, overwrite, frontmatter, multi, formatter, false)
+	}
 
-			if !info.IsDir() {
-				ext := filepath.Ext(path)
-				switch strings.ToLower(ext) {
-				case ".cho", ".chopro", ".chordpro":
-					fmt.Println(relpath)
-					err = trasformFile(path, dstpath, overwrite, frontmatter)
-					if err != nil {
-						fmt.Fprintln(os.Stderr, err)
-					}
-				}
-			}
+	manifest := loadManifest(opts.Output)
+	optsHash := optionsHash(opts)
+
+	// recursively transforms all chordpro files under the input dir
+	err = runPipeline(opts.Input, opts.Jobs, func(path, relpath string) func() error {
+		if !isChordProFile(path) {
 			return nil
+		}
+		dstpath := filepath.Join(opts.Output, relpath) + formatter.Extension()
+		outputs := func() []string {
+			return outputFiles(path, dstpath, multi, formatter)
+		}
+		return buildJob(manifest, path, relpath, optsHash, outputs, overwrite, opts.Force, func(overwrite overwriteMode) error {
+			return trasformFile(path, dstpath, overwrite, frontmatter, multi, formatter)
 		})
+	})
+	// the manifest is written in every overwrite mode,
+	// so that the clean command knows the outputs of the folder
+	if err2 := manifest.save(); err2 != nil {
+		return err2
+	}
+
+	// the errors of the files don't stop the other steps
+	if _, ok := err.(FileErrors); err != nil && !ok {
+		return err
+	}
+
+	if opts.Index {
+		if err2 := createIndexMD(opts.Output); err2 != nil {
+			return err2
+		}
+	}
 
-	if (err == nil) && opts.Index {
-		err = createIndexMD(opts.Output)
+	if opts.Watch {
+		fw, err := newFolderWatcher(opts, frontmatter, multi, formatter, false, manifest)
+		if err != nil {
+			return err
+		}
+		return fw.watch()
 	}
 
 	return err
@@ -1,10 +1,13 @@
 package cmd
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/mmbros/chordpro/pkg/chordpro"
 )
 
 func Test_parseFrontmatter(t *testing.T) {
@@ -107,6 +110,30 @@ func Test_parseOverwrite(t *testing.T) {
 	}
 }
 
+func Test_parseMulti(t *testing.T) {
+	tests := []struct {
+		input string
+		want  multiMode
+		err   error
+	}{
+		{input: "", want: modeMultiError},
+		{input: "error", want: modeMultiError},
+		{input: "Split", want: modeMultiSplit},
+		{input: "ALL", want: modeMultiAll},
+		{input: "xxx", err: ErrInvalidMulti},
+	}
+	for _, tt := range tests {
+		got, err := parseMulti(tt.input)
+		if err != tt.err {
+			t.Errorf("%q: expected %v error, got %v error", tt.input, tt.err, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%q: expected %v, got %v", tt.input, tt.want, got)
+		}
+	}
+}
+
 func Test_Walk(t *testing.T) {
 
 	src := "../test/"
@@ -210,7 +237,7 @@ title: "titolo"
 			r := strings.NewReader(tt.input)
 			w := &strings.Builder{}
 
-			err := transform(r, w, tt.prefix, tt.frontmatter)
+			err := transform(r, w, chordpro.HtmlFormatter{}, tt.prefix, tt.frontmatter)
 			if tt.err != nil {
 				if tt.err != err {
 					t.Errorf("expected %q error, got %q error", tt.err, err)
@@ -229,3 +256,39 @@ title: "titolo"
 		})
 	}
 }
+
+func Test_newFormatter(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        Options
+		frontmatter bool
+		err         error
+	}{
+		{name: "html", opts: Options{}, frontmatter: true},
+		{name: "standalone", opts: Options{Standalone: true}},
+		{name: "format-standalone", opts: Options{Format: FormatStandalone}},
+		{name: "standalone-template", opts: Options{Standalone: true, Format: FormatTemplate}},
+		{name: "standalone-text", opts: Options{Standalone: true, Format: "text"}, err: ErrStandaloneFormat},
+		{name: "standalone-theme", opts: Options{Standalone: true, Theme: "xxx"}, err: chordpro.ErrUnknownTheme},
+		{name: "template-text", opts: Options{Template: ".", Format: "text"}, err: ErrTemplateFormat},
+		{name: "songbook", opts: Options{Format: "songbook"}},
+		{name: "unknown", opts: Options{Format: "xxx"}, err: chordpro.ErrUnknownFormat},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newFormatter(&tt.opts)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Errorf("expected %q error, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error %q", err.Error())
+			}
+			if fm := hasFrontmatter(got); fm != tt.frontmatter {
+				t.Errorf("expected frontmatter %v, got %v", tt.frontmatter, fm)
+			}
+		})
+	}
+}
@@ -0,0 +1,213 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// indexMDName is the name of the folder index files of hugo.
+const indexMDName = "_index.md"
+
+// isGeneratedIndexMD function reports whether the file is an "_index.md" file
+// as created by the index option, and not edited since.
+func isGeneratedIndexMD(path string) bool {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	name := filepath.Base(filepath.Dir(path))
+	return string(data) == fmt.Sprintf("---\ntitle: %q\n---\n", name)
+}
+
+// removeIndexMD function removes the generated "_index.md" file of the folder,
+// if it is the only file left in it, as the clean command does
+// with the Index option.
+func removeIndexMD(dir string) error {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil || len(infos) != 1 || infos[0].Name() != indexMDName {
+		return err
+	}
+	index := filepath.Join(dir, indexMDName)
+	if !isGeneratedIndexMD(index) {
+		return nil
+	}
+	return os.Remove(index)
+}
+
+// goneSources function returns the sorted source files recorded
+// in the build manifest that no longer exist in the input folder.
+// The sources outside the input folder, like the ones of the single file
+// commands, are ignored.
+func goneSources(input string, m *buildManifest) []string {
+	var gone []string
+	for src := range m.Files {
+		if src == ".." || strings.HasPrefix(src, "../") || filepath.IsAbs(filepath.FromSlash(src)) {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(input, filepath.FromSlash(src))); os.IsNotExist(err) {
+			gone = append(gone, src)
+		}
+	}
+	sort.Strings(gone)
+	return gone
+}
+
+// orphanFiles function returns the sorted output files of the output folder
+// whose source no longer exists in the input folder:
+// the outputs recorded in the build manifest for the gone sources,
+// including the split pages and the copied files of the hugo command.
+// The other files of the output folder are never orphans.
+func orphanFiles(input, output string, m *buildManifest) []string {
+	var orphans []string
+	for _, src := range goneSources(input, m) {
+		for _, rel := range m.Files[src].Outputs {
+			path := filepath.Join(output, filepath.FromSlash(rel))
+			if info, err := os.Stat(path); err == nil && !info.IsDir() {
+				orphans = append(orphans, path)
+			}
+		}
+	}
+	sort.Strings(orphans)
+	return orphans
+}
+
+// emptyIndexFiles function returns the generated "_index.md" files
+// of the folders of the output folder that would be left with no other file,
+// once the removed files are removed.
+// The sub folders come before their parents.
+func emptyIndexFiles(output string, removed []string) ([]string, error) {
+	gone := map[string]bool{}
+	for _, path := range removed {
+		gone[path] = true
+	}
+
+	// the folders, sub folders first
+	var dirs []string
+	err := filepath.Walk(output,
+		func(path string, info os.FileInfo, err error) error {
+			if err != nil || !info.IsDir() || path == output {
+				return err
+			}
+			dirs = append(dirs, path)
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(dirs)))
+
+	var indexes []string
+	for _, dir := range dirs {
+		infos, err := ioutil.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		empty := true
+		for _, info := range infos {
+			path := filepath.Join(dir, info.Name())
+			if !gone[path] && info.Name() != indexMDName {
+				empty = false
+				break
+			}
+		}
+		index := filepath.Join(dir, indexMDName)
+		if empty && isGeneratedIndexMD(index) {
+			indexes = append(indexes, index)
+			gone[index] = true
+			gone[dir] = true
+		}
+	}
+	return indexes, nil
+}
+
+// confirm function asks the question and reports whether the answer is yes.
+func confirm(in io.Reader, out io.Writer, question string) bool {
+	fmt.Fprintf(out, "%s [y/N] ", question)
+	answer, _ := bufio.NewReader(in).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	}
+	return false
+}
+
+// clean function removes the orphan files of the output folder,
+// printing them to out, after the confirmation read from in.
+func clean(opts *Options, in io.Reader, out io.Writer) error {
+	err := checkDirs(opts.Input, opts.Output)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(opts.Output); os.IsNotExist(err) {
+		return nil
+	}
+
+	m := loadManifest(opts.Output)
+	gone := goneSources(opts.Input, m)
+	files := orphanFiles(opts.Input, opts.Output, m)
+	if opts.Index {
+		indexes, err := emptyIndexFiles(opts.Output, files)
+		if err != nil {
+			return err
+		}
+		files = append(files, indexes...)
+	}
+
+	if len(files) == 0 {
+		fmt.Fprintln(out, "nothing to clean")
+		return nil
+	}
+	for _, path := range files {
+		rel, _ := filepath.Rel(opts.Output, path)
+		fmt.Fprintln(out, rel)
+	}
+	if opts.DryRun {
+		return nil
+	}
+	if !opts.Yes && !confirm(in, out, fmt.Sprintf("remove %d files?", len(files))) {
+		return nil
+	}
+
+	root := filepath.Clean(opts.Output)
+	var dirs []string
+	for _, path := range files {
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		dirs = append(dirs, filepath.Dir(path))
+	}
+
+	// remove the folders left empty, sub folders first
+	sort.Sort(sort.Reverse(sort.StringSlice(dirs)))
+	for _, dir := range dirs {
+		for dir != root && os.Remove(dir) == nil {
+			dir = filepath.Dir(dir)
+		}
+	}
+
+	// forget the removed sources
+	if len(gone) == 0 {
+		return nil
+	}
+	for _, src := range gone {
+		m.remove(src)
+	}
+	return m.save()
+}
+
+// Clean removes the outputs of the output folder whose source
+// no longer exists in the input folder, as recorded in the build manifest
+// of the output folder, after the confirmation of the user,
+// unless the Yes option is set.
+// With the Index option, the generated "_index.md" files of the folders
+// left empty are removed too.
+// With the DryRun option, the files are printed only.
+func Clean(opts *Options) error {
+	return clean(opts, os.Stdin, os.Stdout)
+}
@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_clean(t *testing.T) {
+	tests := []struct {
+		name    string
+		index   bool
+		dryRun  bool
+		yes     bool
+		answer  string
+		removed []string
+	}{
+		{name: "dry run", dryRun: true},
+		{name: "no", answer: "n\n"},
+		{name: "confirm", answer: "y\n", removed: []string{"old.cho.html", "old.cho-two.html", "old.jpg", "gone/b.cho.html", "edited/c.cho.html"}},
+		{name: "yes", yes: true, removed: []string{"old.cho.html", "old.cho-two.html", "old.jpg", "gone/b.cho.html", "edited/c.cho.html"}},
+		{
+			name: "index", yes: true, index: true,
+			removed: []string{"old.cho.html", "old.cho-two.html", "old.jpg", "gone/b.cho.html", "edited/c.cho.html", "gone/_index.md"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "clean")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+
+			in := filepath.Join(dir, "in")
+			out := filepath.Join(dir, "out")
+			files := map[string]string{
+				"in/a.cho":                  "{title: A}",
+				"in/medley.cho":             "{title: One}\n{new_song}\n{title: Three}",
+				"out/a.cho.html":            "",
+				"out/old.cho.html":          "",
+				"out/old.cho-two.html":      "",
+				"out/stray.cho.html":        "",
+				"out/notes.html":            "",
+				"out/medley.cho-one.txt":    "",
+				"out/medley.cho-two.txt":    "",
+				"out/medley.cho-three.html": "",
+				"out/old.jpg":               "",
+				"out/gone/b.cho.html":       "",
+				"out/gone/_index.md":        "---\ntitle: \"gone\"\n---\n",
+				"out/edited/_index.md":      "---\ntitle: Edited\n---\n",
+				"out/edited/c.cho.html":     "",
+			}
+			for name, content := range files {
+				path := filepath.Join(dir, filepath.FromSlash(name))
+				if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+					t.Fatal(err)
+				}
+				if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			// the outputs of the other formats, and the ones of the existing
+			// sources, are kept; the recorded outputs already removed are ignored
+			m := loadManifest(out)
+			record := func(src string, outputs ...string) {
+				for j, o := range outputs {
+					outputs[j] = filepath.Join(out, filepath.FromSlash(o))
+				}
+				m.record(src, "i", "o", outputs)
+			}
+			record("a.cho", "a.cho.html")
+			record("medley.cho", "medley.cho-one.txt", "medley.cho-two.txt")
+			record("old.cho", "old.cho.html", "old.cho-two.html", "old.cho-three.html")
+			record("old.jpg", "old.jpg")
+			record("gone/b.cho", "gone/b.cho.html")
+			record("edited/c.cho", "edited/c.cho.html")
+			record("../single.cho", "single.cho.html")
+			if err := m.save(); err != nil {
+				t.Fatal(err)
+			}
+
+			opts := &Options{Input: in, Output: out, Index: tt.index, DryRun: tt.dryRun, Yes: tt.yes}
+			var buf bytes.Buffer
+			if err := clean(opts, strings.NewReader(tt.answer), &buf); err != nil {
+				t.Fatal(err)
+			}
+
+			var removed []string
+			for name := range files {
+				if !strings.HasPrefix(name, "out/") {
+					continue
+				}
+				rel := strings.TrimPrefix(name, "out/")
+				if _, err := os.Stat(filepath.Join(out, filepath.FromSlash(rel))); os.IsNotExist(err) {
+					removed = append(removed, rel)
+				}
+			}
+			if !sameStrings(removed, tt.removed) {
+				t.Errorf("expected removed %v, got %v", tt.removed, removed)
+			}
+			if tt.index {
+				if _, err := os.Stat(filepath.Join(out, "gone")); !os.IsNotExist(err) {
+					t.Errorf("expected empty folder removed, got %v", err)
+				}
+			}
+			if len(tt.removed) > 0 {
+				files := loadManifest(out).Files
+				if _, ok := files["old.jpg"]; ok {
+					t.Errorf("expected removed source forgotten by the manifest")
+				}
+				for _, src := range []string{"a.cho", "medley.cho", "../single.cho"} {
+					if _, ok := files[src]; !ok {
+						t.Errorf("expected %q kept by the manifest", src)
+					}
+				}
+			}
+		})
+	}
+}
+
+// sameStrings function reports whether the slices have the same strings,
+// whatever their order.
+func sameStrings(a, b []string) bool {
+	set := func(s []string) map[string]bool {
+		m := map[string]bool{}
+		for _, x := range s {
+			m[x] = true
+		}
+		return m
+	}
+	return len(a) == len(b) && reflect.DeepEqual(set(a), set(b))
+}
+
+func Test_clean_afterTransform(t *testing.T) {
+	dir, err := ioutil.TempDir("", "clean")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	in := filepath.Join(dir, "in")
+	out := filepath.Join(dir, "out")
+	if err := os.MkdirAll(in, 0700); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a.cho", "b.cho"} {
+		if err := ioutil.WriteFile(filepath.Join(in, name), []byte("{title: A}"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the default overwrite mode writes the manifest too
+	opts := &Options{Input: in, Output: out, Format: "html", Overwrite: OverwriteNone, Frontmatter: "none", Recursive: true, Jobs: 1}
+	if err := Run(opts); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(in, "b.cho")); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := clean(&Options{Input: in, Output: out, Yes: true}, strings.NewReader(""), &buf); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(out, "b.cho.html")); !os.IsNotExist(err) {
+		t.Errorf("expected %q removed, got %v", "b.cho.html", err)
+	}
+	if _, err := os.Stat(filepath.Join(out, "a.cho.html")); err != nil {
+		t.Errorf("expected %q kept, got %v", "a.cho.html", err)
+	}
+}
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/mmbros/chordpro/pkg/chordpro"
+)
+
+// FormatChordPro is the name of the default convert format.
+const FormatChordPro = "chordpro"
+
+// convert function reads the songs of the input file with the importer
+// and writes them with the formatter to the output file,
+// or to the standard output if no output file is given.
+func convert(opts *Options, importer chordpro.Importer, formatter chordpro.Formatter) error {
+	overwrite, err := parseOverwrite(opts.Overwrite)
+	if err != nil {
+		return err
+	}
+
+	outputs := func() []string { return []string{opts.Output} }
+	return buildFile(opts, outputs, overwrite, func(overwrite overwriteMode) error {
+		return convertFile(opts.Input, opts.Output, overwrite, importer, formatter)
+	})
+}
+
+// convertFile function converts the songs of the input file
+// to the output file, for the given overwrite mode.
+func convertFile(input, output string, overwrite overwriteMode, importer chordpro.Importer, formatter chordpro.Formatter) error {
+	err := checkFiles(input, output, overwrite)
+	if err != nil {
+		return err
+	}
+
+	data, err := ioutil.ReadFile(input)
+	if err != nil {
+		return err
+	}
+	src := string(data)
+	if _, ok := importer.(chordpro.ChordProImporter); ok {
+		// chordpro files are read as the other commands do
+		src = toUtf8(data)
+	}
+
+	songs, err := importer.Import(strings.NewReader(src))
+	if err != nil {
+		return err
+	}
+	if len(songs) == 0 {
+		return ErrZeroSongs
+	}
+
+	// writer
+	fout := os.Stdout
+	if output != "" {
+		fout, err = createFileAll(output)
+		if err != nil {
+			return err
+		}
+		defer fout.Close()
+	}
+	writer := bufio.NewWriter(fout)
+
+	err = formatter.FormatSongs(writer, songs)
+	if err2 := writer.Flush(); err == nil {
+		err = err2
+	}
+	return err
+}
+
+// Convert converts the songs of the input file from the format
+// given by opts.From to the format given by opts.Format,
+// through the song model. Both the formats default to chordpro.
+func Convert(opts *Options) error {
+	o := *opts
+	if o.From == "" {
+		o.From = FormatChordPro
+	}
+	if o.Format == "" {
+		o.Format = FormatChordPro
+	}
+
+	importer, err := newImporter(&o)
+	if err != nil {
+		return err
+	}
+	formatter, err := newFormatter(&o)
+	if err != nil {
+		return err
+	}
+	return convert(&o, importer, formatter)
+}
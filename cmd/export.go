@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+
+	"github.com/mmbros/chordpro/pkg/chordpro"
+)
+
+// FormatJSON is the name of the default export format.
+const FormatJSON = "json"
+
+// Export exports all the songs of the input file to the output file,
+// or to the standard output if no output file is given.
+// The songs are written as a single document of the format given by the options.
+func Export(opts *Options) error {
+	overwrite, err := parseOverwrite(opts.Overwrite)
+	if err != nil {
+		return err
+	}
+
+	formatter, err := newFormatter(opts)
+	if err != nil {
+		return err
+	}
+
+	outputs := func() []string { return []string{opts.Output} }
+	return buildFile(opts, outputs, overwrite, func(overwrite overwriteMode) error {
+		return exportFile(opts.Input, opts.Output, overwrite, formatter)
+	})
+}
+
+// exportFile function exports all the songs of the input file
+// to the output file, for the given overwrite mode.
+func exportFile(input, output string, overwrite overwriteMode, formatter chordpro.Formatter) error {
+	err := checkFiles(input, output, overwrite)
+	if err != nil {
+		return err
+	}
+
+	data, err := ioutil.ReadFile(input)
+	if err != nil {
+		return err
+	}
+	songs := chordpro.ParseText(toUtf8(data))
+
+	// writer
+	fout := os.Stdout
+	if output != "" {
+		fout, err = createFileAll(output)
+		if err != nil {
+			return err
+		}
+		defer fout.Close()
+	}
+	writer := bufio.NewWriter(fout)
+
+	err = formatter.FormatSongs(writer, songs)
+	if err2 := writer.Flush(); err == nil {
+		err = err2
+	}
+	return err
+}
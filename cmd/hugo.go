@@ -2,40 +2,21 @@ package cmd
 
 import (
 	"bufio"
-	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
+
+	"github.com/mmbros/chordpro/pkg/chordpro"
 )
 
+// copyFile function copies the source file to the destination file,
+// checking them as the transform does for the given overwrite mode.
 func copyFile(srcFile, dstFile string, overwrite overwriteMode) error {
 
-	if overwrite != modeOverwriteAll {
-		// check if output file already exists
-		outFileinfo, err := os.Stat(dstFile)
-		if !os.IsNotExist(err) {
-			// file esists
-			if overwrite == modeOverwriteNone {
-				// skip
-				// return ErrOutputFileExists
-				return nil
-			}
-
-			// case modeOverwriteOld
-			// check time
-			inFileinfo, err2 := os.Stat(srcFile)
-			if err2 != nil {
-				return err2
-			}
-
-			if outFileinfo.ModTime().After(inFileinfo.ModTime()) {
-				// skip
-				// return ErrOutputFileNewer
-				return nil
-			}
-		}
-		// else file not exists
+	err := checkFiles(srcFile, dstFile, overwrite)
+	if err != nil {
+		return err
 	}
 
 	input, err := ioutil.ReadFile(srcFile)
@@ -46,11 +27,19 @@ func copyFile(srcFile, dstFile string, overwrite overwriteMode) error {
 	return err
 }
 
-// trasformFile dunction transforms the ChordPro source file
-// into the HTML destination file.
-func trasformFileHugo(srcFile, dstFile string, overwrite overwriteMode) error {
+// trasformFileHugo dunction transforms the ChordPro source file
+// into the Hugo content destination file, using the given formatter.
+// The front matter of the source file is kept in the destination file.
+// In split mode, each song of a source file with many songs
+// is written to its own content page, with the front matter
+// created from the song metadata.
+func trasformFileHugo(srcFile, dstFile string, overwrite overwriteMode, multi multiMode, formatter chordpro.Formatter) error {
 
-	err := checkFiles(srcFile, dstFile, overwrite)
+	if isSongbook(formatter) {
+		multi = modeMultiAll
+	}
+
+	err := checkFiles(srcFile, "", overwrite)
 	if err != nil {
 		return err
 	}
@@ -64,17 +53,6 @@ func trasformFileHugo(srcFile, dstFile string, overwrite overwriteMode) error {
 	// s := toUtf8(data)
 	s := string(data)
 
-	// writer
-	fout := os.Stdout
-	if dstFile != "" {
-		fout, err = createFileAll(dstFile)
-		if err != nil {
-			return err
-		}
-		defer fout.Close()
-	}
-	writer := bufio.NewWriter(fout)
-
 	saveFrontMatter := getFrontMatterFromReader(strings.NewReader(s))
 
 	L := len(saveFrontMatter)
@@ -86,15 +64,41 @@ func trasformFileHugo(srcFile, dstFile string, overwrite overwriteMode) error {
 	}
 	s = strings.TrimSpace(s)
 
-	songFrontmatter := (saveFrontMatter == "")
+	songs, err := readSongs(toUtf8([]byte(s)), multi)
+	if err != nil {
+		return err
+	}
 
-	// fmt.Println(saveFrontMatter)
-	// fmt.Println("###############################################################################")
+	frontmatter := modeFrontmatterNone
+	if hasFrontmatter(formatter) {
+		frontmatter = modeFrontmatterOverwrite
+	}
+	if len(songs) > 1 && multi == modeMultiSplit && dstFile != "" {
+		return splitSongs(srcFile, dstFile, songs, overwrite, frontmatter, formatter)
+	}
+
+	err = checkFiles(srcFile, dstFile, overwrite)
+	if err != nil {
+		return err
+	}
+
+	// writer
+	fout := os.Stdout
+	if dstFile != "" {
+		fout, err = createFileAll(dstFile)
+		if err != nil {
+			return err
+		}
+		defer fout.Close()
+	}
+	writer := bufio.NewWriter(fout)
 
-	// fmt.Println(s)
+	songFrontmatter := (saveFrontMatter == "") && (frontmatter != modeFrontmatterNone)
 
-	err = transform(strings.NewReader(s), writer, saveFrontMatter, songFrontmatter)
-	writer.Flush()
+	err = writeSongs(writer, formatter, songs, saveFrontMatter, songFrontmatter)
+	if err2 := writer.Flush(); err == nil {
+		err = err2
+	}
 
 	return err
 }
@@ -103,45 +107,83 @@ func trasformFileHugo(srcFile, dstFile string, overwrite overwriteMode) error {
 // based on the given options.
 func runHugo(opts *Options) error {
 
+	// the outputs are rebuilt when the sources, the options or the tool change
 	overwrite := modeOverwriteOld
 
-	err := checkDirs(opts.Input, opts.Output)
+	multi := modeMultiSplit
+	if opts.Multi != "" {
+		m, err := parseMulti(opts.Multi)
+		if err != nil {
+			return err
+		}
+		multi = m
+	}
+
+	formatter, err := newFormatter(opts)
 	if err != nil {
 		return err
 	}
 
-	// recursively transforms all chordpro files under the input dir
-	err = filepath.Walk(opts.Input,
-		func(path string, info os.FileInfo, err error) error {
-			if err != nil {
-				return err
-			}
+	err = checkDirs(opts.Input, opts.Output)
+	if err != nil {
+		return err
+	}
 
-			relpath, _ := filepath.Rel(opts.Input, path)
-
-			if !info.IsDir() {
-				ext := filepath.Ext(path)
-				switch strings.ToLower(ext) {
-				case ".cho", ".chopro", ".chordpro":
-					dstpath := filepath.Join(opts.Output, relpath) + ".html"
-					fmt.Println(relpath)
-					trasformFileHugo(path, dstpath, overwrite)
-					// if err != nil && err != ErrOutputFileNewer {
-					// 	return err
-					// }
-				default:
-					// copy
-					dstpath := filepath.Join(opts.Output, relpath)
-					err = copyFile(path, dstpath, overwrite)
-					if err != nil {
-						fmt.Fprintln(os.Stderr, err)
-					}
-					return err
+	if opts.DryRun {
+		return planFolder(os.Stdout, opts, overwrite, modeFrontmatterOverwrite, multi, formatter, true)
+	}
+
+	manifest := loadManifest(opts.Output)
+	optsHash := optionsHash(opts)
 
+	// recursively transforms all chordpro files under the input dir
+	err = runPipeline(opts.Input, opts.Jobs, func(path, relpath string) func() error {
+		var job func() error
+		if !isChordProFile(path) {
+			// copy
+			dstpath := filepath.Join(opts.Output, relpath)
+			outputs := func() []string { return []string{dstpath} }
+			job = buildJob(manifest, path, relpath, optsHash, outputs, overwrite, opts.Force, func(overwrite overwriteMode) error {
+				// the folder may not be created yet by the other jobs
+				if err := os.MkdirAll(filepath.Dir(dstpath), 0700); err != nil {
+					return err
 				}
+				return copyFile(path, dstpath, overwrite)
+			})
+		} else {
+			dstpath := filepath.Join(opts.Output, relpath) + formatter.Extension()
+			outputs := func() []string {
+				return outputFiles(path, dstpath, multi, formatter)
 			}
-			return nil
-		})
+			job = buildJob(manifest, path, relpath, optsHash, outputs, overwrite, opts.Force, func(overwrite overwriteMode) error {
+				return trasformFileHugo(path, dstpath, overwrite, multi, formatter)
+			})
+		}
+		return func() error {
+			err := job()
+			if isSkip(err) {
+				// the unchanged files are not reported
+				return nil
+			}
+			return err
+		}
+	})
+	if err2 := manifest.save(); err2 != nil {
+		return err2
+	}
+
+	// the errors of the files don't stop the watch mode
+	if _, ok := err.(FileErrors); err != nil && !ok {
+		return err
+	}
+
+	if opts.Watch {
+		fw, err := newFolderWatcher(opts, modeFrontmatterNone, multi, formatter, true, manifest)
+		if err != nil {
+			return err
+		}
+		return fw.watch()
+	}
 
 	return err
 }
@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mmbros/chordpro/pkg/chordpro"
+)
+
+// FormatText is the name of the default import format.
+const FormatText = "text"
+
+// newImporter function returns the importer of the input format
+// given in the options.
+func newImporter(opts *Options) (chordpro.Importer, error) {
+	name := opts.From
+	if name == "" {
+		name = FormatText
+	}
+	im, err := chordpro.NewImporter(name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q (valid formats: %s)", err, name, strings.Join(chordpro.ImporterNames(), ", "))
+	}
+	return im, nil
+}
+
+// Import imports the songs of the input file, in the format given by
+// the options, and saves them as ChordPro source in the output file,
+// or in the standard output if no output file is given.
+func Import(opts *Options) error {
+	importer, err := newImporter(opts)
+	if err != nil {
+		return err
+	}
+	return convert(opts, importer, chordpro.ChordProFormatter{})
+}
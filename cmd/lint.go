@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mmbros/chordpro/pkg/chordpro"
+)
+
+// ErrLintFailed is returned when the lint command finds diagnostics
+// of the failing severity or higher.
+var ErrLintFailed = errors.New("lint failed")
+
+// DefaultFailOn is the default lowest severity that fails the lint command.
+const DefaultFailOn = "error"
+
+// fileDiagnostic is a diagnostic of a chordpro file.
+type fileDiagnostic struct {
+	File string `json:"file"`
+	chordpro.Diagnostic
+}
+
+// String returns the diagnostic as "file:line:col: severity: message (code)".
+func (d *fileDiagnostic) String() string {
+	return d.File + ":" + d.Diagnostic.String()
+}
+
+// lintFile function returns the diagnostics of the chordpro file.
+// The front matter of the file, if any, is skipped,
+// keeping the line numbers of the file.
+func lintFile(path string) ([]fileDiagnostic, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	// the columns of the diagnostics count the runes of the utf-8 sources
+	src, err := chordpro.NewParser(chordpro.ParseOptions{Encoding: chordpro.EncodingAuto}).Decode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	offset := 0
+	if fm := getFrontMatterFromReader(strings.NewReader(src)); fm != "" {
+		j := strings.Index(src, fm) + len(fm)
+		offset = strings.Count(src[:j], "\n")
+		src = src[j:]
+	}
+
+	var diags []fileDiagnostic
+	for _, d := range chordpro.Lint(src) {
+		d.Line += offset
+		diags = append(diags, fileDiagnostic{File: path, Diagnostic: d})
+	}
+	return diags, nil
+}
+
+// lintFiles function returns the diagnostics of the input chordpro file,
+// or of all the chordpro files of the input folder.
+func lintFiles(input string) ([]fileDiagnostic, error) {
+	info, err := os.Stat(input)
+	if os.IsNotExist(err) {
+		return nil, ErrInputFileNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return lintFile(input)
+	}
+
+	var diags []fileDiagnostic
+	err = filepath.Walk(input,
+		func(path string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() || !isChordProFile(path) {
+				return err
+			}
+			d, err := lintFile(path)
+			diags = append(diags, d...)
+			return err
+		})
+	return diags, err
+}
+
+// writeDiagnostics function writes the diagnostics, one for each line,
+// or as a json array.
+func writeDiagnostics(w io.Writer, diags []fileDiagnostic, asJSON bool) error {
+	if asJSON {
+		if diags == nil {
+			diags = []fileDiagnostic{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(diags)
+	}
+	for _, d := range diags {
+		if _, err := fmt.Fprintln(w, &d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lint function writes the diagnostics of the input to w, and returns
+// ErrLintFailed if any of them has the failing severity or higher.
+func lint(opts *Options, w io.Writer) error {
+	if opts.Input == "" {
+		return ErrMissingInput
+	}
+	failOn := opts.FailOn
+	if failOn == "" {
+		failOn = DefaultFailOn
+	}
+	minSeverity, err := chordpro.ParseSeverity(failOn)
+	if err != nil {
+		return err
+	}
+
+	diags, err := lintFiles(opts.Input)
+	if err != nil {
+		return err
+	}
+	if err := writeDiagnostics(w, diags, opts.JSON); err != nil {
+		return err
+	}
+
+	counts := make([]int, chordpro.SeverityError+1)
+	failed := false
+	for _, d := range diags {
+		counts[d.Severity]++
+		failed = failed || d.Severity >= minSeverity
+	}
+	if failed {
+		return fmt.Errorf("%w: %d errors, %d warnings, %d infos", ErrLintFailed,
+			counts[chordpro.SeverityError], counts[chordpro.SeverityWarning], counts[chordpro.SeverityInfo])
+	}
+	return nil
+}
+
+// Lint checks the chordpro file, or all the chordpro files of the folder,
+// given by the input option, and prints their diagnostics
+// as "file:line:col: severity: message (code)", or as json.
+// It returns ErrLintFailed if any diagnostic has the severity
+// of the FailOn option or higher.
+func Lint(opts *Options) error {
+	return lint(opts, os.Stdout)
+}
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_lint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "a.cho")
+	src := "---\ntitle: A\n---\n{title: A}\n{foo}\n"
+	if err := ioutil.WriteFile(path, []byte(src), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		failOn string
+		json   bool
+		out    string
+		err    error
+	}{
+		{name: "text", out: path + ":5:1: warning: unknown directive {foo} (unknown-directive)\n"},
+		{name: "fail on warning", failOn: "warning", out: path + ":5:1: warning: unknown directive {foo} (unknown-directive)\n", err: ErrLintFailed},
+		{
+			name: "json", json: true,
+			out: `[
+  {
+    "file": "` + path + `",
+    "line": 5,
+    "col": 1,
+    "severity": "warning",
+    "code": "unknown-directive",
+    "message": "unknown directive {foo}"
+  }
+]
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := lint(&Options{Input: dir, FailOn: tt.failOn, JSON: tt.json}, &buf)
+			if !errors.Is(err, tt.err) {
+				t.Errorf("expected error %v, got %v", tt.err, err)
+			}
+			if got := buf.String(); got != tt.out {
+				t.Errorf("expected %q, got %q", tt.out, got)
+			}
+		})
+	}
+}
+
+func Test_lintFile_encoding(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{name: "utf-8", data: []byte("{title: Perché}\nperché è [Xyz]do\n"), want: "2:10: warning: invalid chord \"Xyz\" (invalid-chord)"},
+		{name: "latin-1", data: []byte("{title: Perch\xe9}\nperch\xe9 \xe8 [Xyz]do\n"), want: "2:10: warning: invalid chord \"Xyz\" (invalid-chord)"},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(dir, tt.name+".cho")
+		if err := ioutil.WriteFile(path, tt.data, 0600); err != nil {
+			t.Fatal(err)
+		}
+		diags, err := lintFile(path)
+		if err != nil {
+			t.Fatalf("%s: unexpected error %q", tt.name, err.Error())
+		}
+		if len(diags) != 1 || diags[0].Diagnostic.String() != tt.want {
+			t.Errorf("%s: expected %q, got %v", tt.name, tt.want, diags)
+		}
+	}
+}
@@ -0,0 +1,296 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"sync"
+
+	"github.com/mmbros/chordpro/pkg/chordpro"
+)
+
+// Version is the version of the tool, recorded in the build manifest.
+// It is set at build time with:
+//
+//	go build -ldflags "-X github.com/mmbros/chordpro/cmd.Version=v1.2.3"
+//
+// Otherwise it is the version of the module, as installed by
+// "go install github.com/mmbros/chordpro@v1.2.3", or "dev".
+var Version = "dev"
+
+func init() {
+	if Version != "dev" {
+		return
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		Version = info.Main.Version
+	}
+}
+
+// ManifestName is the name of the build manifest file in the output folder.
+const ManifestName = ".chordpro-manifest.json"
+
+// manifestVersion is the version of the format of the build manifest file.
+const manifestVersion = 1
+
+// manifestEntry is the build record of a source file.
+type manifestEntry struct {
+	Outputs []string `json:"outputs"` // output files, relative to the output folder
+	Input   string   `json:"input"`   // hash of the source file
+	Options string   `json:"options"` // hash of the options
+	Tool    string   `json:"tool"`    // version of the tool
+}
+
+// buildManifest records, for each source file of a folder,
+// the output files and what they were built from:
+// the hash of the source file, the hash of the options and the tool version.
+// An output is rebuilt, in the "old" overwrite mode, when one of them changes,
+// whatever the modification times of the files.
+// It is safe for concurrent use.
+type buildManifest struct {
+	dir   string // output folder
+	found bool   // the manifest file was read from the output folder
+
+	mu    sync.Mutex
+	Ver   int                       `json:"version"`
+	Files map[string]*manifestEntry `json:"files"` // by source file, relative to the input folder
+}
+
+// loadManifest function reads the build manifest of the output folder.
+// A missing or invalid manifest gives an empty one,
+// so that all the outputs are rebuilt.
+func loadManifest(dir string) *buildManifest {
+	m := &buildManifest{dir: dir}
+	data, err := ioutil.ReadFile(filepath.Join(dir, ManifestName))
+	if err == nil {
+		m.found = true
+		if json.Unmarshal(data, m) != nil || m.Ver != manifestVersion {
+			m.Files = nil
+		}
+	}
+	m.Ver = manifestVersion
+	if m.Files == nil {
+		m.Files = map[string]*manifestEntry{}
+	}
+	return m
+}
+
+// used method reports whether the build manifest of a single file command
+// must be saved: in the "old" overwrite mode, that reads it,
+// or if it was already found, so that it records the outputs built
+// in the other modes too.
+// The folder commands always save it.
+func (m *buildManifest) used(overwrite overwriteMode) bool {
+	return overwrite == modeOverwriteOld || m.found
+}
+
+// save method writes the build manifest to the output folder.
+func (m *buildManifest) save() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(m.dir, 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(m.dir, ManifestName), append(data, '\n'), 0644)
+}
+
+// relOutputs method returns the output files relative to the output folder,
+// with slashes.
+func (m *buildManifest) relOutputs(outputs []string) []string {
+	rels := make([]string, len(outputs))
+	for j, out := range outputs {
+		rel, err := filepath.Rel(m.dir, out)
+		if err != nil {
+			rel = out
+		}
+		rels[j] = filepath.ToSlash(rel)
+	}
+	return rels
+}
+
+// upToDate method reports whether the outputs of the source file
+// were built from the same source, options and tool version, and still exist.
+func (m *buildManifest) upToDate(src, inputHash, optionsHash string, outputs []string) bool {
+	m.mu.Lock()
+	e, ok := m.Files[filepath.ToSlash(src)]
+	m.mu.Unlock()
+
+	if !ok || e.Input != inputHash || e.Options != optionsHash || e.Tool != Version {
+		return false
+	}
+	rels := m.relOutputs(outputs)
+	if len(rels) != len(e.Outputs) {
+		return false
+	}
+	for j, rel := range rels {
+		if rel != e.Outputs[j] {
+			return false
+		}
+		if _, err := os.Stat(outputs[j]); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// record method records the outputs built from the source file.
+func (m *buildManifest) record(src, inputHash, optionsHash string, outputs []string) {
+	e := &manifestEntry{
+		Outputs: m.relOutputs(outputs),
+		Input:   inputHash,
+		Options: optionsHash,
+		Tool:    Version,
+	}
+	m.mu.Lock()
+	m.Files[filepath.ToSlash(src)] = e
+	m.mu.Unlock()
+}
+
+// outputs method returns the output files recorded for the source file.
+func (m *buildManifest) outputs(src string) []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.Files[filepath.ToSlash(src)]
+	if !ok {
+		return nil
+	}
+	outs := make([]string, len(e.Outputs))
+	for j, rel := range e.Outputs {
+		outs[j] = filepath.Join(m.dir, filepath.FromSlash(rel))
+	}
+	return outs
+}
+
+// remove method removes the record of the source file.
+func (m *buildManifest) remove(src string) {
+	m.mu.Lock()
+	delete(m.Files, filepath.ToSlash(src))
+	m.mu.Unlock()
+}
+
+// fileHash function returns the hex encoded sha256 hash of the file.
+func fileHash(path string) (string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// optionsHash function returns the hex encoded sha256 hash
+// of the options that change the outputs,
+// including the contents of the templates and of the stylesheet.
+func optionsHash(opts *Options) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "format=%s\nfrom=%s\nfrontmatter=%s\nmulti=%s\nwidth=%d\nstandalone=%v\ntheme=%s\ntitle=%s\nhugo=%v\n",
+		strings.ToLower(opts.Format), strings.ToLower(opts.From), strings.ToLower(opts.Frontmatter), strings.ToLower(opts.Multi),
+		opts.Width, opts.Standalone, strings.ToLower(opts.Theme), opts.Title, opts.Hugo)
+
+	if opts.Template != "" {
+		for _, name := range chordpro.TemplateNames {
+			if data, err := ioutil.ReadFile(filepath.Join(opts.Template, name+".html")); err == nil {
+				fmt.Fprintf(h, "template=%s\n", name)
+				h.Write(data)
+			}
+		}
+	}
+	if opts.CSS != "" {
+		if data, err := ioutil.ReadFile(opts.CSS); err == nil {
+			fmt.Fprintln(h, "css")
+			h.Write(data)
+		}
+	}
+	fmt.Fprintf(h, "columns=%d\nfontsize=%g\n", opts.Columns, opts.FontSize)
+	for _, font := range []string{opts.Font, opts.BoldFont} {
+		if data, err := ioutil.ReadFile(font); err == nil {
+			fmt.Fprintln(h, "font")
+			h.Write(data)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// buildJob function returns the job of the folder pipeline that builds
+// the outputs of the source file, given by listOutputs, with the build function,
+// checking and updating the build manifest.
+// In the "old" overwrite mode, the job returns ErrOutputFileUnchanged
+// if the outputs are up to date; in the other modes, the build function
+// checks the outputs by itself. With force, the outputs are always built.
+func buildJob(m *buildManifest, src, rel, optsHash string, listOutputs func() []string, overwrite overwriteMode, force bool, build func(overwriteMode) error) func() error {
+	return func() error {
+		outputs := listOutputs()
+		inputHash, err := fileHash(src)
+		if err != nil {
+			return err
+		}
+		if force {
+			overwrite = modeOverwriteAll
+		}
+		if overwrite == modeOverwriteOld {
+			if m.upToDate(rel, inputHash, optsHash, outputs) {
+				return ErrOutputFileUnchanged
+			}
+			overwrite = modeOverwriteAll
+		}
+		if err := build(overwrite); err != nil {
+			return err
+		}
+		m.record(rel, inputHash, optsHash, outputs)
+		return nil
+	}
+}
+
+// manifestKey function returns the path of the source file
+// relative to the folder of the build manifest, with slashes.
+func manifestKey(dir, src string) (string, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	absSrc, err := filepath.Abs(src)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(absDir, absSrc)
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// buildFile function builds the outputs of the input file of the options,
+// given by listOutputs, with the build function, like a job of the folder pipeline:
+// the build manifest is the one of the folder of the output file,
+// where the input file is recorded by its path relative to that folder.
+// Without an output file, the songs are written to the standard output
+// and the build manifest is not used.
+func buildFile(opts *Options, listOutputs func() []string, overwrite overwriteMode, build func(overwriteMode) error) error {
+	if opts.Output == "" {
+		return build(overwrite)
+	}
+	if err := checkFiles(opts.Input, "", overwrite); err != nil {
+		return err
+	}
+	m := loadManifest(filepath.Dir(opts.Output))
+	key, err := manifestKey(m.dir, opts.Input)
+	if err != nil {
+		return err
+	}
+	err = buildJob(m, opts.Input, key, optionsHash(opts), listOutputs, overwrite, opts.Force, build)()
+	if err == nil && m.used(overwrite) {
+		err = m.save()
+	}
+	return err
+}
@@ -0,0 +1,189 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func Test_optionsHash(t *testing.T) {
+	base := Options{Format: "html", Frontmatter: "preserve"}
+
+	tests := []struct {
+		name  string
+		opts  Options
+		equal bool
+	}{
+		{name: "same", opts: base, equal: true},
+		{name: "case", opts: Options{Format: "HTML", Frontmatter: "Preserve"}, equal: true},
+		{name: "overwrite", opts: Options{Format: "html", Frontmatter: "preserve", Overwrite: "all", Jobs: 4}, equal: true},
+		{name: "frontmatter", opts: Options{Format: "html", Frontmatter: "none"}},
+		{name: "format", opts: Options{Format: "text", Frontmatter: "preserve"}},
+		{name: "theme", opts: Options{Format: "html", Frontmatter: "preserve", Theme: "dark"}},
+	}
+	h := optionsHash(&base)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := optionsHash(&tt.opts) == h; got != tt.equal {
+				t.Errorf("expected equal %v, got %v", tt.equal, got)
+			}
+		})
+	}
+}
+
+func Test_buildJob(t *testing.T) {
+	dir, err := ioutil.TempDir("", "manifest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "a.cho")
+	out := filepath.Join(dir, "out")
+	dst := filepath.Join(out, "a.cho.html")
+	if err := ioutil.WriteFile(src, []byte("[C]do"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	builds := 0
+	build := func(overwrite overwriteMode) error {
+		if overwrite != modeOverwriteAll {
+			t.Errorf("expected overwrite %v, got %v", modeOverwriteAll, overwrite)
+		}
+		builds++
+		return ioutil.WriteFile(dst, nil, 0600)
+	}
+	outputs := func() []string { return []string{dst} }
+	run := func(optsHash string, force bool) error {
+		m := loadManifest(out)
+		err := buildJob(m, src, "a.cho", optsHash, outputs, modeOverwriteOld, force, build)()
+		if err2 := m.save(); err2 != nil {
+			t.Fatal(err2)
+		}
+		return err
+	}
+
+	tests := []struct {
+		name     string
+		action   func() error
+		optsHash string
+		force    bool
+		err      error
+		builds   int
+	}{
+		{name: "new", optsHash: "o1", builds: 1},
+		{name: "unchanged", optsHash: "o1", err: ErrOutputFileUnchanged, builds: 1},
+		{
+			name: "touched",
+			action: func() error {
+				// a checkout changes the time, not the contents
+				tm := time.Now().Add(time.Hour)
+				return os.Chtimes(src, tm, tm)
+			},
+			optsHash: "o1", err: ErrOutputFileUnchanged, builds: 1,
+		},
+		{name: "options", optsHash: "o2", builds: 2},
+		{name: "force", optsHash: "o2", force: true, builds: 3},
+		{
+			name:     "input",
+			action:   func() error { return ioutil.WriteFile(src, []byte("[D]re"), 0600) },
+			optsHash: "o2", builds: 4,
+		},
+		{
+			name:     "removed-output",
+			action:   func() error { return os.Remove(dst) },
+			optsHash: "o2", builds: 5,
+		},
+		{
+			name: "tool",
+			action: func() error {
+				Version = "test"
+				return nil
+			},
+			optsHash: "o2", builds: 6,
+		},
+	}
+	defer func(v string) { Version = v }(Version)
+	if err := os.MkdirAll(out, 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range tests {
+		if tt.action != nil {
+			if err := tt.action(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := run(tt.optsHash, tt.force); err != tt.err {
+			t.Errorf("%s: expected %v error, got %v", tt.name, tt.err, err)
+		}
+		if builds != tt.builds {
+			t.Errorf("%s: expected %v builds, got %v", tt.name, tt.builds, builds)
+		}
+	}
+}
+
+func Test_buildFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "manifest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "a.cho")
+	out := filepath.Join(dir, "out")
+	if err := ioutil.WriteFile(src, []byte("{title: A}\n[C]do"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	manifest := filepath.Join(out, ManifestName)
+
+	tests := []struct {
+		name      string
+		overwrite string
+		action    func() error
+		err       error
+		manifest  bool
+	}{
+		{name: "none", overwrite: OverwriteNone},
+		{name: "exists", overwrite: OverwriteNone, err: ErrOutputFileExists},
+		{name: "all", overwrite: OverwriteAll},
+		{name: "old", overwrite: OverwriteOld, manifest: true},
+		{name: "unchanged", overwrite: OverwriteOld, err: ErrOutputFileUnchanged, manifest: true},
+		{
+			name:      "touched",
+			overwrite: OverwriteOld,
+			action: func() error {
+				tm := time.Now().Add(time.Hour)
+				return os.Chtimes(src, tm, tm)
+			},
+			err: ErrOutputFileUnchanged, manifest: true,
+		},
+		{
+			name:      "input",
+			overwrite: OverwriteOld,
+			action:    func() error { return ioutil.WriteFile(src, []byte("{title: A}\n[D]re"), 0600) },
+			manifest:  true,
+		},
+	}
+	for _, tt := range tests {
+		if tt.action != nil {
+			if err := tt.action(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		opts := &Options{Input: src, Output: filepath.Join(out, "a.json"), Overwrite: tt.overwrite}
+		if err := Export(opts); err != tt.err {
+			t.Errorf("%s: expected %v error, got %v", tt.name, tt.err, err)
+		}
+		if _, err := os.Stat(manifest); (err == nil) != tt.manifest {
+			t.Errorf("%s: expected manifest %v, got %v", tt.name, tt.manifest, err == nil)
+		}
+	}
+
+	m := loadManifest(out)
+	if _, ok := m.Files["../a.cho"]; !ok {
+		t.Errorf("expected %q in the manifest, got %v", "../a.cho", m.Files)
+	}
+}
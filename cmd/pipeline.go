@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+)
+
+// FileError is the error of a file of the folder pipeline.
+type FileError struct {
+	Path string // path of the source file, relative to the input folder
+	Err  error
+}
+
+func (e *FileError) Error() string {
+	return e.Path + ": " + e.Err.Error()
+}
+
+// Unwrap returns the error of the file.
+func (e *FileError) Unwrap() error {
+	return e.Err
+}
+
+// FileErrors are the errors of the files of the folder pipeline
+// that were not transformed.
+// Each error is already printed with its path when its file is done,
+// so the message is the number of the failed files only.
+type FileErrors []*FileError
+
+func (es FileErrors) Error() string {
+	if len(es) == 1 {
+		return "1 file failed"
+	}
+	return fmt.Sprintf("%d files failed", len(es))
+}
+
+// isSkip function reports whether the error means that the file
+// was skipped because its output already exists, and not that it failed.
+func isSkip(err error) bool {
+	return errors.Is(err, ErrOutputFileExists) || errors.Is(err, ErrOutputFileUnchanged)
+}
+
+// fileJob is a file of the folder pipeline.
+type fileJob struct {
+	index int    // position of the file in the walk order
+	src   string // source file
+	rel   string // source file relative to the input folder
+	do    func() error
+}
+
+// fileResult is the result of a fileJob.
+type fileResult struct {
+	job *fileJob
+	err error
+}
+
+// numJobs function returns the number of concurrent jobs:
+// the given one, or the number of CPUs if not positive.
+func numJobs(jobs int) int {
+	if jobs <= 0 {
+		return runtime.NumCPU()
+	}
+	return jobs
+}
+
+// runPipeline function walks the input folder and runs the job returned
+// by newJob for each file, with at most jobs concurrent jobs.
+// newJob returns a nil function for the files to ignore.
+// The relative paths of the chordpro files and the errors, with their paths,
+// are printed in the walk order, whatever the order the jobs end.
+// The skipped files are not errors; the errors of the other files
+// are returned as FileErrors.
+func runPipeline(input string, jobs int, newJob func(path, rel string) func() error) error {
+	jobc := make(chan *fileJob)
+	resc := make(chan fileResult)
+
+	// discovery
+	var walkErr error
+	go func() {
+		defer close(jobc)
+		index := 0
+		walkErr = filepath.Walk(input,
+			func(path string, info os.FileInfo, err error) error {
+				if err != nil {
+					return err
+				}
+				if info.IsDir() {
+					return nil
+				}
+				rel, _ := filepath.Rel(input, path)
+				if do := newJob(path, rel); do != nil {
+					jobc <- &fileJob{index: index, src: path, rel: rel, do: do}
+					index++
+				}
+				return nil
+			})
+	}()
+
+	// workers: parsing, formatting and writing
+	var wg sync.WaitGroup
+	for j := 0; j < numJobs(jobs); j++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobc {
+				resc <- fileResult{job, job.do()}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(resc)
+	}()
+
+	// ordered logging
+	var errs FileErrors
+	pending := map[int]fileResult{}
+	next := 0
+	for res := range resc {
+		pending[res.job.index] = res
+		for {
+			r, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+
+			if isChordProFile(r.job.src) {
+				fmt.Println(r.job.rel)
+			}
+			if r.err != nil {
+				ferr := &FileError{Path: r.job.rel, Err: r.err}
+				fmt.Fprintln(os.Stderr, ferr)
+				if !isSkip(r.err) {
+					errs = append(errs, ferr)
+				}
+			}
+		}
+	}
+
+	if walkErr != nil {
+		return walkErr
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func Test_runPipeline(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pipeline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	names := []string{"a.cho", "b.cho", "c.cho", "d.chopro", "e.txt", "f.cho"}
+	for _, name := range names {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	errFail := errors.New("fail")
+
+	var mu sync.Mutex
+	var done []string
+	index := 0
+	err = runPipeline(dir, 3, func(path, rel string) func() error {
+		if !isChordProFile(path) {
+			return nil
+		}
+		index++
+		wait := time.Duration(len(names)-index) * time.Millisecond
+		return func() error {
+			// the first files end last
+			time.Sleep(wait)
+			mu.Lock()
+			done = append(done, rel)
+			mu.Unlock()
+
+			switch rel {
+			case "b.cho", "f.cho":
+				return errFail
+			case "c.cho":
+				return ErrOutputFileUnchanged
+			}
+			return nil
+		}
+	})
+
+	if len(done) != 5 {
+		t.Errorf("expected %v jobs, got %v", 5, len(done))
+	}
+	errs, ok := err.(FileErrors)
+	if !ok {
+		t.Fatalf("expected FileErrors, got %v", err)
+	}
+	var got []string
+	for _, e := range errs {
+		if !errors.Is(e, errFail) {
+			t.Errorf("expected %q error, got %q", errFail, e.Err)
+		}
+		got = append(got, e.Path)
+	}
+	expected := []string{"b.cho", "f.cho"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+	if msg := err.Error(); msg != "2 files failed" {
+		t.Errorf("expected %q, got %q", "2 files failed", msg)
+	}
+}
+
+func TestFileErrors_Error(t *testing.T) {
+	errFail := errors.New("fail")
+	tests := []struct {
+		errs     FileErrors
+		expected string
+	}{
+		{FileErrors{{"a.cho", errFail}}, "1 file failed"},
+		{FileErrors{{"a.cho", errFail}, {"b.cho", errFail}}, "2 files failed"},
+	}
+	for _, tt := range tests {
+		if got := tt.errs.Error(); got != tt.expected {
+			t.Errorf("expected %q, got %q", tt.expected, got)
+		}
+	}
+}
+
+func Test_numJobs(t *testing.T) {
+	if got := numJobs(4); got != 4 {
+		t.Errorf("expected %v, got %v", 4, got)
+	}
+	if got := numJobs(0); got < 1 {
+		t.Errorf("expected at least 1 job, got %v", got)
+	}
+}
@@ -0,0 +1,229 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mmbros/chordpro/pkg/chordpro"
+)
+
+// planAction is what a command would do with an output file.
+type planAction string
+
+const (
+	planCreate        planAction = "create"
+	planOverwrite     planAction = "overwrite"
+	planPrint         planAction = "print"
+	planCopy          planAction = "copy"
+	planSkipExists    planAction = "skip exists"
+	planSkipUnchanged planAction = "skip unchanged"
+	planError         planAction = "error"
+)
+
+// planEntry is the plan of an output file of the dry-run mode.
+type planEntry struct {
+	action      planAction
+	src         string // source file
+	dst         string // output file, empty for the standard output
+	frontmatter string // front matter decision: "preserve", "overwrite" or "none"
+	err         error  // error of the planError action
+}
+
+// String returns the plan line of the output file.
+func (e *planEntry) String() string {
+	dst := e.dst
+	if dst == "" {
+		dst = "<stdout>"
+	}
+	s := fmt.Sprintf("%-14s %s -> %s", e.action, e.src, dst)
+	if e.err != nil {
+		return s + ": " + e.err.Error()
+	}
+	if e.frontmatter != "" {
+		s += " (frontmatter: " + e.frontmatter + ")"
+	}
+	return s
+}
+
+// planFrontmatter function returns the front matter decision for the output file:
+// "preserve" if its front matter would be kept, "overwrite" if the front matter
+// would be written from the song metadata, "none" otherwise.
+func planFrontmatter(dst string, frontmatter frontmatterMode) string {
+	switch frontmatter {
+	case modeFrontmatterOverwrite:
+		return FrontmatterOverwrite
+	case modeFrontmatterPreserve:
+		if dst != "" && getFrontMatter(dst) != "" {
+			return FrontmatterPreserve
+		}
+		return FrontmatterOverwrite
+	}
+	return FrontmatterNone
+}
+
+// planOutput function returns the plan of the output file,
+// checking it as the transform or the copy of the source file would do.
+func planOutput(src, dst string, overwrite overwriteMode, isCopy bool) *planEntry {
+	e := &planEntry{src: src, dst: dst}
+	if dst == "" {
+		e.action = planPrint
+		return e
+	}
+
+	switch err := checkFiles(src, dst, overwrite); err {
+	case nil:
+		switch _, err := os.Stat(dst); {
+		case isCopy:
+			e.action = planCopy
+		case err == nil:
+			e.action = planOverwrite
+		default:
+			e.action = planCreate
+		}
+	case ErrOutputFileExists:
+		e.action = planSkipExists
+	default:
+		e.action, e.err = planError, err
+	}
+	return e
+}
+
+// planTransform function returns the plans of the outputs of the chordpro source file:
+// one for each song in split mode, one for the destination file otherwise.
+// In hugo mode, the front matter of the source file is kept in the output,
+// and the split pages get the front matter of their song.
+func planTransform(srcFile, dstFile string, overwrite overwriteMode, frontmatter frontmatterMode, multi multiMode, formatter chordpro.Formatter, hugo bool) []*planEntry {
+	if !hasFrontmatter(formatter) {
+		frontmatter = modeFrontmatterNone
+	}
+	if isSongbook(formatter) {
+		multi = modeMultiAll
+	}
+
+	data, err := ioutil.ReadFile(srcFile)
+	if err != nil {
+		return []*planEntry{{action: planError, src: srcFile, dst: dstFile, err: err}}
+	}
+	src := toUtf8(data)
+	srcFrontmatter := ""
+	if hugo {
+		s := string(data)
+		srcFrontmatter = getFrontMatterFromReader(strings.NewReader(s))
+		if srcFrontmatter != "" {
+			s = s[strings.Index(s, srcFrontmatter)+len(srcFrontmatter):]
+		}
+		src = toUtf8([]byte(strings.TrimSpace(s)))
+	}
+	songs, err := readSongs(src, multi)
+	if err != nil {
+		return []*planEntry{{action: planError, src: srcFile, dst: dstFile, err: err}}
+	}
+
+	if len(songs) > 1 && multi == modeMultiSplit && dstFile != "" {
+		if hugo && frontmatter != modeFrontmatterNone {
+			frontmatter = modeFrontmatterOverwrite
+		}
+		var plans []*planEntry
+		for _, name := range splitFileNames(dstFile, formatter.Extension(), songs) {
+			e := planOutput(srcFile, name, overwrite, false)
+			e.frontmatter = planFrontmatter(name, frontmatter)
+			plans = append(plans, e)
+		}
+		return plans
+	}
+
+	e := planOutput(srcFile, dstFile, overwrite, false)
+	switch {
+	case hugo && srcFrontmatter != "":
+		e.frontmatter = FrontmatterPreserve
+	case hugo && frontmatter != modeFrontmatterNone:
+		e.frontmatter = FrontmatterOverwrite
+	default:
+		e.frontmatter = planFrontmatter(dstFile, frontmatter)
+	}
+	return []*planEntry{e}
+}
+
+// planBuild function returns the plans of the outputs of the source file
+// as its build job would do: in the "old" overwrite mode, the outputs
+// recorded as up to date in the build manifest are skipped, and the other
+// ones are planned as overwritten; in the other modes, plan checks them.
+func planBuild(m *buildManifest, src, rel, optsHash string, outputs []string, overwrite overwriteMode, plan func(overwriteMode) []*planEntry) []*planEntry {
+	if overwrite != modeOverwriteOld {
+		return plan(overwrite)
+	}
+	if hash, err := fileHash(src); err == nil && m.upToDate(rel, hash, optsHash, outputs) {
+		var plans []*planEntry
+		for _, out := range outputs {
+			plans = append(plans, &planEntry{action: planSkipUnchanged, src: src, dst: out})
+		}
+		return plans
+	}
+	return plan(modeOverwriteAll)
+}
+
+// planFile function returns the plans of the outputs of the input file
+// of the options, given by listOutputs, as buildFile would do.
+func planFile(opts *Options, listOutputs func() []string, overwrite overwriteMode, plan func(overwriteMode) []*planEntry) []*planEntry {
+	if opts.Force {
+		overwrite = modeOverwriteAll
+	}
+	if opts.Output == "" {
+		return plan(overwrite)
+	}
+	m := loadManifest(filepath.Dir(opts.Output))
+	key, err := manifestKey(m.dir, opts.Input)
+	if err != nil {
+		return []*planEntry{{action: planError, src: opts.Input, dst: opts.Output, err: err}}
+	}
+	return planBuild(m, opts.Input, key, optionsHash(opts), listOutputs(), overwrite, plan)
+}
+
+// planFolder function writes the plans of all the files of the input folder,
+// in the walk order, without writing any file.
+// The other files are copied in hugo mode, and ignored otherwise.
+// In the "old" overwrite mode, the build manifest decides the files
+// to skip; force plans all the files as transformed again.
+func planFolder(w io.Writer, opts *Options, overwrite overwriteMode, frontmatter frontmatterMode, multi multiMode, formatter chordpro.Formatter, hugo bool) error {
+	manifest := loadManifest(opts.Output)
+	optsHash := optionsHash(opts)
+	if opts.Force {
+		overwrite = modeOverwriteAll
+	}
+
+	return filepath.Walk(opts.Input,
+		func(path string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() {
+				return err
+			}
+			relpath, _ := filepath.Rel(opts.Input, path)
+			isSrc := isChordProFile(path)
+			if !isSrc && !hugo {
+				return nil
+			}
+
+			dstpath := filepath.Join(opts.Output, relpath)
+			if isSrc {
+				dstpath += formatter.Extension()
+			}
+
+			outputs := []string{dstpath}
+			if isSrc {
+				outputs = outputFiles(path, dstpath, multi, formatter)
+			}
+			plans := planBuild(manifest, path, relpath, optsHash, outputs, overwrite, func(mode overwriteMode) []*planEntry {
+				if !isSrc {
+					return []*planEntry{planOutput(path, dstpath, mode, true)}
+				}
+				return planTransform(path, dstpath, mode, frontmatter, multi, formatter, hugo)
+			})
+			for _, e := range plans {
+				fmt.Fprintln(w, e)
+			}
+			return nil
+		})
+}
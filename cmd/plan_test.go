@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func Test_planTransform(t *testing.T) {
+	dir, err := ioutil.TempDir("", "plan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	write := func(name, content string, mtime time.Time) string {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	now := time.Now()
+	old := now.Add(-time.Hour)
+
+	song := write("song.cho", "{title: Yesterday}\n[C]do", old)
+	medley := write("medley.cho", "{title: One}\n[C]do\n{new_song}\n{title: Two}\n[D]re", old)
+	write("newer.html", "---\ntitle: Yesterday\n---\n", now)
+	write("older.html", "<p></p>", old.Add(-time.Hour))
+
+	formatter, err := newFormatter(&Options{Format: FormatHTML})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		src, dst    string
+		overwrite   overwriteMode
+		frontmatter frontmatterMode
+		multi       multiMode
+		hugo        bool
+		actions     []planAction
+		fronts      []string
+	}{
+		{
+			name: "create", src: song, dst: filepath.Join(dir, "new.html"),
+			frontmatter: modeFrontmatterPreserve,
+			actions:     []planAction{planCreate}, fronts: []string{FrontmatterOverwrite},
+		},
+		{
+			name: "stdout", src: song,
+			frontmatter: modeFrontmatterNone,
+			actions:     []planAction{planPrint}, fronts: []string{FrontmatterNone},
+		},
+		{
+			name: "skip exists", src: song, dst: filepath.Join(dir, "newer.html"),
+			overwrite: modeOverwriteNone, frontmatter: modeFrontmatterPreserve,
+			actions: []planAction{planSkipExists}, fronts: []string{FrontmatterPreserve},
+		},
+		{
+			name: "overwrite", src: song, dst: filepath.Join(dir, "older.html"),
+			overwrite: modeOverwriteOld, frontmatter: modeFrontmatterPreserve,
+			actions: []planAction{planOverwrite}, fronts: []string{FrontmatterOverwrite},
+		},
+		{
+			name: "multiple songs", src: medley, dst: filepath.Join(dir, "medley.html"),
+			multi:   modeMultiError,
+			actions: []planAction{planError}, fronts: []string{""},
+		},
+		{
+			name: "split", src: medley, dst: filepath.Join(dir, "medley.cho.html"),
+			multi: modeMultiSplit, frontmatter: modeFrontmatterPreserve,
+			actions: []planAction{planCreate, planCreate},
+			fronts:  []string{FrontmatterOverwrite, FrontmatterOverwrite},
+		},
+		{
+			name: "hugo", src: write("hugo.cho", "---\ntitle: x\n---\n[C]do", old), dst: filepath.Join(dir, "hugo.html"),
+			frontmatter: modeFrontmatterOverwrite, hugo: true,
+			actions: []planAction{planCreate}, fronts: []string{FrontmatterPreserve},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plans := planTransform(tt.src, tt.dst, tt.overwrite, tt.frontmatter, tt.multi, formatter, tt.hugo)
+			if len(plans) != len(tt.actions) {
+				t.Fatalf("expected %d plans, got %d", len(tt.actions), len(plans))
+			}
+			for j, e := range plans {
+				if e.action != tt.actions[j] {
+					t.Errorf("expected action %q, got %q (%v)", tt.actions[j], e.action, e.err)
+				}
+				if e.frontmatter != tt.fronts[j] {
+					t.Errorf("expected frontmatter %q, got %q", tt.fronts[j], e.frontmatter)
+				}
+			}
+		})
+	}
+
+	// nothing is written
+	if _, err := os.Stat(filepath.Join(dir, "new.html")); !os.IsNotExist(err) {
+		t.Errorf("expected no output file, got %v", err)
+	}
+}
@@ -0,0 +1,392 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/mmbros/chordpro/pkg/chordpro"
+)
+
+// DefaultAddr is the default address of the preview server.
+const DefaultAddr = "localhost:8080"
+
+// eventsPath is the url path of the server-sent events of the live reload.
+const eventsPath = "/_events"
+
+// ErrInvalidTranspose is returned when the transpose parameter is not a number.
+var ErrInvalidTranspose = errors.New("invalid transpose")
+
+// liveReloadScript is added to the html pages to reload them
+// when the server sends a change event.
+const liveReloadScript = `<script>
+new EventSource("` + eventsPath + `").onmessage = function() { location.reload(); };
+</script>
+`
+
+// indexTemplate is the template of the folder index pages.
+var indexTemplate = template.Must(template.New("index").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+{{.CSS}}
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<ul>
+{{- if .Parent}}
+<li><a href="{{.Parent}}">..</a></li>
+{{- end}}
+{{- range .Entries}}
+<li><a href="{{.URL}}">{{.Name}}</a>{{with .Artist}} <span class="artist">{{.}}</span>{{end}}</li>
+{{- end}}
+</ul>
+` + liveReloadScript + `</body>
+</html>
+`))
+
+// indexEntry is an entry of a folder index page.
+type indexEntry struct {
+	Name   string
+	Artist string
+	URL    string
+}
+
+// server is the http.Handler of the preview server.
+// It renders the chordpro files of the root folder, lists its folders,
+// serves the other files as they are, and sends the change events
+// to the pages for the live reload.
+type server struct {
+	root string
+	opts Options
+
+	mu      sync.Mutex
+	clients map[chan string]bool
+}
+
+// newServer function returns the preview server of the root folder,
+// with the defaults given by the options.
+func newServer(root string, opts *Options) *server {
+	return &server{
+		root:    root,
+		opts:    *opts,
+		clients: map[chan string]bool{},
+	}
+}
+
+// broadcast method sends the changed paths to the connected pages.
+// A page that is not ready to receive misses the event.
+func (s *server) broadcast(changes []change) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, c := range changes {
+		rel, _ := filepath.Rel(s.root, c.path)
+		for ch := range s.clients {
+			select {
+			case ch <- filepath.ToSlash(rel):
+			default:
+			}
+		}
+	}
+}
+
+// serveEvents method sends the change events to a page, until it disconnects.
+func (s *server) serveEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	ch := make(chan string, 1)
+	s.mu.Lock()
+	s.clients[ch] = true
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.clients, ch)
+		s.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case rel := <-ch:
+			fmt.Fprintf(w, "data: %s\n\n", rel)
+			flusher.Flush()
+		}
+	}
+}
+
+// previewFormatter method returns the formatter of the page options.
+// The html fragments are written as complete documents with the theme.
+func (s *server) previewFormatter(opts *Options) (chordpro.Formatter, error) {
+	f, err := newBodyFormatter(opts)
+	if err != nil {
+		return nil, err
+	}
+	css := ""
+	if opts.CSS != "" {
+		// read at each request, to preview the changes of the stylesheet
+		data, err := ioutil.ReadFile(opts.CSS)
+		if err != nil {
+			return nil, err
+		}
+		css = string(data)
+	}
+
+	switch tf := f.(type) {
+	case *chordpro.StandaloneFormatter:
+		tf.Theme, tf.CSS = opts.Theme, css
+		return tf, nil
+	case *chordpro.SongbookFormatter:
+		tf.Theme, tf.CSS = opts.Theme, css
+		return tf, nil
+	}
+	if f.MimeType() != "text/html" {
+		return f, nil
+	}
+	return &chordpro.StandaloneFormatter{Body: f, Theme: opts.Theme, CSS: css}, nil
+}
+
+// pageOptions method returns the options of the page,
+// given by the "format" and "theme" query parameters, and the transpose semitones,
+// given by the "transpose" parameter.
+func (s *server) pageOptions(q url.Values) (*Options, int, error) {
+	opts := s.opts
+	if v := q.Get("format"); v != "" {
+		opts.Format = v
+	}
+	if v := q.Get("theme"); v != "" {
+		opts.Theme = v
+	}
+	if _, err := chordpro.ThemeCSS(opts.Theme); err != nil {
+		return nil, 0, fmt.Errorf("%w: %q (valid themes: %s)", err, opts.Theme, strings.Join(chordpro.ThemeNames(), ", "))
+	}
+
+	transpose := 0
+	if v := q.Get("transpose"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, 0, fmt.Errorf("%w: %q", ErrInvalidTranspose, v)
+		}
+		transpose = n
+	}
+	return &opts, transpose, nil
+}
+
+// serveSong method writes the songs of the chordpro file
+// in the format of the page options.
+func (s *server) serveSong(w http.ResponseWriter, r *http.Request, file string) {
+	opts, transpose, err := s.pageOptions(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	formatter, err := s.previewFormatter(opts)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	songs := chordpro.ParseText(toUtf8(data))
+	if len(songs) == 0 {
+		http.Error(w, ErrZeroSongs.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	for _, song := range songs {
+		song.Transpose(transpose)
+	}
+
+	var buf bytes.Buffer
+	if err := formatter.FormatSongs(&buf, songs); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	out := buf.Bytes()
+
+	mime := formatter.MimeType()
+	if mime == "text/html" {
+		// add the live reload script at the end of the body
+		if j := bytes.LastIndex(out, []byte("</body>")); j >= 0 {
+			out = append(out[:j:j], append([]byte(liveReloadScript), out[j:]...)...)
+		}
+	}
+	if strings.HasPrefix(mime, "text/") || strings.HasSuffix(mime, "xml") || strings.HasSuffix(mime, "json") {
+		mime += "; charset=utf-8"
+	}
+	w.Header().Set("Content-Type", mime)
+	w.Write(out)
+}
+
+// serveIndex method writes the index page of the folder,
+// with its sub folders and its chordpro files.
+// The links of the page keep the format and the theme of the request.
+func (s *server) serveIndex(w http.ResponseWriter, r *http.Request, dir, urlPath string) {
+	opts, _, err := s.pageOptions(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	css, _ := chordpro.ThemeCSS(opts.Theme)
+
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	query := url.Values{}
+	for _, k := range []string{"format", "theme"} {
+		if v := r.URL.Query().Get(k); v != "" {
+			query.Set(k, v)
+		}
+	}
+	link := func(p string) string {
+		u := url.URL{Path: p, RawQuery: query.Encode()}
+		return u.String()
+	}
+
+	var entries []indexEntry
+	for _, info := range infos {
+		name := info.Name()
+		switch {
+		case strings.HasPrefix(name, "."):
+		case info.IsDir():
+			entries = append(entries, indexEntry{Name: name + "/", URL: link(path.Join(urlPath, name) + "/")})
+		case isChordProFile(name):
+			e := indexEntry{Name: name, URL: link(path.Join(urlPath, name))}
+			if data, err := ioutil.ReadFile(filepath.Join(dir, name)); err == nil {
+				if songs := chordpro.ParseText(toUtf8(data)); len(songs) > 0 {
+					if t := songs[0].Title(); t != "" {
+						e.Name = t
+					}
+					e.Artist = songs[0].Artist()
+				}
+			}
+			entries = append(entries, e)
+		}
+	}
+
+	data := struct {
+		Title   string
+		CSS     template.CSS
+		Parent  string
+		Entries []indexEntry
+	}{
+		Title:   urlPath,
+		CSS:     template.CSS(css),
+		Entries: entries,
+	}
+	if urlPath != "/" {
+		parent := path.Dir(strings.TrimSuffix(urlPath, "/"))
+		if parent != "/" {
+			parent += "/"
+		}
+		data.Parent = link(parent)
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := indexTemplate.Execute(w, data); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+}
+
+// ServeHTTP serves the request.
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == eventsPath {
+		s.serveEvents(w, r)
+		return
+	}
+
+	// the cleaned path can't go outside the root folder
+	urlPath := path.Clean("/" + r.URL.Path)
+	file := filepath.Join(s.root, filepath.FromSlash(urlPath))
+
+	info, err := os.Stat(file)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	switch {
+	case info.IsDir():
+		if urlPath != "/" {
+			urlPath += "/"
+		}
+		s.serveIndex(w, r, file, urlPath)
+	case isChordProFile(file):
+		s.serveSong(w, r, file)
+	default:
+		http.ServeFile(w, r, file)
+	}
+}
+
+// Serve serves the chordpro files of the input folder as rendered pages,
+// on the address given by the options, with an index page for each folder.
+// The pages are reloaded by the browser when the files change.
+func Serve(opts *Options) error {
+	if opts.Input == "" {
+		return ErrMissingInput
+	}
+	info, err := os.Stat(opts.Input)
+	if os.IsNotExist(err) {
+		return ErrInputFileNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return errors.New("input path not a directory")
+	}
+
+	o := *opts
+	o.Standalone = false
+	s := newServer(opts.Input, &o)
+
+	// check the options before to start
+	if _, _, err := s.pageOptions(url.Values{}); err != nil {
+		return err
+	}
+	if _, err := s.previewFormatter(&o); err != nil {
+		return err
+	}
+
+	w, err := newWatcher(opts.Input, isChordProFile)
+	if err != nil {
+		return err
+	}
+	go w.run(watchInterval, nil, s.broadcast)
+
+	addr := opts.Addr
+	if addr == "" {
+		addr = DefaultAddr
+	}
+	fmt.Printf("serving %s on http://%s/\n", opts.Input, addr)
+	return http.ListenAndServe(addr, s)
+}
@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_server(t *testing.T) {
+	dir, err := ioutil.TempDir("", "serve")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := os.Mkdir(filepath.Join(dir, "rock"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	src := "{title: Angie}\n{artist: Rolling Stones}\n[Am]An[E7]gie\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, "rock", "angie.cho"), []byte(src), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	s := newServer(dir, &Options{Theme: "light"})
+
+	tests := []struct {
+		name   string
+		url    string
+		status int
+		mime   string
+		want   []string
+	}{
+		{
+			name:   "index",
+			url:    "/",
+			status: http.StatusOK,
+			mime:   "text/html; charset=utf-8",
+			want:   []string{`<a href="/rock/">rock/</a>`, "EventSource"},
+		},
+		{
+			name:   "index-sub",
+			url:    "/rock?theme=dark",
+			status: http.StatusOK,
+			want: []string{
+				`<a href="/?theme=dark">..</a>`,
+				`<a href="/rock/angie.cho?theme=dark">Angie</a> <span class="artist">Rolling Stones</span>`,
+				"--bg: #1e1e1e;",
+			},
+		},
+		{
+			name:   "song",
+			url:    "/rock/angie.cho",
+			status: http.StatusOK,
+			mime:   "text/html; charset=utf-8",
+			want:   []string{"<!DOCTYPE html>", `<u class="chord">Am</u>`, "EventSource"},
+		},
+		{
+			name:   "song-transpose",
+			url:    "/rock/angie.cho?transpose=2&theme=dark",
+			status: http.StatusOK,
+			want:   []string{`<u class="chord">Bm</u>`, `<u class="chord">F#7</u>`, "--bg: #1e1e1e;"},
+		},
+		{
+			name:   "song-text",
+			url:    "/rock/angie.cho?format=text",
+			status: http.StatusOK,
+			mime:   "text/plain; charset=utf-8",
+			want:   []string{"Am"},
+		},
+		{
+			name:   "bad-transpose",
+			url:    "/rock/angie.cho?transpose=x",
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "bad-format",
+			url:    "/rock/angie.cho?format=xxx",
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "not-found",
+			url:    "/../serve_test.go",
+			status: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, httptest.NewRequest("GET", tt.url, nil))
+
+			if rec.Code != tt.status {
+				t.Fatalf("expected status %v, got %v", tt.status, rec.Code)
+			}
+			if tt.mime != "" {
+				if got := rec.Header().Get("Content-Type"); got != tt.mime {
+					t.Errorf("expected %q content type, got %q", tt.mime, got)
+				}
+			}
+			got := rec.Body.String()
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("expected %q in output, got %q", want, got)
+				}
+			}
+		})
+	}
+}
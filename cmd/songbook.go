@@ -0,0 +1,201 @@
+package cmd
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mmbros/chordpro/pkg/chordpro"
+)
+
+// ErrSongbookFormat is returned when the songbook is requested with a format other than html.
+var ErrSongbookFormat = errors.New("songbook is supported only by the html format")
+
+// readSongFiles function parses the chordpro files and returns all their songs,
+// in the order of the files, and the hex encoded sha256 hash
+// of the names and the contents of the files.
+func readSongFiles(files []string) (chordpro.Songs, string, error) {
+	var songs chordpro.Songs
+	h := sha256.New()
+
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, "", err
+		}
+		fmt.Fprintf(h, "%s\n%d\n", filepath.ToSlash(file), len(data))
+		h.Write(data)
+		songs = append(songs, chordpro.ParseText(toUtf8(data))...)
+	}
+	return songs, hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// songbookFolderFiles function returns the chordpro files found
+// recursively under the root folder.
+func songbookFolderFiles(root string) ([]string, error) {
+	var files []string
+	err := filepath.Walk(root,
+		func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !info.IsDir() && isChordProFile(path) {
+				files = append(files, path)
+			}
+			return nil
+		})
+	return files, err
+}
+
+// songbookManifestFiles function returns the chordpro files listed in the manifest:
+// one path per line, relative to the folder of the manifest.
+// Blank lines and lines beginning with '#' are ignored.
+func songbookManifestFiles(manifest string) ([]string, error) {
+	data, err := ioutil.ReadFile(manifest)
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Dir(manifest)
+
+	var files []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if !filepath.IsAbs(line) {
+			line = filepath.Join(dir, line)
+		}
+		files = append(files, line)
+	}
+	return files, nil
+}
+
+// songbookFiles function returns the chordpro files of the songbook input:
+// the files under the folder, the file itself if it is a chordpro file,
+// or the files listed in the manifest otherwise.
+func songbookFiles(input string) ([]string, error) {
+	if input == "" {
+		return nil, ErrMissingInput
+	}
+	info, err := os.Stat(input)
+	if os.IsNotExist(err) {
+		return nil, ErrInputFileNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return songbookFolderFiles(input)
+	}
+	if isChordProFile(input) {
+		return []string{input}, nil
+	}
+	return songbookManifestFiles(input)
+}
+
+// newSongbookFormatter function returns the songbook formatter
+// configured with the options.
+func newSongbookFormatter(opts *Options) (*chordpro.SongbookFormatter, error) {
+	o := *opts
+	o.Standalone = false
+	body, err := newBodyFormatter(&o)
+	if err != nil {
+		return nil, err
+	}
+	if body.MimeType() != "text/html" {
+		return nil, fmt.Errorf("%w: %q", ErrSongbookFormat, opts.Format)
+	}
+	if _, err := chordpro.ThemeCSS(opts.Theme); err != nil {
+		return nil, fmt.Errorf("%w: %q (valid themes: %s)", err, opts.Theme, strings.Join(chordpro.ThemeNames(), ", "))
+	}
+
+	sf := &chordpro.SongbookFormatter{Title: opts.Title, Body: body, Theme: opts.Theme}
+	if opts.CSS != "" {
+		data, err := ioutil.ReadFile(opts.CSS)
+		if err != nil {
+			return nil, err
+		}
+		sf.CSS = string(data)
+	}
+	return sf, nil
+}
+
+// Songbook writes a single html document with all the songs
+// found under the input folder, or listed in the input manifest,
+// sorted by title, with a cover, a table of contents and the indexes.
+func Songbook(opts *Options) error {
+	overwrite, err := parseOverwrite(opts.Overwrite)
+	if err != nil {
+		return err
+	}
+
+	formatter, err := newSongbookFormatter(opts)
+	if err != nil {
+		return err
+	}
+
+	files, err := songbookFiles(opts.Input)
+	if err != nil {
+		return err
+	}
+	songs, inputHash, err := readSongFiles(files)
+	if err != nil {
+		return err
+	}
+	if len(songs) == 0 {
+		return ErrZeroSongs
+	}
+	chordpro.SortSongs(songs)
+
+	// check output file: in the "old" overwrite mode, the build manifest
+	// of its folder records the songbook by the path of the input
+	var manifest *buildManifest
+	var key, optsHash string
+	if opts.Output != "" {
+		manifest = loadManifest(filepath.Dir(opts.Output))
+		key, err = manifestKey(manifest.dir, opts.Input)
+		if err != nil {
+			return err
+		}
+		optsHash = optionsHash(opts)
+		switch {
+		case opts.Force:
+		case overwrite == modeOverwriteNone:
+			if _, err := os.Stat(opts.Output); err == nil {
+				return ErrOutputFileExists
+			}
+		case overwrite == modeOverwriteOld:
+			if manifest.upToDate(key, inputHash, optsHash, []string{opts.Output}) {
+				return ErrOutputFileUnchanged
+			}
+		}
+	}
+
+	// writer
+	fout := os.Stdout
+	if opts.Output != "" {
+		fout, err = createFileAll(opts.Output)
+		if err != nil {
+			return err
+		}
+		defer fout.Close()
+	}
+	writer := bufio.NewWriter(fout)
+
+	err = formatter.FormatSongs(writer, songs)
+	if err2 := writer.Flush(); err == nil {
+		err = err2
+	}
+	if err == nil && manifest != nil && manifest.used(overwrite) {
+		manifest.record(key, inputHash, optsHash, []string{opts.Output})
+		err = manifest.save()
+	}
+	return err
+}
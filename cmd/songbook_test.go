@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func Test_songbookManifestFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "songbook")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	manifest := filepath.Join(dir, "gig.txt")
+	src := "# opening\nb.chopro\n\n  sub/a.cho  \n/abs/c.cho\n"
+	if err := ioutil.WriteFile(manifest, []byte(src), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := songbookFiles(manifest)
+	if err != nil {
+		t.Fatalf("unexpected error %q", err.Error())
+	}
+	expected := []string{
+		filepath.Join(dir, "b.chopro"),
+		filepath.Join(dir, "sub", "a.cho"),
+		"/abs/c.cho",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestSongbook_overwrite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "songbook")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	in := filepath.Join(dir, "in")
+	if err := os.MkdirAll(in, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(in, "a.cho"), []byte("{title: A}\n[C]do"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(dir, "book.html")
+
+	tests := []struct {
+		name      string
+		overwrite string
+		force     bool
+		err       error
+	}{
+		{name: "none", overwrite: OverwriteNone},
+		{name: "exists", overwrite: OverwriteNone, err: ErrOutputFileExists},
+		{name: "old", overwrite: OverwriteOld},
+		{name: "unchanged", overwrite: OverwriteOld, err: ErrOutputFileUnchanged},
+		{name: "force", overwrite: OverwriteOld, force: true},
+		{name: "force none", overwrite: OverwriteNone, force: true},
+	}
+	for _, tt := range tests {
+		opts := &Options{Input: in, Output: out, Overwrite: tt.overwrite, Force: tt.force}
+		if err := Songbook(opts); err != tt.err {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.err, err)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/mmbros/chordpro/pkg/chordpro"
+)
+
+// slug function returns the lower case text with the letters and digits only,
+// the other characters replaced by a single dash.
+func slug(s string) string {
+	var sb strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && sb.Len() > 0 {
+				sb.WriteByte('-')
+			}
+			sb.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	return sb.String()
+}
+
+// splitFileNames function returns the names of the files of the songs
+// split from the same source, given its destination file.
+// Each name is the destination file, without the output extension,
+// followed by the slug of the song title, or by the index of the song
+// if the title is missing or already used, and by the output extension.
+// As for a single song, the chordpro extension is kept:
+// for example, "medley.cho.html" gives "medley.cho-yesterday.html" and "medley.cho-2.html".
+func splitFileNames(dstFile, ext string, songs chordpro.Songs) []string {
+	base := strings.TrimSuffix(dstFile, ext)
+
+	used := map[string]bool{}
+	names := make([]string, len(songs))
+	for j, s := range songs {
+		suffix := slug(s.Title())
+		if suffix == "" || used[suffix] {
+			suffix = strconv.Itoa(j + 1)
+		}
+		used[suffix] = true
+		names[j] = base + "-" + suffix + ext
+	}
+	return names
+}
+
+// outputFiles function returns the output files of the chordpro source file
+// whose destination file is dstFile: one file for each song in split mode,
+// the destination file otherwise.
+func outputFiles(srcFile, dstFile string, multi multiMode, formatter chordpro.Formatter) []string {
+	if multi != modeMultiSplit || isSongbook(formatter) {
+		return []string{dstFile}
+	}
+	data, err := ioutil.ReadFile(srcFile)
+	if err != nil {
+		return []string{dstFile}
+	}
+	songs := chordpro.ParseText(toUtf8(data))
+	if len(songs) <= 1 {
+		return []string{dstFile}
+	}
+	return splitFileNames(dstFile, formatter.Extension(), songs)
+}
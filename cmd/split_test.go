@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mmbros/chordpro/pkg/chordpro"
+)
+
+func Test_slug(t *testing.T) {
+	tests := []struct {
+		s    string
+		want string
+	}{
+		{"Yesterday", "yesterday"},
+		{"  Let It Be!  ", "let-it-be"},
+		{"Rock'n'Roll -- 2", "rock-n-roll-2"},
+		{"Perché no", "perché-no"},
+		{"???", ""},
+	}
+	for _, tt := range tests {
+		if got := slug(tt.s); got != tt.want {
+			t.Errorf("%q: expected %q, got %q", tt.s, tt.want, got)
+		}
+	}
+}
+
+func Test_splitFileNames(t *testing.T) {
+	songs := chordpro.ParseText("{title: Yesterday}\n[C]do\n{ns}\n[D]re\n{ns}\n{title: Yesterday}\n[E]mi\n")
+
+	got := splitFileNames(filepath.Join("out", "medley.cho.html"), ".html", songs)
+	expected := []string{
+		filepath.Join("out", "medley.cho-yesterday.html"),
+		filepath.Join("out", "medley.cho-2.html"),
+		filepath.Join("out", "medley.cho-3.html"),
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func Test_trasformFileMulti(t *testing.T) {
+	dir, err := ioutil.TempDir("", "split")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "medley.cho")
+	if err := ioutil.WriteFile(src, []byte("{title: One}\n[C]do\n{new_song}\n{title: Two}\n[D]re\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	dst := filepath.Join(dir, "medley.cho.html")
+	f := chordpro.HtmlFormatter{}
+
+	// error
+	err = trasformFile(src, dst, modeOverwriteAll, modeFrontmatterOverwrite, modeMultiError, f)
+	if err != ErrMultipleSongs {
+		t.Errorf("expected %q error, got %v", ErrMultipleSongs, err)
+	}
+
+	// all
+	err = trasformFile(src, dst, modeOverwriteAll, modeFrontmatterOverwrite, modeMultiAll, f)
+	if err != nil {
+		t.Fatalf("unexpected error %q", err.Error())
+	}
+	data, _ := ioutil.ReadFile(dst)
+	if got := strings.Count(string(data), `<div class="chord-sheet">`); got != 2 {
+		t.Errorf("expected %v songs, got %v", 2, got)
+	}
+	os.Remove(dst)
+
+	// split
+	err = trasformFile(src, dst, modeOverwriteAll, modeFrontmatterOverwrite, modeMultiSplit, f)
+	if err != nil {
+		t.Fatalf("unexpected error %q", err.Error())
+	}
+	for _, title := range []string{"One", "Two"} {
+		name := filepath.Join(dir, "medley.cho-"+strings.ToLower(title)+".html")
+		data, err := ioutil.ReadFile(name)
+		if err != nil {
+			t.Errorf("unexpected error %q", err.Error())
+			continue
+		}
+		want := "---\ntitle: \"" + title + "\"\n---\n"
+		if !strings.HasPrefix(string(data), want) {
+			t.Errorf("expected %q prefix, got %q", want, string(data))
+		}
+	}
+	if _, err := os.Stat(dst); !os.IsNotExist(err) {
+		t.Errorf("expected no %s file", dst)
+	}
+}
@@ -0,0 +1,447 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/mmbros/chordpro/pkg/chordpro"
+)
+
+// watchInterval is the polling interval of the watcher.
+const watchInterval = 500 * time.Millisecond
+
+// changeOp is the operation of a change found by the watcher.
+type changeOp int
+
+const (
+	opCreate changeOp = iota
+	opWrite
+	opRemove
+	opRename
+)
+
+func (op changeOp) String() string {
+	return []string{"create", "write", "remove", "rename"}[op]
+}
+
+// change is a change of a file or a folder found by the watcher.
+// A file removed and another one created with the same modification time
+// and size are found as the rename of the file from the old path.
+// A renamed folder is found as the remove of the old path
+// and the create of the new one.
+type change struct {
+	path string
+	op   changeOp
+	dir  bool
+	from string // old path of a renamed file
+}
+
+// fileState is the state of a watched file used to find its changes.
+type fileState struct {
+	modTime time.Time
+	size    int64
+	dir     bool
+}
+
+// watcher finds the changes of the files under the root folder
+// comparing their state between two polls.
+// It doesn't depend on the notifications of the operating system,
+// so it works the same way on every platform.
+type watcher struct {
+	root   string
+	filter func(path string) bool // files to watch, all if nil
+	files  map[string]fileState
+}
+
+// newWatcher function returns a watcher of the files under the root folder
+// accepted by the filter, or of all the files if the filter is nil.
+// The folders are always watched.
+func newWatcher(root string, filter func(path string) bool) (*watcher, error) {
+	w := &watcher{root: root, filter: filter}
+	files, err := w.scan()
+	if err != nil {
+		return nil, err
+	}
+	w.files = files
+	return w, nil
+}
+
+// scan method returns the current state of the watched files.
+func (w *watcher) scan() (map[string]fileState, error) {
+	files := map[string]fileState{}
+	err := filepath.Walk(w.root,
+		func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) && path != w.root {
+					// removed during the walk
+					return nil
+				}
+				return err
+			}
+			if path == w.root {
+				return nil
+			}
+			if info.IsDir() {
+				files[path] = fileState{dir: true}
+				return nil
+			}
+			if w.filter == nil || w.filter(path) {
+				files[path] = fileState{modTime: info.ModTime(), size: info.Size()}
+			}
+			return nil
+		})
+	return files, err
+}
+
+// poll method returns the changes since the previous poll, sorted by path.
+// The changes of the contents of the folders are not returned,
+// but the ones of their files.
+func (w *watcher) poll() ([]change, error) {
+	files, err := w.scan()
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []change
+	for path, cur := range files {
+		old, ok := w.files[path]
+		switch {
+		case !ok:
+			changes = append(changes, change{path: path, op: opCreate, dir: cur.dir})
+		case cur.dir != old.dir:
+			changes = append(changes, change{path: path, op: opRemove, dir: old.dir}, change{path: path, op: opCreate, dir: cur.dir})
+		case !cur.dir && (cur.size != old.size || !cur.modTime.Equal(old.modTime)):
+			changes = append(changes, change{path: path, op: opWrite})
+		}
+	}
+	for path, old := range w.files {
+		if _, ok := files[path]; !ok {
+			changes = append(changes, change{path: path, op: opRemove, dir: old.dir})
+		}
+	}
+	changes = w.renames(changes, files)
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].path < changes[j].path
+	})
+
+	w.files = files
+	return changes, nil
+}
+
+// renames method replaces the remove and the create of the same file
+// with its rename. A file is renamed only if a single removed file
+// and a single created file have its modification time and size.
+func (w *watcher) renames(changes []change, files map[string]fileState) []change {
+	type key struct {
+		modTime int64
+		size    int64
+	}
+	removed := map[key][]int{}
+	created := map[key][]int{}
+	for j, c := range changes {
+		switch {
+		case c.dir:
+		case c.op == opRemove:
+			st := w.files[c.path]
+			k := key{st.modTime.UnixNano(), st.size}
+			removed[k] = append(removed[k], j)
+		case c.op == opCreate:
+			st := files[c.path]
+			k := key{st.modTime.UnixNano(), st.size}
+			created[k] = append(created[k], j)
+		}
+	}
+
+	drop := map[int]bool{}
+	for k, rj := range removed {
+		cj := created[k]
+		if len(rj) != 1 || len(cj) != 1 {
+			continue
+		}
+		changes[cj[0]].op = opRename
+		changes[cj[0]].from = changes[rj[0]].path
+		drop[rj[0]] = true
+	}
+	if len(drop) == 0 {
+		return changes
+	}
+
+	res := changes[:0]
+	for j, c := range changes {
+		if !drop[j] {
+			res = append(res, c)
+		}
+	}
+	return res
+}
+
+// run method polls the files every interval and calls fn with the changes, if any,
+// until the stop channel is closed. The poll errors are printed to stderr.
+func (w *watcher) run(interval time.Duration, stop <-chan struct{}, fn func([]change)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			changes, err := w.poll()
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				continue
+			}
+			if len(changes) > 0 {
+				fn(changes)
+			}
+		}
+	}
+}
+
+// folderWatcher keeps the output folder of the transform and hugo commands
+// in sync with the input folder: the changed chordpro files are transformed again,
+// and the outputs of the removed or renamed files are removed or moved.
+type folderWatcher struct {
+	opts        *Options
+	frontmatter frontmatterMode
+	multi       multiMode
+	formatter   chordpro.Formatter
+	hugo        bool                // the other files are copied, like the hugo command does
+	outputs     map[string][]string // output files of each chordpro file
+	manifest    *buildManifest      // build manifest of the output folder, or nil
+	optsHash    string
+}
+
+// newFolderWatcher function returns the folderWatcher of the options,
+// with the output files of the chordpro files already transformed.
+// The build manifest, if not nil, is updated with the changes.
+func newFolderWatcher(opts *Options, frontmatter frontmatterMode, multi multiMode, formatter chordpro.Formatter, hugo bool, manifest *buildManifest) (*folderWatcher, error) {
+	fw := &folderWatcher{
+		opts:        opts,
+		frontmatter: frontmatter,
+		multi:       multi,
+		formatter:   formatter,
+		hugo:        hugo,
+		outputs:     map[string][]string{},
+		manifest:    manifest,
+		optsHash:    optionsHash(opts),
+	}
+	err := filepath.Walk(opts.Input,
+		func(path string, info os.FileInfo, err error) error {
+			if err == nil && !info.IsDir() && isChordProFile(path) {
+				fw.outputs[path] = fw.outputFiles(path)
+			}
+			return err
+		})
+	return fw, err
+}
+
+// dstPath method returns the path of the output file of the input file.
+func (fw *folderWatcher) dstPath(src string) string {
+	rel, _ := filepath.Rel(fw.opts.Input, src)
+	dst := filepath.Join(fw.opts.Output, rel)
+	if isChordProFile(src) {
+		dst += fw.formatter.Extension()
+	}
+	return dst
+}
+
+// outputFiles method returns the output files of the chordpro file:
+// one file for each song in split mode, a single file otherwise.
+func (fw *folderWatcher) outputFiles(src string) []string {
+	return outputFiles(src, fw.dstPath(src), fw.multi, fw.formatter)
+}
+
+// removeOutputs method removes the output files of the chordpro file
+// that are not kept.
+func (fw *folderWatcher) removeOutputs(src string, keep []string) {
+	outs, ok := fw.outputs[src]
+	if !ok {
+		outs = []string{fw.dstPath(src)}
+	}
+	for _, out := range outs {
+		kept := false
+		for _, k := range keep {
+			kept = kept || k == out
+		}
+		if !kept {
+			if err := os.Remove(out); err != nil && !os.IsNotExist(err) {
+				fmt.Fprintln(os.Stderr, err)
+			}
+		}
+	}
+}
+
+// update method builds the outputs of the created or changed file,
+// and records them in the build manifest.
+func (fw *folderWatcher) update(src string) error {
+	err := fw.build(src)
+	if err == nil && fw.manifest != nil {
+		rel, _ := filepath.Rel(fw.opts.Input, src)
+		outs, ok := fw.outputs[src]
+		if !ok {
+			outs = []string{fw.dstPath(src)}
+		}
+		if hash, err := fileHash(src); err == nil {
+			fw.manifest.record(rel, hash, fw.optsHash, outs)
+		}
+	}
+	return err
+}
+
+// build method transforms the chordpro file,
+// or copies the other file in hugo mode.
+// The output files of the previous version that are not produced anymore,
+// like the ones of the songs removed from a file in split mode, are removed.
+func (fw *folderWatcher) build(src string) error {
+	dst := fw.dstPath(src)
+	if !isChordProFile(src) {
+		if err := os.MkdirAll(filepath.Dir(dst), 0700); err != nil {
+			return err
+		}
+		return copyFile(src, dst, modeOverwriteAll)
+	}
+
+	outs := fw.outputFiles(src)
+	fw.removeOutputs(src, outs)
+	fw.outputs[src] = outs
+
+	// the source has changed: its outputs are always overwritten
+	if fw.hugo {
+		return trasformFileHugo(src, dst, modeOverwriteAll, fw.multi, fw.formatter)
+	}
+	return trasformFile(src, dst, modeOverwriteAll, fw.frontmatter, fw.multi, fw.formatter)
+}
+
+// remove method removes the output files of the removed file,
+// including the ones recorded in the build manifest.
+func (fw *folderWatcher) remove(src string) {
+	if fw.manifest != nil {
+		rel, _ := filepath.Rel(fw.opts.Input, src)
+		for _, out := range fw.manifest.outputs(rel) {
+			if err := os.Remove(out); err != nil && !os.IsNotExist(err) {
+				fmt.Fprintln(os.Stderr, err)
+			}
+		}
+		fw.manifest.remove(rel)
+	}
+	if !isChordProFile(src) {
+		if err := os.Remove(fw.dstPath(src)); err != nil && !os.IsNotExist(err) {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		return
+	}
+	fw.removeOutputs(src, nil)
+	delete(fw.outputs, src)
+}
+
+// rename method moves the output files of the renamed file,
+// keeping their front matter, then it updates them.
+// If the outputs can't be moved one by one, they are removed and created again.
+func (fw *folderWatcher) rename(from, to string) error {
+	if isChordProFile(from) != isChordProFile(to) {
+		fw.remove(from)
+		return fw.update(to)
+	}
+
+	oldOuts, ok := fw.outputs[from]
+	if !ok {
+		oldOuts = []string{fw.dstPath(from)}
+	}
+	newOuts := fw.outputFiles(to)
+	if len(oldOuts) != len(newOuts) {
+		fw.remove(from)
+		return fw.update(to)
+	}
+
+	for j, out := range oldOuts {
+		if err := os.MkdirAll(filepath.Dir(newOuts[j]), 0700); err != nil {
+			return err
+		}
+		if err := os.Rename(out, newOuts[j]); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	delete(fw.outputs, from)
+	fw.outputs[to] = newOuts
+	if fw.manifest != nil {
+		rel, _ := filepath.Rel(fw.opts.Input, from)
+		fw.manifest.remove(rel)
+	}
+	return fw.update(to)
+}
+
+// handle method applies the changes of the input folder to the output folder.
+// The output folders of the removed folders are removed if empty,
+// and the "_index.md" files are created for the new folders in index mode,
+// where the generated ones of the removed folders are removed too.
+func (fw *folderWatcher) handle(changes []change) {
+	var removedDirs []string
+	newDirs := false
+
+	for _, c := range changes {
+		rel, _ := filepath.Rel(fw.opts.Input, c.path)
+		fmt.Println(c.op, rel)
+
+		var err error
+		switch {
+		case c.dir && c.op == opCreate:
+			err = os.MkdirAll(fw.dstPath(c.path), 0700)
+			newDirs = true
+		case c.dir:
+			removedDirs = append(removedDirs, fw.dstPath(c.path))
+		case c.op == opRemove:
+			fw.remove(c.path)
+		case c.op == opRename:
+			err = fw.rename(c.from, c.path)
+		default:
+			err = fw.update(c.path)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}
+
+	// remove the sub folders first
+	sort.Sort(sort.Reverse(sort.StringSlice(removedDirs)))
+	for _, dir := range removedDirs {
+		if fw.opts.Index {
+			if err := removeIndexMD(dir); err != nil && !os.IsNotExist(err) {
+				fmt.Fprintln(os.Stderr, err)
+			}
+		}
+		os.Remove(dir)
+	}
+
+	if newDirs && fw.opts.Index {
+		if err := createIndexMD(fw.opts.Output); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}
+
+	if fw.manifest != nil {
+		if err := fw.manifest.save(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}
+}
+
+// watch method keeps the output folder in sync with the input folder,
+// until the process is stopped.
+func (fw *folderWatcher) watch() error {
+	var filter func(string) bool
+	if !fw.hugo {
+		filter = isChordProFile
+	}
+	w, err := newWatcher(fw.opts.Input, filter)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("watching %s for changes\n", fw.opts.Input)
+	w.run(watchInterval, nil, fw.handle)
+	return nil
+}
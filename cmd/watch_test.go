@@ -0,0 +1,206 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mmbros/chordpro/pkg/chordpro"
+)
+
+func Test_watcher(t *testing.T) {
+	dir, err := ioutil.TempDir("", "watch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	song := filepath.Join(dir, "a.cho")
+	other := filepath.Join(dir, "notes.txt")
+	sub := filepath.Join(dir, "sub")
+	if err := ioutil.WriteFile(song, []byte("[C]do"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := newWatcher(dir, isChordProFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		action func() error
+		want   []change
+	}{
+		{
+			name:   "none",
+			action: func() error { return nil },
+		},
+		{
+			name: "create",
+			action: func() error {
+				if err := os.Mkdir(sub, 0700); err != nil {
+					return err
+				}
+				if err := ioutil.WriteFile(other, nil, 0600); err != nil {
+					return err
+				}
+				return ioutil.WriteFile(filepath.Join(sub, "b.chopro"), nil, 0600)
+			},
+			want: []change{
+				{path: sub, op: opCreate, dir: true},
+				{path: filepath.Join(sub, "b.chopro"), op: opCreate},
+			},
+		},
+		{
+			name: "write",
+			action: func() error {
+				t := time.Now().Add(time.Hour)
+				return os.Chtimes(song, t, t)
+			},
+			want: []change{{path: song, op: opWrite}},
+		},
+		{
+			name: "rename",
+			action: func() error {
+				return os.Rename(song, filepath.Join(dir, "c.cho"))
+			},
+			want: []change{
+				{path: filepath.Join(dir, "c.cho"), op: opRename, from: song},
+			},
+		},
+		{
+			name: "remove",
+			action: func() error {
+				return os.RemoveAll(sub)
+			},
+			want: []change{
+				{path: sub, op: opRemove, dir: true},
+				{path: filepath.Join(sub, "b.chopro"), op: opRemove},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.action(); err != nil {
+				t.Fatal(err)
+			}
+			got, err := w.poll()
+			if err != nil {
+				t.Fatalf("unexpected error %q", err.Error())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func Test_folderWatcher(t *testing.T) {
+	dir, err := ioutil.TempDir("", "watch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	in := filepath.Join(dir, "in")
+	out := filepath.Join(dir, "out")
+	write := func(name, src string) {
+		if err := os.MkdirAll(filepath.Dir(name), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(name, []byte(src), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	exists := func(name string) bool {
+		_, err := os.Stat(name)
+		return err == nil
+	}
+
+	write(filepath.Join(in, "a.cho"), "{title: A}\n[C]do\n")
+	opts := &Options{Input: in, Output: out, Index: true}
+	fw, err := newFolderWatcher(opts, modeFrontmatterPreserve, modeMultiSplit, chordpro.HtmlFormatter{}, false, loadManifest(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := newWatcher(in, isChordProFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	step := func() {
+		changes, err := w.poll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.handle(changes)
+	}
+
+	// create
+	write(filepath.Join(in, "rock", "b.cho"), "{title: B}\n[D]re\n")
+	step()
+	if !exists(filepath.Join(out, "rock", "b.cho.html")) {
+		t.Errorf("expected %s file", "rock/b.cho.html")
+	}
+	if !exists(filepath.Join(out, "rock", "_index.md")) {
+		t.Errorf("expected %s file", "rock/_index.md")
+	}
+
+	// rename keeps the front matter
+	write(filepath.Join(out, "rock", "b.cho.html"), "---\ntitle: kept\n---\nold")
+	if err := os.Rename(filepath.Join(in, "rock", "b.cho"), filepath.Join(in, "rock", "c.cho")); err != nil {
+		t.Fatal(err)
+	}
+	step()
+	if exists(filepath.Join(out, "rock", "b.cho.html")) {
+		t.Errorf("expected no %s file", "rock/b.cho.html")
+	}
+	data, _ := ioutil.ReadFile(filepath.Join(out, "rock", "c.cho.html"))
+	if !strings.HasPrefix(string(data), "---\ntitle: kept\n---\n<div") {
+		t.Errorf("expected the kept front matter, got %q", string(data))
+	}
+
+	// split and remove a song
+	write(filepath.Join(in, "rock", "c.cho"), "{title: One}\n[C]do\n{ns}\n{title: Two}\n[D]re\n")
+	step()
+	for _, name := range []string{"c.cho-one.html", "c.cho-two.html"} {
+		if !exists(filepath.Join(out, "rock", name)) {
+			t.Errorf("expected %s file", name)
+		}
+	}
+	if exists(filepath.Join(out, "rock", "c.cho.html")) {
+		t.Errorf("expected no %s file", "rock/c.cho.html")
+	}
+	write(filepath.Join(in, "rock", "c.cho"), "{title: One}\n[C]do do\n")
+	step()
+	if exists(filepath.Join(out, "rock", "c.cho-two.html")) {
+		t.Errorf("expected no %s file", "rock/c.cho-two.html")
+	}
+
+	// remove, with the outputs recorded by a previous build
+	old := filepath.Join(out, "a.cho-old.html")
+	write(old, "")
+	fw.manifest.record("a.cho", "i", "o", []string{old})
+	if err := os.Remove(filepath.Join(in, "a.cho")); err != nil {
+		t.Fatal(err)
+	}
+	step()
+	for _, name := range []string{"a.cho.html", "a.cho-old.html"} {
+		if exists(filepath.Join(out, name)) {
+			t.Errorf("expected no %s file", name)
+		}
+	}
+
+	// remove a folder with its generated "_index.md"
+	if err := os.RemoveAll(filepath.Join(in, "rock")); err != nil {
+		t.Fatal(err)
+	}
+	step()
+	if exists(filepath.Join(out, "rock")) {
+		t.Errorf("expected no %s folder", "rock")
+	}
+}
@@ -28,7 +28,6 @@
 package lexer
 
 import (
-	"errors"
 	"fmt"
 	"strings"
 	"unicode/utf8"
@@ -43,17 +42,44 @@ const (
 	EmptyToken TokenType = 0
 )
 
+// Position is a position in the source.
+type Position struct {
+	Offset int // byte offset, starting from 0
+	Line   int // line number, starting from 1
+	Col    int // column number in runes, starting from 1
+}
+
+// String returns the position as "line:col".
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Col)
+}
+
 type Token struct {
 	Type  TokenType
 	Value string
+	Pos   Position // position of the first rune of the token
+}
+
+// Error is an error of the lexer, at the position of the last rune read.
+type Error struct {
+	Pos Position
+	Msg string
+}
+
+// Error returns the message of the error, without the position.
+func (e *Error) Error() string {
+	return e.Msg
 }
 
 type L struct {
 	source          string
 	start, position int
+	startPos, pos   Position
 	startState      StateFunc
 	Err             error
 	tokens          chan Token
+	queue           []Token // tokens of the synchronous mode
+	sync            bool
 	ErrorHandler    func(l *L)
 	rewind          runeStack
 }
@@ -65,6 +91,8 @@ func New(src string, start StateFunc) *L {
 		startState: start,
 		start:      0,
 		position:   0,
+		startPos:   Position{Line: 1, Col: 1},
+		pos:        Position{Line: 1, Col: 1},
 		rewind:     newRuneStack(),
 	}
 }
@@ -80,13 +108,10 @@ func (l *L) Start() {
 	go l.run()
 }
 
+// StartSync executes the Lexer up to the end of the source, before returning.
+// The tokens are queued, whatever their number.
 func (l *L) StartSync() {
-	// Take half the string length as a buffer size.
-	buffSize := len(l.source) / 2
-	if buffSize <= 0 {
-		buffSize = 1
-	}
-	l.tokens = make(chan Token, buffSize)
+	l.sync = true
 	l.run()
 }
 
@@ -101,9 +126,15 @@ func (l *L) Emit(t TokenType) {
 	tok := Token{
 		Type:  t,
 		Value: l.Current(),
+		Pos:   l.startPos,
+	}
+	if l.sync {
+		l.queue = append(l.queue, tok)
+	} else {
+		l.tokens <- tok
 	}
-	l.tokens <- tok
 	l.start = l.position
+	l.startPos = l.pos
 	l.rewind.clear()
 }
 
@@ -113,6 +144,7 @@ func (l *L) Emit(t TokenType) {
 func (l *L) Ignore() {
 	l.rewind.clear()
 	l.start = l.position
+	l.startPos = l.pos
 }
 
 // Peek performs a Next operation immediately followed by a Rewind returning the
@@ -128,12 +160,14 @@ func (l *L) Peek() rune {
 // occur more than once per call to Next but you can never rewind past the
 // last point a token was emitted.
 func (l *L) Rewind() {
-	r := l.rewind.pop()
+	r, pos := l.rewind.pop()
 	if r > EOFRune {
 		size := utf8.RuneLen(r)
 		l.position -= size
+		l.pos = pos
 		if l.position < l.start {
 			l.position = l.start
+			l.pos = l.startPos
 		}
 	}
 }
@@ -151,8 +185,18 @@ func (l *L) Next() rune {
 	} else {
 		r, s = utf8.DecodeRuneInString(str)
 	}
+	l.rewind.push(r, l.pos)
 	l.position += s
-	l.rewind.push(r)
+
+	// a newline is "\r\n", "\n" or "\r"
+	l.pos.Offset = l.position
+	switch {
+	case r == '\n', r == '\r' && !strings.HasPrefix(l.source[l.position:], "\n"):
+		l.pos.Line++
+		l.pos.Col = 1
+	case r != EOFRune:
+		l.pos.Col++
+	}
 
 	return r
 }
@@ -171,6 +215,14 @@ func (l *L) Take(chars string) {
 // NextToken returns the next token from the lexer and a value to denote whether
 // or not the token is finished.
 func (l *L) NextToken() (*Token, bool) {
+	if l.sync {
+		if len(l.queue) == 0 {
+			return nil, true
+		}
+		tok := l.queue[0]
+		l.queue = l.queue[1:]
+		return &tok, false
+	}
 	if tok, ok := <-l.tokens; ok {
 		return &tok, false
 	} else {
@@ -180,13 +232,22 @@ func (l *L) NextToken() (*Token, bool) {
 
 // Partial yyLexer implementation
 
+// Error reports the error e, at the position of the last rune read,
+// to the ErrorHandler. Without an ErrorHandler, the error is only kept in Err.
 func (l *L) Error(e string) {
+	pos := l.pos
+	if last, ok := l.rewind.peek(); ok {
+		pos = last
+	}
+	l.ErrorAt(pos, e)
+}
 
+// ErrorAt reports the error e, at the position pos, to the ErrorHandler.
+// Without an ErrorHandler, the error is only kept in Err.
+func (l *L) ErrorAt(pos Position, e string) {
+	l.Err = &Error{Pos: pos, Msg: e}
 	if l.ErrorHandler != nil {
-		l.Err = errors.New(e)
 		l.ErrorHandler(l)
-	} else {
-		panic(e)
 	}
 }
 
@@ -197,39 +258,17 @@ func (l *L) run() {
 	for state != nil {
 		state = state(l)
 	}
-	close(l.tokens)
+	if !l.sync {
+		close(l.tokens)
+	}
 }
 
-func (l *L) Position() (siz, lin, col int) {
-	p := l.position
-	s := l.source
-	siz = p
-	lin = 1
-	for {
-		col = p
-		fmt.Printf("(%d, %d) %q\n", lin, col, s)
-
-		j := strings.IndexAny(s, "\n\r")
-		if j < 0 {
-			return
-		}
-
-		if len(s) > j+1 {
-			if (s[j] == '\n') && (s[j+1] == '\r') {
-				j++
-			}
-		}
-		j++
-
-		if p <= j {
-			return
-		}
-
-		s = s[j:]
-
-		p -= j
-		lin++
-
-	}
+// TokenPosition returns the position of the token being analyzed.
+func (l *L) TokenPosition() Position {
+	return l.startPos
+}
 
+// Position returns the current position in the source.
+func (l *L) Position() Position {
+	return l.pos
 }
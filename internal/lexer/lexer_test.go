@@ -200,3 +200,70 @@ func Test_LexerError(t *testing.T) {
 		return
 	}
 }
+
+func Test_LexerPosition(t *testing.T) {
+	cases := []struct {
+		val string
+		pos Position
+	}{
+		{"12", Position{0, 1, 1}},
+		{".", Position{2, 1, 3}},
+		{"ab", Position{3, 1, 4}},
+		{"3", Position{8, 3, 1}},
+		{".", Position{9, 3, 2}},
+		{"cd", Position{10, 3, 3}},
+		{"4", Position{15, 4, 2}},
+	}
+
+	l := New("12.ab\r\n\n3.cd \r 4", NumberState)
+	l.Start()
+
+	for _, c := range cases {
+		tok, done := l.NextToken()
+		if done {
+			t.Fatal("Expected there to be more tokens, but there weren't")
+		}
+		if tok.Value != c.val || tok.Pos != c.pos {
+			t.Errorf("Expected %q at %v but got %q at %v", c.val, c.pos, tok.Value, tok.Pos)
+		}
+	}
+}
+
+func Test_LexerErrorPosition(t *testing.T) {
+	l := New("1.a\n2.ab!", NumberState)
+	l.ErrorHandler = func(*L) {}
+	l.Start()
+	for _, done := l.NextToken(); !done; _, done = l.NextToken() {
+	}
+
+	err, ok := l.Err.(*Error)
+	if !ok {
+		t.Fatalf("Expected a lexer error, but got %v", l.Err)
+	}
+	if expected := (Position{8, 2, 5}); err.Pos != expected {
+		t.Errorf("Expected error at %v but got %v", expected, err.Pos)
+	}
+}
+
+func Test_LexerStartSync(t *testing.T) {
+	// more tokens than half the length of the source
+	l := New("1.a 2.b 3.c 4.d", NumberState)
+	l.StartSync()
+
+	n := 0
+	for _, done := l.NextToken(); !done; _, done = l.NextToken() {
+		n++
+	}
+	if n != 12 {
+		t.Errorf("Expected %d tokens but got %d", 12, n)
+	}
+}
+
+func Test_LexerErrorWithoutHandler(t *testing.T) {
+	l := New("1", WhitespaceState)
+	l.StartSync()
+
+	if l.Err == nil || l.Err.Error() != "unexpected token '1'" {
+		t.Errorf("Expected the error to be on the lexer, but got %v", l.Err)
+	}
+}
@@ -2,6 +2,7 @@ package lexer
 
 type runeNode struct {
 	r    rune
+	pos  Position // position of the rune
 	next *runeNode
 }
 
@@ -13,8 +14,8 @@ func newRuneStack() runeStack {
 	return runeStack{}
 }
 
-func (s *runeStack) push(r rune) {
-	node := &runeNode{r: r}
+func (s *runeStack) push(r rune, pos Position) {
+	node := &runeNode{r: r, pos: pos}
 	if s.start == nil {
 		s.start = node
 	} else {
@@ -23,16 +24,24 @@ func (s *runeStack) push(r rune) {
 	}
 }
 
-func (s *runeStack) pop() rune {
+func (s *runeStack) pop() (rune, Position) {
 	if s.start == nil {
-		return EOFRune
+		return EOFRune, Position{}
 	} else {
 		n := s.start
 		s.start = n.next
-		return n.r
+		return n.r, n.pos
 	}
 }
 
+// peek returns the position of the last rune pushed, if any.
+func (s *runeStack) peek() (Position, bool) {
+	if s.start == nil {
+		return Position{}, false
+	}
+	return s.start.pos, true
+}
+
 func (s *runeStack) clear() {
 	s.start = nil
 }
@@ -0,0 +1,498 @@
+// Package pdf implements a minimal writer of PDF documents.
+//
+// Only the features needed to typeset text are supported:
+// pages, text in the standard Type 1 fonts or in embedded TrueType fonts,
+// lines, internal links and a flat outline (bookmarks).
+// The standard fonts are part of every conforming PDF reader,
+// so the documents don't depend on external font files,
+// but they only have the characters of the WinAnsiEncoding (Latin-1).
+// Any other character needs an embedded TrueType font.
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf16"
+)
+
+// Font is one of the standard Type 1 fonts.
+type Font int
+
+// Standard fonts
+const (
+	Courier Font = iota
+	CourierBold
+	CourierOblique
+	Helvetica
+	HelveticaBold
+	HelveticaOblique
+)
+
+var fontNames = []string{
+	"Courier",
+	"Courier-Bold",
+	"Courier-Oblique",
+	"Helvetica",
+	"Helvetica-Bold",
+	"Helvetica-Oblique",
+}
+
+// CourierWidth is the width of every glyph of the Courier fonts,
+// in units of the font size.
+const CourierWidth = 0.6
+
+// Page sizes in points.
+const (
+	A4Width  = 595.28
+	A4Height = 841.89
+)
+
+type link struct {
+	x1, y1, x2, y2 float64
+	page           int
+}
+
+type outline struct {
+	title string
+	page  int
+}
+
+// Page is a page of the document.
+// The origin of the coordinates is the bottom left corner.
+type Page struct {
+	Width, Height float64
+	doc           *Document
+	content       bytes.Buffer
+	links         []link
+}
+
+// Document is a PDF document.
+type Document struct {
+	Title    string
+	Author   string
+	pages    []*Page
+	outlines []outline
+	fonts    map[Font]*TrueType
+	used     map[*TrueType]map[uint16]rune // used glyphs of the fonts, with their rune
+}
+
+// New returns an empty document.
+func New() *Document {
+	return &Document{}
+}
+
+// AddPage appends a new page of the given size to the document.
+func (d *Document) AddPage(width, height float64) *Page {
+	p := &Page{Width: width, Height: height, doc: d}
+	d.pages = append(d.pages, p)
+	return p
+}
+
+// NumPages returns the number of pages of the document.
+func (d *Document) NumPages() int {
+	return len(d.pages)
+}
+
+// AddOutline adds a bookmark to the page with the given index (zero based).
+func (d *Document) AddOutline(title string, page int) {
+	d.outlines = append(d.outlines, outline{title, page})
+}
+
+// SetFont replaces the standard font with the TrueType font,
+// embedded in the document with the glyphs of its text.
+// The same TrueType font can replace many standard fonts.
+func (d *Document) SetFont(font Font, t *TrueType) {
+	if d.fonts == nil {
+		d.fonts = map[Font]*TrueType{}
+		d.used = map[*TrueType]map[uint16]rune{}
+	}
+	d.fonts[font] = t
+	if d.used[t] == nil {
+		d.used[t] = map[uint16]rune{}
+	}
+}
+
+// Text prints the string s at the (x, y) position of the baseline.
+func (p *Page) Text(x, y float64, font Font, size float64, s string) {
+	str := "(" + escape(encode(s)) + ")"
+	if t := p.doc.fonts[font]; t != nil {
+		used := p.doc.used[t]
+		var sb strings.Builder
+		sb.WriteByte('<')
+		for _, r := range s {
+			gid := t.glyph(r)
+			if _, ok := used[gid]; !ok && gid != 0 {
+				used[gid] = r
+			}
+			fmt.Fprintf(&sb, "%04X", gid)
+		}
+		sb.WriteByte('>')
+		str = sb.String()
+	}
+	fmt.Fprintf(&p.content, "BT /F%d %s Tf %s %s Td %s Tj ET\n",
+		font+1, num(size), num(x), num(y), str)
+}
+
+// Line draws a line from (x1, y1) to (x2, y2).
+func (p *Page) Line(x1, y1, x2, y2, width float64) {
+	fmt.Fprintf(&p.content, "%s w %s %s m %s %s l S\n",
+		num(width), num(x1), num(y1), num(x2), num(y2))
+}
+
+// Link makes the rectangle a link to the page
+// with the given index (zero based).
+func (p *Page) Link(x1, y1, x2, y2 float64, page int) {
+	p.links = append(p.links, link{x1, y1, x2, y2, page})
+}
+
+// num formats a number with at most two decimals.
+func num(f float64) string {
+	s := fmt.Sprintf("%.2f", f)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+// winAnsi maps the runes of the range 0x80-0x9F of the WinAnsiEncoding.
+var winAnsi = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87,
+	'ˆ': 0x88, '‰': 0x89, 'Š': 0x8A, '‹': 0x8B, 'Œ': 0x8C, 'Ž': 0x8E,
+	'‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97,
+	'˜': 0x98, '™': 0x99, 'š': 0x9A, '›': 0x9B, 'œ': 0x9C, 'ž': 0x9E, 'Ÿ': 0x9F,
+}
+
+// Encodable reports whether the rune is in the WinAnsiEncoding
+// of the standard fonts.
+func Encodable(r rune) bool {
+	return r < 0x80 || (r >= 0xA0 && r <= 0xFF) || winAnsi[r] != 0
+}
+
+// encode converts the string to the WinAnsiEncoding
+// used by the fonts of the document.
+// Runes that can't be encoded are replaced by '?'.
+func encode(s string) []byte {
+	b := make([]byte, 0, len(s))
+	for _, r := range s {
+		switch {
+		case r < 0x80 || (r >= 0xA0 && r <= 0xFF):
+			b = append(b, byte(r))
+		case winAnsi[r] != 0:
+			b = append(b, winAnsi[r])
+		default:
+			b = append(b, '?')
+		}
+	}
+	return b
+}
+
+// textString function returns the PDF text string of s, like the title
+// of the document: a literal string if s is ASCII, UTF-16BE otherwise.
+func textString(s string) string {
+	ascii := true
+	for _, r := range s {
+		ascii = ascii && r < 0x80
+	}
+	if ascii {
+		return "(" + escape([]byte(s)) + ")"
+	}
+	var sb strings.Builder
+	sb.WriteString("<FEFF")
+	for _, u := range utf16.Encode([]rune(s)) {
+		fmt.Fprintf(&sb, "%04X", u)
+	}
+	sb.WriteByte('>')
+	return sb.String()
+}
+
+// escape escapes the characters with special meaning in a PDF string.
+func escape(b []byte) string {
+	var sb strings.Builder
+	for _, c := range b {
+		switch c {
+		case '\\', '(', ')':
+			sb.WriteByte('\\')
+			sb.WriteByte(c)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\n':
+			sb.WriteString(`\n`)
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String()
+}
+
+// writer keeps track of the offsets of the objects.
+type writer struct {
+	w       io.Writer
+	n       int64
+	offsets []int64
+	err     error
+}
+
+func (pw *writer) printf(format string, a ...interface{}) {
+	if pw.err != nil {
+		return
+	}
+	n, err := fmt.Fprintf(pw.w, format, a...)
+	pw.n += int64(n)
+	pw.err = err
+}
+
+func (pw *writer) write(b []byte) {
+	if pw.err != nil {
+		return
+	}
+	n, err := pw.w.Write(b)
+	pw.n += int64(n)
+	pw.err = err
+}
+
+// beginObj begins the object with the given number.
+// The objects must be written in order.
+func (pw *writer) beginObj(id int) {
+	pw.offsets[id] = pw.n
+	pw.printf("%d 0 obj\n", id)
+}
+
+func (pw *writer) endObj() {
+	pw.printf("endobj\n")
+}
+
+// WriteTo writes the document to w.
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	// object numbers
+	const (
+		idCatalog = 1
+		idPages   = 2
+		idInfo    = 3
+		idFonts   = 4
+	)
+	idFirstPage := idFonts + len(fontNames)
+	pageID := func(j int) int { return idFirstPage + 2*j }
+	idOutlines := idFirstPage + 2*len(d.pages)
+	total := idOutlines - 1
+	if len(d.outlines) > 0 {
+		total = idOutlines + len(d.outlines)
+	}
+
+	// the embedded fonts follow, with 5 objects each,
+	// in the order of the standard fonts they replace
+	var embedded []*TrueType
+	embeddedID := map[*TrueType]int{}
+	fontID := make([]int, len(fontNames))
+	for k := range fontNames {
+		fontID[k] = idFonts + k
+		t := d.fonts[Font(k)]
+		if t == nil {
+			continue
+		}
+		if _, ok := embeddedID[t]; !ok {
+			embedded = append(embedded, t)
+			embeddedID[t] = total + 1
+			total += 5
+		}
+		fontID[k] = embeddedID[t]
+	}
+
+	pw := &writer{w: w, offsets: make([]int64, total+1)}
+	pw.printf("%%PDF-1.4\n%%\xe2\xe3\xcf\xd3\n")
+
+	// catalog
+	pw.beginObj(idCatalog)
+	if len(d.outlines) > 0 {
+		pw.printf("<< /Type /Catalog /Pages %d 0 R /Outlines %d 0 R /PageMode /UseOutlines >>\n", idPages, idOutlines)
+	} else {
+		pw.printf("<< /Type /Catalog /Pages %d 0 R >>\n", idPages)
+	}
+	pw.endObj()
+
+	// pages
+	pw.beginObj(idPages)
+	pw.printf("<< /Type /Pages /Count %d /Kids [", len(d.pages))
+	for j := range d.pages {
+		pw.printf(" %d 0 R", pageID(j))
+	}
+	pw.printf(" ] >>\n")
+	pw.endObj()
+
+	// info
+	pw.beginObj(idInfo)
+	pw.printf("<< /Producer (chordpro)")
+	if d.Title != "" {
+		pw.printf(" /Title %s", textString(d.Title))
+	}
+	if d.Author != "" {
+		pw.printf(" /Author %s", textString(d.Author))
+	}
+	pw.printf(" >>\n")
+	pw.endObj()
+
+	// fonts
+	for j, name := range fontNames {
+		pw.beginObj(idFonts + j)
+		pw.printf("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>\n", name)
+		pw.endObj()
+	}
+
+	// pages and contents
+	for j, p := range d.pages {
+		pw.beginObj(pageID(j))
+		pw.printf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %s %s] /Contents %d 0 R",
+			idPages, num(p.Width), num(p.Height), pageID(j)+1)
+		pw.printf(" /Resources << /Font <<")
+		for k := range fontNames {
+			pw.printf(" /F%d %d 0 R", k+1, fontID[k])
+		}
+		pw.printf(" >> >>")
+		if len(p.links) > 0 {
+			pw.printf(" /Annots [")
+			for _, l := range p.links {
+				pw.printf(" << /Type /Annot /Subtype /Link /Border [0 0 0] /Rect [%s %s %s %s] /Dest [%d 0 R /Fit] >>",
+					num(l.x1), num(l.y1), num(l.x2), num(l.y2), pageID(l.page))
+			}
+			pw.printf(" ]")
+		}
+		pw.printf(" >>\n")
+		pw.endObj()
+
+		pw.writeStream(pageID(j)+1, "", p.content.Bytes())
+	}
+
+	// outlines
+	if len(d.outlines) > 0 {
+		pw.beginObj(idOutlines)
+		pw.printf("<< /Type /Outlines /First %d 0 R /Last %d 0 R /Count %d >>\n",
+			idOutlines+1, idOutlines+len(d.outlines), len(d.outlines))
+		pw.endObj()
+		for j, o := range d.outlines {
+			id := idOutlines + 1 + j
+			pw.beginObj(id)
+			pw.printf("<< /Title %s /Parent %d 0 R /Dest [%d 0 R /Fit]", textString(o.title), idOutlines, pageID(o.page))
+			if j > 0 {
+				pw.printf(" /Prev %d 0 R", id-1)
+			}
+			if j < len(d.outlines)-1 {
+				pw.printf(" /Next %d 0 R", id+1)
+			}
+			pw.printf(" >>\n")
+			pw.endObj()
+		}
+	}
+
+	for _, t := range embedded {
+		pw.writeTrueType(embeddedID[t], t, d.used[t])
+	}
+
+	// cross reference table
+	xref := pw.n
+	pw.printf("xref\n0 %d\n0000000000 65535 f \n", total+1)
+	for id := 1; id <= total; id++ {
+		pw.printf("%010d 00000 n \n", pw.offsets[id])
+	}
+	pw.printf("trailer\n<< /Size %d /Root %d 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n",
+		total+1, idCatalog, idInfo, xref)
+
+	return pw.n, pw.err
+}
+
+// writeStream writes the object with the stream of the data, compressed,
+// and the given entries of its dictionary.
+func (pw *writer) writeStream(id int, entries string, data []byte) {
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	zw.Write(data)
+	zw.Close()
+
+	pw.beginObj(id)
+	pw.printf("<< /Length %d /Filter /FlateDecode%s >>\nstream\n", buf.Len(), entries)
+	pw.write(buf.Bytes())
+	pw.printf("\nendstream\n")
+	pw.endObj()
+}
+
+// writeTrueType writes the objects of the embedded TrueType font,
+// with the subset of the used glyphs, starting from the given object number:
+// the font, its descendant CID font, the font descriptor,
+// the font file and the map of the glyphs to unicode.
+// The text of the font is encoded by the glyph ids (Identity-H).
+func (pw *writer) writeTrueType(id int, t *TrueType, used map[uint16]rune) {
+	name := subsetTag(used) + "+" + t.name
+	gids := make([]int, 0, len(used)+1)
+	gids = append(gids, 0)
+	for gid := range used {
+		gids = append(gids, int(gid))
+	}
+	sort.Ints(gids)
+
+	pw.beginObj(id)
+	pw.printf("<< /Type /Font /Subtype /Type0 /BaseFont /%s /Encoding /Identity-H /DescendantFonts [%d 0 R] /ToUnicode %d 0 R >>\n",
+		name, id+1, id+4)
+	pw.endObj()
+
+	pw.beginObj(id + 1)
+	pw.printf("<< /Type /Font /Subtype /CIDFontType2 /BaseFont /%s", name)
+	pw.printf(" /CIDSystemInfo << /Registry (Adobe) /Ordering (Identity) /Supplement 0 >>")
+	pw.printf(" /FontDescriptor %d 0 R /CIDToGIDMap /Identity /W [", id+2)
+	for _, gid := range gids {
+		pw.printf(" %d [%d]", gid, t.scale(t.advances[gid]))
+	}
+	pw.printf(" ] >>\n")
+	pw.endObj()
+
+	flags := 32 // nonsymbolic
+	if t.fixedPitch {
+		flags |= 1
+	}
+	if t.italicAngle != 0 {
+		flags |= 64
+	}
+	pw.beginObj(id + 2)
+	pw.printf("<< /Type /FontDescriptor /FontName /%s /Flags %d /FontBBox [%d %d %d %d] /ItalicAngle %s",
+		name, flags, t.scale(t.bbox[0]), t.scale(t.bbox[1]), t.scale(t.bbox[2]), t.scale(t.bbox[3]), num(t.italicAngle))
+	pw.printf(" /Ascent %d /Descent %d /CapHeight %d /StemV 80 /FontFile2 %d 0 R >>\n",
+		t.scale(t.ascent), t.scale(t.descent), t.scale(t.capHeight), id+3)
+	pw.endObj()
+
+	font := t.subset(used)
+	pw.writeStream(id+3, fmt.Sprintf(" /Length1 %d", len(font)), font)
+
+	var cmap bytes.Buffer
+	cmap.WriteString(`/CIDInit /ProcSet findresource begin
+12 dict begin
+begincmap
+/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def
+/CMapName /Adobe-Identity-UCS def
+/CMapType 2 def
+1 begincodespacerange
+<0000> <FFFF>
+endcodespacerange
+`)
+	gids = gids[1:] // .notdef has no unicode
+	for len(gids) > 0 {
+		n := len(gids)
+		if n > 100 {
+			n = 100 // entries of a block at most
+		}
+		fmt.Fprintf(&cmap, "%d beginbfchar\n", n)
+		for _, gid := range gids[:n] {
+			fmt.Fprintf(&cmap, "<%04X> <", gid)
+			for _, u := range utf16.Encode([]rune{used[uint16(gid)]}) {
+				fmt.Fprintf(&cmap, "%04X", u)
+			}
+			cmap.WriteString(">\n")
+		}
+		cmap.WriteString("endbfchar\n")
+		gids = gids[n:]
+	}
+	cmap.WriteString(`endcmap
+CMapName currentdict /CMap defineresource pop
+end
+end
+`)
+	pw.writeStream(id+4, "", cmap.Bytes())
+}
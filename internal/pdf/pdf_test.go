@@ -0,0 +1,100 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+func Test_encode(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"abc", "abc"},
+		{"perché", "perch\xe9"},
+		{"“quoted”", "\x93quoted\x94"},
+		{"日本", "??"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := string(encode(tt.input)); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func Test_escape(t *testing.T) {
+	want := `a\(b\)\\c`
+	if got := escape([]byte(`a(b)\c`)); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestDocument_WriteTo(t *testing.T) {
+	doc := New()
+	doc.Title = "Test"
+	for j := 0; j < 3; j++ {
+		p := doc.AddPage(A4Width, A4Height)
+		p.Text(50, 700, Helvetica, 12, fmt.Sprintf("page %d", j+1))
+		p.Link(50, 700, 100, 712, 0)
+	}
+	doc.AddOutline("first", 0)
+
+	var buf bytes.Buffer
+	n, err := doc.WriteTo(&buf)
+	if err != nil {
+		t.Fatalf("unexpected error %q", err.Error())
+	}
+	if int(n) != buf.Len() {
+		t.Errorf("written bytes: expected %d, got %d", buf.Len(), n)
+	}
+	data := buf.Bytes()
+	checkXref(t, data)
+
+	if got := bytes.Count(data, []byte("/Type /Page ")); got != 3 {
+		t.Errorf("pages: expected %d, got %d", 3, got)
+	}
+}
+
+// checkXref function checks the offsets of the cross reference table.
+func checkXref(t *testing.T, data []byte) {
+	t.Helper()
+	m := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(data)
+	if m == nil {
+		t.Fatal("startxref not found")
+	}
+	xref, _ := strconv.Atoi(string(m[1]))
+	m = regexp.MustCompile(`^xref\n0 (\d+)\n`).FindSubmatch(data[xref:])
+	if m == nil {
+		t.Fatal("xref table not found")
+	}
+	size, _ := strconv.Atoi(string(m[1]))
+	entries := data[xref+len(m[0]):]
+	for id := 1; id < size; id++ {
+		off, _ := strconv.Atoi(string(entries[20*id : 20*id+10]))
+		want := fmt.Sprintf("%d 0 obj", id)
+		if !bytes.HasPrefix(data[off:], []byte(want)) {
+			t.Errorf("object %d: wrong offset %d", id, off)
+		}
+	}
+}
+
+func Test_textString(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"a(b)", `(a\(b\))`},
+		{"perché", "<FEFF0070006500720063006800E9>"},
+		{"日本", "<FEFF65E5672C>"},
+	}
+	for _, tt := range tests {
+		if got := textString(tt.input); got != tt.want {
+			t.Errorf("expected %q, got %q", tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,447 @@
+package pdf
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sort"
+	"strings"
+	"unicode/utf16"
+)
+
+// ErrInvalidFont is returned when a font file is not a TrueType font
+// that can be embedded in a document.
+var ErrInvalidFont = errors.New("invalid TrueType font")
+
+// TrueType is a TrueType font to embed in a document.
+// Only the glyphs of the printed text are embedded.
+type TrueType struct {
+	name        string // PostScript name
+	unitsPerEm  int
+	bbox        [4]int // xMin, yMin, xMax, yMax
+	ascent      int
+	descent     int
+	capHeight   int
+	italicAngle float64
+	fixedPitch  bool
+	numGlyphs   int
+	advances    []int // advance width of each glyph
+	loca        []int // offsets of the glyphs in the glyf table, numGlyphs+1
+	cmap        map[rune]uint16
+	tables      map[string][]byte
+}
+
+// tables of the embedded subset: the ones needed by the PDF readers
+// to draw the glyphs, with the instructions of the font
+var subsetTables = []string{"cvt ", "fpgm", "glyf", "head", "hhea", "hmtx", "loca", "maxp", "prep"}
+
+func u16(b []byte, off int) int { return int(binary.BigEndian.Uint16(b[off:])) }
+func i16(b []byte, off int) int { return int(int16(binary.BigEndian.Uint16(b[off:]))) }
+func u32(b []byte, off int) int { return int(binary.BigEndian.Uint32(b[off:])) }
+
+// ParseTrueType parses the data of a TrueType font file (.ttf).
+// The OpenType fonts with PostScript outlines (.otf) and the font collections
+// (.ttc) are not supported, nor the fonts whose license doesn't allow embedding.
+func ParseTrueType(data []byte) (t *TrueType, err error) {
+	// the bounds of the tables are checked in parseTables:
+	// a malformed table is reported as an invalid font
+	defer func() {
+		if r := recover(); r != nil {
+			t, err = nil, fmt.Errorf("%w: malformed table", ErrInvalidFont)
+		}
+	}()
+
+	tables, err := parseTables(data)
+	if err != nil {
+		return nil, err
+	}
+	for _, tag := range []string{"head", "hhea", "maxp", "hmtx", "cmap", "loca", "glyf"} {
+		if tables[tag] == nil {
+			return nil, fmt.Errorf("%w: missing %q table", ErrInvalidFont, tag)
+		}
+	}
+
+	t = &TrueType{tables: tables, name: "Font"}
+
+	head := tables["head"]
+	t.unitsPerEm = u16(head, 18)
+	if t.unitsPerEm == 0 {
+		return nil, fmt.Errorf("%w: zero units per em", ErrInvalidFont)
+	}
+	t.bbox = [4]int{i16(head, 36), i16(head, 38), i16(head, 40), i16(head, 42)}
+	longLoca := i16(head, 50) == 1
+
+	hhea := tables["hhea"]
+	t.ascent, t.descent = i16(hhea, 4), i16(hhea, 6)
+	t.capHeight = t.ascent
+	numHMetrics := u16(hhea, 34)
+
+	t.numGlyphs = u16(tables["maxp"], 4)
+	if numHMetrics == 0 || numHMetrics > t.numGlyphs {
+		return nil, fmt.Errorf("%w: invalid number of metrics", ErrInvalidFont)
+	}
+
+	hmtx := tables["hmtx"]
+	t.advances = make([]int, t.numGlyphs)
+	for j := range t.advances {
+		if j < numHMetrics {
+			t.advances[j] = u16(hmtx, 4*j)
+		} else {
+			t.advances[j] = t.advances[numHMetrics-1]
+		}
+	}
+
+	loca := tables["loca"]
+	t.loca = make([]int, t.numGlyphs+1)
+	for j := range t.loca {
+		if longLoca {
+			t.loca[j] = u32(loca, 4*j)
+		} else {
+			t.loca[j] = 2 * u16(loca, 2*j)
+		}
+		if t.loca[j] > len(tables["glyf"]) || (j > 0 && t.loca[j] < t.loca[j-1]) {
+			return nil, fmt.Errorf("%w: invalid glyph offsets", ErrInvalidFont)
+		}
+	}
+
+	if post := tables["post"]; len(post) >= 16 {
+		t.italicAngle = float64(int32(u32(post, 4))) / 65536
+		t.fixedPitch = u32(post, 12) != 0
+	}
+	if os2 := tables["OS/2"]; len(os2) >= 10 {
+		if fsType := u16(os2, 8); fsType&0x000F == 0x0002 {
+			return nil, fmt.Errorf("%w: the license doesn't allow embedding", ErrInvalidFont)
+		}
+		if u16(os2, 0) >= 2 && len(os2) >= 90 {
+			t.capHeight = i16(os2, 88)
+		}
+	}
+	if name := fontName(tables["name"]); name != "" {
+		t.name = name
+	}
+
+	if t.cmap, err = parseCmap(tables["cmap"]); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// parseTables function returns the tables of the font by their tag.
+func parseTables(data []byte) (map[string][]byte, error) {
+	if len(data) < 12 {
+		return nil, fmt.Errorf("%w: file too short", ErrInvalidFont)
+	}
+	switch string(data[:4]) {
+	case "\x00\x01\x00\x00", "true":
+	case "OTTO":
+		return nil, fmt.Errorf("%w: PostScript outlines are not supported", ErrInvalidFont)
+	case "ttcf":
+		return nil, fmt.Errorf("%w: font collections are not supported", ErrInvalidFont)
+	default:
+		return nil, ErrInvalidFont
+	}
+
+	n := u16(data, 4)
+	if len(data) < 12+16*n {
+		return nil, fmt.Errorf("%w: file too short", ErrInvalidFont)
+	}
+	tables := map[string][]byte{}
+	for j := 0; j < n; j++ {
+		rec := data[12+16*j:]
+		tag := string(rec[:4])
+		off, size := u32(rec, 8), u32(rec, 12)
+		if off+size > len(data) || off+size < off {
+			return nil, fmt.Errorf("%w: %q table out of bounds", ErrInvalidFont, tag)
+		}
+		tables[tag] = data[off : off+size]
+	}
+	return tables, nil
+}
+
+// fontName function returns the PostScript name of the name table,
+// without the characters not allowed in a PDF name.
+func fontName(name []byte) string {
+	if len(name) < 6 {
+		return ""
+	}
+	count, storage := u16(name, 2), u16(name, 4)
+	for j := 0; j < count && 6+12*j+12 <= len(name); j++ {
+		rec := name[6+12*j:]
+		platform, nameID := u16(rec, 0), u16(rec, 6)
+		size, off := u16(rec, 8), storage+u16(rec, 10)
+		if nameID != 6 || off+size > len(name) {
+			continue
+		}
+		s := name[off : off+size]
+		var raw string
+		switch platform {
+		case 0, 3: // UTF-16BE
+			u := make([]uint16, len(s)/2)
+			for k := range u {
+				u[k] = uint16(u16(s, 2*k))
+			}
+			raw = string(utf16.Decode(u))
+		case 1: // Mac Roman, ASCII in PostScript names
+			raw = string(s)
+		default:
+			continue
+		}
+		var sb strings.Builder
+		for _, r := range raw {
+			if r > ' ' && r < 0x7F && !strings.ContainsRune("()<>[]{}/%#", r) {
+				sb.WriteRune(r)
+			}
+		}
+		if sb.Len() > 0 {
+			return sb.String()
+		}
+	}
+	return ""
+}
+
+// parseCmap function returns the glyphs of the runes of the unicode subtable
+// of the cmap table: format 12 for the full range, or format 4 for the BMP.
+func parseCmap(cmap []byte) (map[rune]uint16, error) {
+	var format4, format12 []byte
+	n := u16(cmap, 2)
+	for j := 0; j < n; j++ {
+		rec := cmap[4+8*j:]
+		platform, encoding, off := u16(rec, 0), u16(rec, 2), u32(rec, 4)
+		if off+4 > len(cmap) {
+			continue
+		}
+		unicode := platform == 0 || (platform == 3 && (encoding == 1 || encoding == 10))
+		if !unicode {
+			continue
+		}
+		switch u16(cmap, off) {
+		case 4:
+			format4 = cmap[off:]
+		case 12:
+			format12 = cmap[off:]
+		}
+	}
+
+	m := map[rune]uint16{}
+	switch {
+	case format12 != nil:
+		groups := u32(format12, 12)
+		for j := 0; j < groups; j++ {
+			g := format12[16+12*j:]
+			start, end, gid := u32(g, 0), u32(g, 4), u32(g, 8)
+			if end > unicodeMax || start > end {
+				continue
+			}
+			for c := start; c <= end && gid+c-start <= 0xFFFF; c++ {
+				m[rune(c)] = uint16(gid + c - start)
+			}
+		}
+	case format4 != nil:
+		segs := u16(format4, 6) / 2
+		ends, starts := 14, 16+2*segs
+		deltas, ranges := starts+2*segs, starts+4*segs
+		for j := 0; j < segs; j++ {
+			start, end := u16(format4, starts+2*j), u16(format4, ends+2*j)
+			delta, rangeOff := u16(format4, deltas+2*j), u16(format4, ranges+2*j)
+			for c := start; c <= end && c != 0xFFFF; c++ {
+				gid := c + delta
+				if rangeOff != 0 {
+					gid = u16(format4, ranges+2*j+rangeOff+2*(c-start))
+					if gid != 0 {
+						gid += delta
+					}
+				}
+				if gid &= 0xFFFF; gid != 0 {
+					m[rune(c)] = uint16(gid)
+				}
+			}
+		}
+	default:
+		return nil, fmt.Errorf("%w: missing unicode cmap", ErrInvalidFont)
+	}
+	return m, nil
+}
+
+const unicodeMax = 0x10FFFF
+
+// glyph method returns the glyph of the rune, or 0 (.notdef) if the font hasn't it.
+func (t *TrueType) glyph(r rune) uint16 {
+	gid := t.cmap[r]
+	if int(gid) >= t.numGlyphs {
+		return 0
+	}
+	return gid
+}
+
+// Advance returns the advance width of the rune, in units of the font size.
+func (t *TrueType) Advance(r rune) float64 {
+	return float64(t.advances[t.glyph(r)]) / float64(t.unitsPerEm)
+}
+
+// scale method returns the font units in the 1/1000 text space units of PDF.
+func (t *TrueType) scale(v int) int {
+	return int(math.Round(float64(v) * 1000 / float64(t.unitsPerEm)))
+}
+
+// components method returns the glyphs of the components of the composite glyph.
+func (t *TrueType) components(gid uint16) []uint16 {
+	glyf := t.tables["glyf"]
+	data := glyf[t.loca[gid]:t.loca[gid+1]]
+	if len(data) < 10 || i16(data, 0) >= 0 {
+		return nil
+	}
+	const (
+		argWords  = 0x0001
+		haveScale = 0x0008
+		more      = 0x0020
+		haveXY    = 0x0040
+		have2x2   = 0x0080
+	)
+	var gids []uint16
+	for off := 10; off+4 <= len(data); {
+		flags := u16(data, off)
+		gids = append(gids, uint16(u16(data, off+2)))
+		off += 4
+		if flags&argWords != 0 {
+			off += 4
+		} else {
+			off += 2
+		}
+		switch {
+		case flags&haveScale != 0:
+			off += 2
+		case flags&haveXY != 0:
+			off += 4
+		case flags&have2x2 != 0:
+			off += 8
+		}
+		if flags&more == 0 {
+			break
+		}
+	}
+	return gids
+}
+
+// subset method returns the font file with the outlines of the used glyphs only,
+// with the components of the composite glyphs and the .notdef glyph.
+// The other glyphs are kept empty, so that the glyph ids don't change.
+func (t *TrueType) subset(used map[uint16]rune) []byte {
+	keep := map[uint16]bool{}
+	stack := []uint16{0}
+	for gid := range used {
+		stack = append(stack, gid)
+	}
+	for len(stack) > 0 {
+		gid := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if keep[gid] || int(gid) >= t.numGlyphs {
+			continue
+		}
+		keep[gid] = true
+		stack = append(stack, t.components(gid)...)
+	}
+
+	// glyf and long loca
+	glyf := t.tables["glyf"]
+	var newGlyf []byte
+	newLoca := make([]byte, 4*(t.numGlyphs+1))
+	for gid := 0; gid < t.numGlyphs; gid++ {
+		binary.BigEndian.PutUint32(newLoca[4*gid:], uint32(len(newGlyf)))
+		if keep[uint16(gid)] {
+			newGlyf = append(newGlyf, glyf[t.loca[gid]:t.loca[gid+1]]...)
+			for len(newGlyf)%4 != 0 {
+				newGlyf = append(newGlyf, 0)
+			}
+		}
+	}
+	binary.BigEndian.PutUint32(newLoca[4*t.numGlyphs:], uint32(len(newGlyf)))
+
+	head := append([]byte(nil), t.tables["head"]...)
+	binary.BigEndian.PutUint32(head[8:], 0)  // checkSumAdjustment
+	binary.BigEndian.PutUint16(head[50:], 1) // indexToLocFormat: long
+
+	tables := map[string][]byte{"glyf": newGlyf, "loca": newLoca, "head": head}
+	for _, tag := range subsetTables {
+		if tables[tag] == nil && t.tables[tag] != nil {
+			tables[tag] = t.tables[tag]
+		}
+	}
+	return writeFont(tables)
+}
+
+// writeFont function returns the font file of the tables,
+// with the checksums of the tables and of the file.
+// The head table is required.
+func writeFont(tables map[string][]byte) []byte {
+	tags := make([]string, 0, len(tables))
+	for tag := range tables {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	// offset table
+	n := len(tags)
+	pow, selector := 1, 0
+	for pow*2 <= n {
+		pow, selector = pow*2, selector+1
+	}
+	font := make([]byte, 12+16*n)
+	binary.BigEndian.PutUint32(font[0:], 0x00010000)
+	binary.BigEndian.PutUint16(font[4:], uint16(n))
+	binary.BigEndian.PutUint16(font[6:], uint16(pow*16))
+	binary.BigEndian.PutUint16(font[8:], uint16(selector))
+	binary.BigEndian.PutUint16(font[10:], uint16(n*16-pow*16))
+
+	headOff := 0
+	for j, tag := range tags {
+		data := tables[tag]
+		rec := font[12+16*j:]
+		copy(rec, tag)
+		binary.BigEndian.PutUint32(rec[4:], checksum(data))
+		binary.BigEndian.PutUint32(rec[8:], uint32(len(font)))
+		binary.BigEndian.PutUint32(rec[12:], uint32(len(data)))
+		if tag == "head" {
+			headOff = len(font)
+		}
+		font = append(font, data...)
+		for len(font)%4 != 0 {
+			font = append(font, 0)
+		}
+	}
+	binary.BigEndian.PutUint32(font[headOff+8:], 0xB1B0AFBA-checksum(font))
+	return font
+}
+
+// checksum function returns the checksum of a table, or of the font file.
+func checksum(data []byte) uint32 {
+	var sum uint32
+	for j := 0; j < len(data); j += 4 {
+		var word [4]byte
+		copy(word[:], data[j:])
+		sum += binary.BigEndian.Uint32(word[:])
+	}
+	return sum
+}
+
+// subsetTag function returns the tag of the subset of the used glyphs,
+// six uppercase letters that prefix the name of the font.
+func subsetTag(used map[uint16]rune) string {
+	gids := make([]int, 0, len(used))
+	for gid := range used {
+		gids = append(gids, int(gid))
+	}
+	sort.Ints(gids)
+	h := fnv.New32a()
+	for _, gid := range gids {
+		h.Write([]byte{byte(gid >> 8), byte(gid)})
+	}
+	sum := h.Sum32()
+	tag := make([]byte, 6)
+	for j := range tag {
+		tag[j] = byte('A' + sum%26)
+		sum /= 26
+	}
+	return string(tag)
+}
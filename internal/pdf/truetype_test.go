@@ -0,0 +1,231 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"io/ioutil"
+	"regexp"
+	"testing"
+	"unicode/utf16"
+)
+
+// be function returns the big endian encoding of the 16 bit values.
+func be(values ...int) []byte {
+	b := make([]byte, 2*len(values))
+	for j, v := range values {
+		binary.BigEndian.PutUint16(b[2*j:], uint16(v))
+	}
+	return b
+}
+
+// testFont function returns a TrueType font with the glyphs
+// .notdef, 'A', 'B' and the composite 'Á', made of 'A'.
+func testFont() []byte {
+	simple := func(x int) []byte {
+		// one contour of one point, on curve, with word coordinates
+		g := be(1, 0, 0, x, 10, 0, 0)
+		return append(g, append([]byte{1}, be(x, 10)...)...)
+	}
+	composite := append(be(-1, 0, 0, 10, 20), be(0x0002, 1)...)
+	composite = append(composite, 0, 5)
+	glyphs := [][]byte{simple(1), simple(2), simple(3), composite}
+
+	var glyf []byte
+	var loca []int
+	for _, g := range glyphs {
+		loca = append(loca, len(glyf)/2)
+		glyf = append(glyf, g...)
+		if len(glyf)%2 != 0 {
+			glyf = append(glyf, 0)
+		}
+	}
+	loca = append(loca, len(glyf)/2)
+
+	head := make([]byte, 54)
+	copy(head, be(1, 0))
+	copy(head[12:], be(0x5F0F, 0x3CF5))
+	copy(head[18:], be(1000))
+	copy(head[36:], be(0, -200, 600, 800))
+
+	hhea := make([]byte, 36)
+	copy(hhea, be(1, 0, 800, -200))
+	copy(hhea[34:], be(2))
+
+	name := []byte("Test Font(1)")
+	nameUTF16 := be()
+	for _, u := range utf16.Encode([]rune(string(name))) {
+		nameUTF16 = append(nameUTF16, be(int(u))...)
+	}
+
+	post := make([]byte, 32)
+	copy(post, be(3, 0))
+	copy(post[12:], be(0, 1))
+
+	// segments: 'A'-'B', 'Á', end
+	cmap := be(0, 1, 3, 1, 0, 12)
+	cmap = append(cmap, be(4, 40, 0, 6, 4, 1, 2)...)
+	cmap = append(cmap, be('B', 0xC1, 0xFFFF, 0)...)
+	cmap = append(cmap, be('A', 0xC1, 0xFFFF)...)
+	cmap = append(cmap, be(1-'A', 3-0xC1, 1)...)
+	cmap = append(cmap, be(0, 0, 0)...)
+
+	return writeFont(map[string][]byte{
+		"head": head,
+		"hhea": hhea,
+		"maxp": be(0, 0x5000, 4),
+		"hmtx": be(500, 0, 600, 0, 0, 0),
+		"cmap": cmap,
+		"loca": be(loca...),
+		"glyf": glyf,
+		"post": post,
+		"name": append(be(0, 1, 18, 3, 1, 0x409, 6, len(nameUTF16), 0), nameUTF16...),
+	})
+}
+
+func TestParseTrueType(t *testing.T) {
+	tt, err := ParseTrueType(testFont())
+	if err != nil {
+		t.Fatalf("unexpected error %q", err.Error())
+	}
+	if tt.name != "TestFont1" {
+		t.Errorf("name: expected %q, got %q", "TestFont1", tt.name)
+	}
+	if !tt.fixedPitch {
+		t.Errorf("expected fixed pitch font")
+	}
+	for r, want := range map[rune]uint16{'A': 1, 'B': 2, 'Á': 3, 'C': 0, '日': 0} {
+		if got := tt.glyph(r); got != want {
+			t.Errorf("%q: expected glyph %d, got %d", r, want, got)
+		}
+	}
+	if got := tt.Advance('A'); got != 0.6 {
+		t.Errorf("advance: expected %v, got %v", 0.6, got)
+	}
+	if got := tt.Advance('日'); got != 0.5 {
+		t.Errorf("advance of .notdef: expected %v, got %v", 0.5, got)
+	}
+}
+
+func TestParseTrueType_errors(t *testing.T) {
+	font := testFont()
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"otf", append([]byte("OTTO"), font[4:]...)},
+		{"ttc", append([]byte("ttcf"), font[4:]...)},
+		{"truncated", font[:len(font)/2]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseTrueType(tt.data); !errors.Is(err, ErrInvalidFont) {
+				t.Errorf("expected %v, got %v", ErrInvalidFont, err)
+			}
+		})
+	}
+}
+
+func TestTrueType_subset(t *testing.T) {
+	tt, err := ParseTrueType(testFont())
+	if err != nil {
+		t.Fatalf("unexpected error %q", err.Error())
+	}
+
+	font := tt.subset(map[uint16]rune{3: 'Á'})
+	if sum := checksum(font); sum != 0xB1B0AFBA {
+		t.Errorf("font checksum: expected %#x, got %#x", 0xB1B0AFBA, sum)
+	}
+	tables, err := parseTables(font)
+	if err != nil {
+		t.Fatalf("unexpected error %q", err.Error())
+	}
+	for tag := range tables {
+		if tag == "cmap" || tag == "name" || tag == "post" {
+			t.Errorf("unexpected %q table", tag)
+		}
+	}
+
+	// .notdef, 'Á' and its component 'A' are kept, 'B' is empty
+	loca := tables["loca"]
+	for gid, keep := range []bool{true, true, false, true} {
+		start, end := u32(loca, 4*gid), u32(loca, 4*gid+4)
+		orig := tt.tables["glyf"][tt.loca[gid]:tt.loca[gid+1]]
+		got := tables["glyf"][start:end]
+		if keep && !bytes.HasPrefix(got, orig) {
+			t.Errorf("glyph %d: expected %v, got %v", gid, orig, got)
+		}
+		if !keep && len(got) != 0 {
+			t.Errorf("glyph %d: expected empty, got %v", gid, got)
+		}
+	}
+}
+
+func TestDocument_SetFont(t *testing.T) {
+	tt, err := ParseTrueType(testFont())
+	if err != nil {
+		t.Fatalf("unexpected error %q", err.Error())
+	}
+	doc := New()
+	doc.Title = "Á"
+	doc.SetFont(Courier, tt)
+	doc.SetFont(CourierBold, tt)
+	p := doc.AddPage(A4Width, A4Height)
+	p.Text(50, 700, Courier, 12, "AÁ日")
+	p.Text(50, 680, CourierBold, 12, "B")
+	p.Text(50, 660, Helvetica, 12, "A")
+
+	var buf bytes.Buffer
+	if _, err := doc.WriteTo(&buf); err != nil {
+		t.Fatalf("unexpected error %q", err.Error())
+	}
+	data := buf.Bytes()
+	checkXref(t, data)
+
+	for _, want := range []string{
+		"/Title <FEFF00C1>",
+		"/F1 12 0 R /F2 12 0 R /F3 6 0 R",
+		"/Subtype /Type0 /BaseFont /",
+		"+TestFont1 /Encoding /Identity-H",
+		"/W [ 0 [500] 1 [600] 2 [600] 3 [600] ]",
+		"/Flags 33 ",
+		"/FontFile2 15 0 R",
+	} {
+		if !bytes.Contains(data, []byte(want)) {
+			t.Errorf("expected %q in the document", want)
+		}
+	}
+	if n := bytes.Count(data, []byte("/Subtype /Type0")); n != 1 {
+		t.Errorf("embedded fonts: expected %d, got %d", 1, n)
+	}
+
+	streams := map[string]bool{}
+	re := regexp.MustCompile(`(?s)stream\n(.*?)\nendstream`)
+	for _, m := range re.FindAllSubmatch(data, -1) {
+		zr, err := zlib.NewReader(bytes.NewReader(m[1]))
+		if err != nil {
+			t.Fatalf("unexpected error %q", err.Error())
+		}
+		b, _ := ioutil.ReadAll(zr)
+		streams[string(b)] = true
+	}
+	var content, cmap string
+	for s := range streams {
+		if bytes.HasPrefix([]byte(s), []byte("BT ")) {
+			content = s
+		}
+		if bytes.Contains([]byte(s), []byte("begincmap")) {
+			cmap = s
+		}
+	}
+	for _, want := range []string{"/F1 12 Tf 50 700 Td <000100030000> Tj", "/F2 12 Tf 50 680 Td <0002> Tj", "/F4 12 Tf 50 660 Td (A) Tj"} {
+		if !bytes.Contains([]byte(content), []byte(want)) {
+			t.Errorf("expected %q in the content %q", want, content)
+		}
+	}
+	if want := "3 beginbfchar\n<0001> <0041>\n<0002> <0042>\n<0003> <00C1>\nendbfchar"; !bytes.Contains([]byte(cmap), []byte(want)) {
+		t.Errorf("expected %q in the cmap %q", want, cmap)
+	}
+}
@@ -5,14 +5,24 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"strings"
 
 	"github.com/mmbros/chordpro/cmd"
+	"github.com/mmbros/chordpro/pkg/chordpro"
 	"github.com/mmbros/simpleflag"
 )
 
 const (
 	defaultOverwrite   = cmd.OverwriteNone
 	defaultFrontmatter = cmd.FrontmatterPreserve
+	defaultFormat      = cmd.FormatHTML
+	defaultWidth       = chordpro.DefaultTextWidth
+	defaultColumns     = chordpro.DefaultPdfColumns
+	defaultFontSize    = chordpro.DefaultPdfFontSize
+	defaultTheme       = chordpro.DefaultTheme
+	defaultMulti       = cmd.MultiError
+	defaultMultiHugo   = cmd.MultiSplit
+	defaultJobs        = 0
 
 	cmdnameTranformFolder      = "transform"
 	cmdnameTranformFolderAlias = "folder, dir"
@@ -22,11 +32,39 @@ const (
 
 	cmdnameTranformHugo = "hugo"
 
-	// cmdnameClearFolder = "clear"
+	cmdnameExport = "export"
+
+	cmdnameImport = "import"
+
+	cmdnameConvert = "convert"
+
+	cmdnameSongbook = "songbook"
+
+	cmdnameServe = "serve"
+
+	cmdnameLint = "lint"
+
+	cmdnameClean      = "clean"
+	cmdnameCleanAlias = "prune"
 )
 
 var appname string
 
+// formatNames returns the names of the available output formats.
+func formatNames() string {
+	return strings.Join(chordpro.FormatterNames(), ", ")
+}
+
+// themeNames returns the names of the themes of the standalone html output.
+func themeNames() string {
+	return strings.Join(chordpro.ThemeNames(), ", ")
+}
+
+// importerNames returns the names of the available input formats.
+func importerNames() string {
+	return strings.Join(chordpro.ImporterNames(), ", ")
+}
+
 func usageApp() {
 	const msg = `%[1]s : utility to converts chordpro files to html format
 
@@ -36,12 +74,26 @@ Command:
   %-24[2]s transform all the chordpro files in the source folder
   %-24[3]s transform a single chordpro file
   %-24[4]s adapt source folder to Hugo content folder
+  %-24[5]s export all the songs of a chordpro file
+  %-24[6]s import songs from other formats to chordpro
+  %-24[7]s convert songs between formats
+  %-24[8]s write a single html songbook of many songs
+  %-24[9]s preview the chordpro files in the browser
+  %-24[10]s remove the outputs whose source no longer exists
+  %-24[11]s check the chordpro files and report their problems
 `
 
 	fmt.Fprintf(flag.CommandLine.Output(), msg, appname,
 		fmt.Sprintf("%s (%s)", cmdnameTranformFolder, cmdnameTranformFolderAlias),
 		fmt.Sprintf("%s (%s)", cmdnameTranformFile, cmdnameTranformFileAlias),
 		cmdnameTranformHugo,
+		cmdnameExport,
+		cmdnameImport,
+		cmdnameConvert,
+		cmdnameSongbook,
+		cmdnameServe,
+		fmt.Sprintf("%s (%s)", cmdnameClean, cmdnameCleanAlias),
+		cmdnameLint,
 	)
 }
 
@@ -56,15 +108,57 @@ Options:
   -o, --overwrite <overwrite-mode>
         how to handle existing output file (default %[2]q)
           %-11[3]q: never overwrite existing files
-          %-11[4]q: overwrite older files
+          %-11[4]q: overwrite the files whose source, options
+                       or tool version changed since the last build
           %-11[5]q: overwrite all files
   -f, --frontmatter string
         how to handle frontmatter (default %[6]q)
           %-11[7]q: don't print frontmatter
           %-11[8]q: overwrite existing frontmatter
           %-11[9]q: preserve existing frontmatter
+  -m, --multi <multi-mode>
+        how to handle a source file with many songs (default %[16]q)
+          %-11[17]q: the file is not transformed
+          %-11[18]q: each song is saved to its own file,
+                       named after the song title or its index
+          %-11[19]q: all the songs are saved in sequence
+      --format <format>
+        output format (default %[11]q)
+          one of: %[12]s
+  -w, --width <columns>
+        wrap width of the text and markdown formats, 0 for no wrap (default %[13]d)
+      --columns <number>
+        columns of the pages of the pdf format (default %[21]d)
+      --font-size <points>
+        font size of the pdf format (default %[22]g)
+      --font <file>
+        TrueType font embedded in the pdf format,
+        instead of the standard fonts limited to Latin-1
+      --bold-font <file>
+        TrueType font of the chords and titles of the pdf format
+  -t, --template <folder>
+        folder of the html templates: song.html, meta.html,
+        paragraph.html, line.html and pair.html
+  -s, --standalone
+        write the html output as a complete document, without frontmatter
+      --theme <theme>
+        theme of the standalone html output (default %[14]q)
+          one of: %[15]s
+      --css <file>
+        custom stylesheet added to the standalone html output
+  -j, --jobs <number>
+        number of files transformed concurrently,
+        0 for the number of CPUs (default %[20]d)
   -i, --index
         recursively creates "_index.md" files for folders
+      --force
+        transform all the files, whatever the overwrite mode
+      --dry-run
+        print what would be done for each output file,
+        with the frontmatter decision, without writing anything
+      --watch
+        keep running and transform the changed files again,
+        removing or moving the outputs of the removed or renamed files
   -h, --help
         print this help message
 `
@@ -73,6 +167,11 @@ Options:
 		defaultOverwrite, cmd.OverwriteNone, cmd.OverwriteOld, cmd.OverwriteAll,
 		defaultFrontmatter, cmd.FrontmatterNone, cmd.FrontmatterOverwrite, cmd.FrontmatterPreserve,
 		cmdnameTranformFolder,
+		defaultFormat, formatNames(), defaultWidth,
+		defaultTheme, themeNames(),
+		defaultMulti, cmd.MultiError, cmd.MultiSplit, cmd.MultiAll,
+		defaultJobs,
+		defaultColumns, defaultFontSize,
 	)
 }
 
@@ -86,13 +185,49 @@ Options:
   -o, --overwrite <overwrite-mode>
         how to handle existing output file (default %[2]q)
           %-11[3]q: never overwrite existing files
-          %-11[4]q: overwrite older files
+          %-11[4]q: overwrite the files whose source, options
+                       or tool version changed since the last build
           %-11[5]q: overwrite all files
   -f, --frontmatter string
         how to handle frontmatter (default %[6]q)
           %-11[7]q: don't print frontmatter
           %-11[8]q: overwrite existing frontmatter
           %-11[9]q: preserve existing frontmatter
+  -m, --multi <multi-mode>
+        how to handle a source file with many songs (default %[16]q)
+          %-11[17]q: the file is not transformed
+          %-11[18]q: each song is saved to its own file,
+                       named after the song title or its index
+          %-11[19]q: all the songs are saved in sequence
+      --format <format>
+        output format (default %[11]q)
+          one of: %[12]s
+  -w, --width <columns>
+        wrap width of the text and markdown formats, 0 for no wrap (default %[13]d)
+      --columns <number>
+        columns of the pages of the pdf format (default %[20]d)
+      --font-size <points>
+        font size of the pdf format (default %[21]g)
+      --font <file>
+        TrueType font embedded in the pdf format,
+        instead of the standard fonts limited to Latin-1
+      --bold-font <file>
+        TrueType font of the chords and titles of the pdf format
+  -t, --template <folder>
+        folder of the html templates: song.html, meta.html,
+        paragraph.html, line.html and pair.html
+  -s, --standalone
+        write the html output as a complete document, without frontmatter
+      --theme <theme>
+        theme of the standalone html output (default %[14]q)
+          one of: %[15]s
+      --css <file>
+        custom stylesheet added to the standalone html output
+      --force
+        transform the file, whatever the overwrite mode
+      --dry-run
+        print what would be done for each output file,
+        with the frontmatter decision, without writing anything
   -h, --help
         print this help message
 `
@@ -101,6 +236,10 @@ Options:
 		defaultOverwrite, cmd.OverwriteNone, cmd.OverwriteOld, cmd.OverwriteAll,
 		defaultFrontmatter, cmd.FrontmatterNone, cmd.FrontmatterOverwrite, cmd.FrontmatterPreserve,
 		cmdnameTranformFile,
+		defaultFormat, formatNames(), defaultWidth,
+		defaultTheme, themeNames(),
+		defaultMulti, cmd.MultiError, cmd.MultiSplit, cmd.MultiAll,
+		defaultColumns, defaultFontSize,
 	)
 }
 
@@ -112,11 +251,266 @@ func usageTransformHugo() {
 Usage: %[1]s %[2]s [options] <source-folder> <dest-folder> 
 
 Options:
+  -m, --multi <multi-mode>
+        how to handle a source file with many songs (default %[6]q)
+          %-11[7]q: the file is not transformed
+          %-11[8]q: each song is saved to its own content page,
+                       with its own frontmatter
+          %-11[9]q: all the songs are saved in sequence
+      --format <format>
+        output format (default %[3]q)
+          one of: %[4]s
+  -w, --width <columns>
+        wrap width of the text and markdown formats, 0 for no wrap (default %[5]d)
+  -t, --template <folder>
+        folder of the html templates: song.html, meta.html,
+        paragraph.html, line.html and pair.html
+  -j, --jobs <number>
+        number of files transformed concurrently,
+        0 for the number of CPUs (default %[10]d)
+      --force
+        transform all the files, whatever the overwrite mode
+      --dry-run
+        print what would be done for each output file,
+        with the frontmatter decision, without writing anything
+      --watch
+        keep running and transform the changed files again,
+        removing or moving the outputs of the removed or renamed files
+  -h, --help
+        print this help message
+`
+
+	fmt.Fprintf(flag.CommandLine.Output(), msg, appname, cmdnameTranformHugo,
+		defaultFormat, formatNames(), defaultWidth,
+		defaultMultiHugo, cmd.MultiError, cmd.MultiSplit, cmd.MultiAll,
+		defaultJobs,
+	)
+}
+
+func usageExport() {
+	const msg = `%[1]s %[2]s
+    export all the songs of a chordpro file as a single document.
+
+Usage: %[1]s %[2]s [options] <source-file> [<dest-file>=StdOut] 
+
+Options:
+  -o, --overwrite <overwrite-mode>
+        how to handle existing output file (default %[3]q)
+          %-11[4]q: never overwrite existing files
+          %-11[5]q: overwrite the files whose source, options
+                       or tool version changed since the last build
+          %-11[6]q: overwrite all files
+      --format <format>
+        output format (default %[7]q)
+          one of: %[8]s
+      --columns <number>
+        columns of the pages of the pdf format (default %[9]d)
+      --font-size <points>
+        font size of the pdf format (default %[10]g)
+      --font <file>
+        TrueType font embedded in the pdf format,
+        instead of the standard fonts limited to Latin-1
+      --bold-font <file>
+        TrueType font of the chords and titles of the pdf format
+  -h, --help
+        print this help message
+`
+
+	fmt.Fprintf(flag.CommandLine.Output(), msg, appname, cmdnameExport,
+		defaultOverwrite, cmd.OverwriteNone, cmd.OverwriteOld, cmd.OverwriteAll,
+		cmd.FormatJSON, formatNames(),
+		defaultColumns, defaultFontSize,
+	)
+}
+
+func usageImport() {
+	const msg = `%[1]s %[2]s
+    import the songs of a file in another format
+    and saves them as a chordpro file.
+
+Usage: %[1]s %[2]s [options] <source-file> [<dest-file>=StdOut] 
+
+Options:
+  -o, --overwrite <overwrite-mode>
+        how to handle existing output file (default %[3]q)
+          %-11[4]q: never overwrite existing files
+          %-11[5]q: overwrite the files whose source, options
+                       or tool version changed since the last build
+          %-11[6]q: overwrite all files
+      --from <format>
+        input format (default %[7]q)
+          one of: %[8]s
+  -h, --help
+        print this help message
+`
+
+	fmt.Fprintf(flag.CommandLine.Output(), msg, appname, cmdnameImport,
+		defaultOverwrite, cmd.OverwriteNone, cmd.OverwriteOld, cmd.OverwriteAll,
+		cmd.FormatText, importerNames(),
+	)
+}
+
+func usageConvert() {
+	const msg = `%[1]s %[2]s
+    convert the songs of a file from a format to another.
+
+Usage: %[1]s %[2]s [options] <source-file> [<dest-file>=StdOut] 
+
+Options:
+  -o, --overwrite <overwrite-mode>
+        how to handle existing output file (default %[3]q)
+          %-11[4]q: never overwrite existing files
+          %-11[5]q: overwrite the files whose source, options
+                       or tool version changed since the last build
+          %-11[6]q: overwrite all files
+      --from <format>
+        input format (default %[7]q)
+          one of: %[8]s
+      --to <format>
+        output format (default %[7]q)
+          one of: %[9]s
+  -w, --width <columns>
+        wrap width of the text and markdown formats, 0 for no wrap (default %[10]d)
+      --columns <number>
+        columns of the pages of the pdf format (default %[11]d)
+      --font-size <points>
+        font size of the pdf format (default %[12]g)
+      --font <file>
+        TrueType font embedded in the pdf format,
+        instead of the standard fonts limited to Latin-1
+      --bold-font <file>
+        TrueType font of the chords and titles of the pdf format
+  -h, --help
+        print this help message
+`
+
+	fmt.Fprintf(flag.CommandLine.Output(), msg, appname, cmdnameConvert,
+		defaultOverwrite, cmd.OverwriteNone, cmd.OverwriteOld, cmd.OverwriteAll,
+		cmd.FormatChordPro, importerNames(), formatNames(), defaultWidth,
+		defaultColumns, defaultFontSize,
+	)
+}
+
+func usageSongbook() {
+	const msg = `%[1]s %[2]s
+    write all the songs found in the source folder, or listed in the
+    manifest file, into a single html document sorted by title, with a
+    cover, a table of contents and the indexes by title and by artist.
+    The manifest lists a chordpro file per line, relative to its folder;
+    blank lines and lines beginning with '#' are ignored.
+
+Usage: %[1]s %[2]s [options] <source-folder|manifest> [<dest-file>=StdOut] 
+
+Options:
+  -o, --overwrite <overwrite-mode>
+        how to handle existing output file (default %[3]q)
+          %-11[4]q: never overwrite existing files
+          %-11[5]q: overwrite the files whose source, options
+                       or tool version changed since the last build
+          %-11[6]q: overwrite all files
+      --title <title>
+        title of the songbook (default %[7]q)
+  -t, --template <folder>
+        folder of the html templates: song.html, meta.html,
+        paragraph.html, line.html and pair.html
+      --theme <theme>
+        theme of the songbook (default %[8]q)
+          one of: %[9]s
+      --css <file>
+        custom stylesheet added to the songbook
+      --force
+        write the songbook, whatever the overwrite mode
+  -h, --help
+        print this help message
+`
+
+	fmt.Fprintf(flag.CommandLine.Output(), msg, appname, cmdnameSongbook,
+		defaultOverwrite, cmd.OverwriteNone, cmd.OverwriteOld, cmd.OverwriteAll,
+		chordpro.DefaultSongbookTitle, defaultTheme, themeNames(),
+	)
+}
+
+func usageServe() {
+	const msg = `%[1]s %[2]s
+    serve the chordpro files of the source folder as html pages,
+    with an index page for each folder. The pages are reloaded
+    by the browser when the files change.
+    The pages accept the query parameters:
+      format=<format>        output format
+      theme=<theme>          theme of the html output
+      transpose=<semitones>  transpose the chords, like transpose=-2
+
+Usage: %[1]s %[2]s [options] <source-folder> 
+
+Options:
+  -a, --addr <address>
+        address of the server (default %[3]q)
+      --format <format>
+        default output format (default %[4]q)
+          one of: %[5]s
+  -t, --template <folder>
+        folder of the html templates: song.html, meta.html,
+        paragraph.html, line.html and pair.html
+      --theme <theme>
+        default theme of the html output (default %[6]q)
+          one of: %[7]s
+      --css <file>
+        custom stylesheet added to the html output
+  -h, --help
+        print this help message
+`
+
+	fmt.Fprintf(flag.CommandLine.Output(), msg, appname, cmdnameServe,
+		cmd.DefaultAddr, defaultFormat, formatNames(), defaultTheme, themeNames(),
+	)
+}
+
+func usageClean() {
+	const msg = `%[1]s %[2]s
+    remove the outputs in the dest folder whose source no longer exists
+    in the source folder, as recorded in the build manifest of the dest folder:
+    the transformed files, the split pages and the files copied by the hugo command.
+    The files to remove are printed and confirmed before they are removed.
+
+Usage: %[1]s %[2]s [options] <source-folder> <dest-folder> 
+
+Options:
+  -i, --index
+        remove the generated "_index.md" files of the folders left empty
+      --dry-run
+        print the files to remove, without removing them
+  -y, --yes
+        remove the files without asking for confirmation
   -h, --help
         print this help message
 `
 
-	fmt.Fprintf(flag.CommandLine.Output(), msg, appname, cmdnameTranformHugo)
+	fmt.Fprintf(flag.CommandLine.Output(), msg, appname, cmdnameClean)
+}
+
+func usageLint() {
+	const msg = `%[1]s %[2]s
+    check the chordpro file, or all the chordpro files in the source folder,
+    and print their problems as "file:line:col: severity: message (code)":
+    unknown directives, unclosed or unmatched environments, songs without
+    title, duplicate metadata, invalid chords, unterminated chords and directives.
+    The command fails if any problem has the failing severity or higher.
+
+Usage: %[1]s %[2]s [options] <source-file-or-folder> 
+
+Options:
+      --json
+        print the problems as a json array
+      --fail-on <severity>
+        lowest severity of the problems that fails the command (default %[3]q)
+          one of: %[4]s
+  -h, --help
+        print this help message
+`
+
+	fmt.Fprintf(flag.CommandLine.Output(), msg, appname, cmdnameLint,
+		cmd.DefaultFailOn, "info, warning, error",
+	)
 }
 
 func cmdApp(name string, arguments []string) error {
@@ -141,7 +535,22 @@ func cmdTransformFolder(name string, arguments []string) error {
 	opts.Recursive = true
 	simpleflag.AliasedStringVar(fs, &opts.Overwrite, "overwrite,o", defaultOverwrite, "")
 	simpleflag.AliasedStringVar(fs, &opts.Frontmatter, "frontmatter,f", defaultFrontmatter, "")
+	simpleflag.AliasedStringVar(fs, &opts.Multi, "multi,m", defaultMulti, "")
+	simpleflag.AliasedStringVar(fs, &opts.Format, "format", defaultFormat, "")
+	simpleflag.AliasedIntVar(fs, &opts.Width, "width,w", defaultWidth, "")
+	simpleflag.AliasedStringVar(fs, &opts.Template, "template,t", "", "")
+	simpleflag.AliasedBoolVar(fs, &opts.Standalone, "standalone,s", false, "")
+	simpleflag.AliasedStringVar(fs, &opts.Theme, "theme", defaultTheme, "")
+	simpleflag.AliasedStringVar(fs, &opts.CSS, "css", "", "")
 	simpleflag.AliasedBoolVar(fs, &opts.Index, "index,i", false, "")
+	simpleflag.AliasedIntVar(fs, &opts.Jobs, "jobs,j", defaultJobs, "")
+	simpleflag.AliasedBoolVar(fs, &opts.Force, "force", false, "")
+	simpleflag.AliasedBoolVar(fs, &opts.DryRun, "dry-run", false, "")
+	simpleflag.AliasedBoolVar(fs, &opts.Watch, "watch", false, "")
+	simpleflag.AliasedIntVar(fs, &opts.Columns, "columns", defaultColumns, "")
+	fs.Float64Var(&opts.FontSize, "font-size", defaultFontSize, "")
+	simpleflag.AliasedStringVar(fs, &opts.Font, "font", "", "")
+	simpleflag.AliasedStringVar(fs, &opts.BoldFont, "bold-font", "", "")
 
 	err := fs.Parse(arguments)
 	if err != nil {
@@ -165,7 +574,20 @@ func cmdTransformFile(name string, arguments []string) error {
 	fs.Usage = usageTransformFile
 	simpleflag.AliasedStringVar(fs, &opts.Overwrite, "overwrite,o", defaultOverwrite, "")
 	simpleflag.AliasedStringVar(fs, &opts.Frontmatter, "frontmatter,f", defaultFrontmatter, "")
+	simpleflag.AliasedStringVar(fs, &opts.Multi, "multi,m", defaultMulti, "")
+	simpleflag.AliasedStringVar(fs, &opts.Format, "format", defaultFormat, "")
+	simpleflag.AliasedIntVar(fs, &opts.Width, "width,w", defaultWidth, "")
+	simpleflag.AliasedStringVar(fs, &opts.Template, "template,t", "", "")
+	simpleflag.AliasedBoolVar(fs, &opts.Standalone, "standalone,s", false, "")
+	simpleflag.AliasedStringVar(fs, &opts.Theme, "theme", defaultTheme, "")
+	simpleflag.AliasedStringVar(fs, &opts.CSS, "css", "", "")
 	simpleflag.AliasedBoolVar(fs, &opts.Index, "index,i", false, "")
+	simpleflag.AliasedBoolVar(fs, &opts.Force, "force", false, "")
+	simpleflag.AliasedBoolVar(fs, &opts.DryRun, "dry-run", false, "")
+	simpleflag.AliasedIntVar(fs, &opts.Columns, "columns", defaultColumns, "")
+	fs.Float64Var(&opts.FontSize, "font-size", defaultFontSize, "")
+	simpleflag.AliasedStringVar(fs, &opts.Font, "font", "", "")
+	simpleflag.AliasedStringVar(fs, &opts.BoldFont, "bold-font", "", "")
 
 	err := fs.Parse(arguments)
 	if err != nil {
@@ -187,6 +609,14 @@ func cmdTransformHugo(name string, arguments []string) error {
 	var opts cmd.Options
 
 	fs.Usage = usageTransformHugo
+	simpleflag.AliasedStringVar(fs, &opts.Multi, "multi,m", defaultMultiHugo, "")
+	simpleflag.AliasedStringVar(fs, &opts.Format, "format", defaultFormat, "")
+	simpleflag.AliasedIntVar(fs, &opts.Width, "width,w", defaultWidth, "")
+	simpleflag.AliasedStringVar(fs, &opts.Template, "template,t", "", "")
+	simpleflag.AliasedIntVar(fs, &opts.Jobs, "jobs,j", defaultJobs, "")
+	simpleflag.AliasedBoolVar(fs, &opts.Force, "force", false, "")
+	simpleflag.AliasedBoolVar(fs, &opts.DryRun, "dry-run", false, "")
+	simpleflag.AliasedBoolVar(fs, &opts.Watch, "watch", false, "")
 
 	err := fs.Parse(arguments)
 	if err != nil {
@@ -204,6 +634,185 @@ func cmdTransformHugo(name string, arguments []string) error {
 	return err
 }
 
+func cmdExport(name string, arguments []string) error {
+
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	var opts cmd.Options
+
+	fs.Usage = usageExport
+	simpleflag.AliasedStringVar(fs, &opts.Overwrite, "overwrite,o", defaultOverwrite, "")
+	simpleflag.AliasedStringVar(fs, &opts.Format, "format", cmd.FormatJSON, "")
+	simpleflag.AliasedIntVar(fs, &opts.Columns, "columns", defaultColumns, "")
+	fs.Float64Var(&opts.FontSize, "font-size", defaultFontSize, "")
+	simpleflag.AliasedStringVar(fs, &opts.Font, "font", "", "")
+	simpleflag.AliasedStringVar(fs, &opts.BoldFont, "bold-font", "", "")
+
+	err := fs.Parse(arguments)
+	if err != nil {
+		return err
+	}
+	opts.Input = fs.Arg(0)
+	opts.Output = fs.Arg(1)
+	opts.Width = defaultWidth
+
+	err = cmd.Export(&opts)
+	if err != nil {
+		err = simpleflag.WrapError(err, name)
+	}
+	return err
+}
+
+func cmdImport(name string, arguments []string) error {
+
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	var opts cmd.Options
+
+	fs.Usage = usageImport
+	simpleflag.AliasedStringVar(fs, &opts.Overwrite, "overwrite,o", defaultOverwrite, "")
+	simpleflag.AliasedStringVar(fs, &opts.From, "from", cmd.FormatText, "")
+
+	err := fs.Parse(arguments)
+	if err != nil {
+		return err
+	}
+	opts.Input = fs.Arg(0)
+	opts.Output = fs.Arg(1)
+
+	err = cmd.Import(&opts)
+	if err != nil {
+		err = simpleflag.WrapError(err, name)
+	}
+	return err
+}
+
+func cmdConvert(name string, arguments []string) error {
+
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	var opts cmd.Options
+
+	fs.Usage = usageConvert
+	simpleflag.AliasedStringVar(fs, &opts.Overwrite, "overwrite,o", defaultOverwrite, "")
+	simpleflag.AliasedStringVar(fs, &opts.From, "from", cmd.FormatChordPro, "")
+	simpleflag.AliasedStringVar(fs, &opts.Format, "to", cmd.FormatChordPro, "")
+	simpleflag.AliasedIntVar(fs, &opts.Width, "width,w", defaultWidth, "")
+	simpleflag.AliasedIntVar(fs, &opts.Columns, "columns", defaultColumns, "")
+	fs.Float64Var(&opts.FontSize, "font-size", defaultFontSize, "")
+	simpleflag.AliasedStringVar(fs, &opts.Font, "font", "", "")
+	simpleflag.AliasedStringVar(fs, &opts.BoldFont, "bold-font", "", "")
+
+	err := fs.Parse(arguments)
+	if err != nil {
+		return err
+	}
+	opts.Input = fs.Arg(0)
+	opts.Output = fs.Arg(1)
+
+	err = cmd.Convert(&opts)
+	if err != nil {
+		err = simpleflag.WrapError(err, name)
+	}
+	return err
+}
+
+func cmdSongbook(name string, arguments []string) error {
+
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	var opts cmd.Options
+
+	fs.Usage = usageSongbook
+	simpleflag.AliasedStringVar(fs, &opts.Overwrite, "overwrite,o", defaultOverwrite, "")
+	simpleflag.AliasedStringVar(fs, &opts.Title, "title", chordpro.DefaultSongbookTitle, "")
+	simpleflag.AliasedStringVar(fs, &opts.Template, "template,t", "", "")
+	simpleflag.AliasedStringVar(fs, &opts.Theme, "theme", defaultTheme, "")
+	simpleflag.AliasedStringVar(fs, &opts.CSS, "css", "", "")
+	simpleflag.AliasedBoolVar(fs, &opts.Force, "force", false, "")
+
+	err := fs.Parse(arguments)
+	if err != nil {
+		return err
+	}
+	opts.Input = fs.Arg(0)
+	opts.Output = fs.Arg(1)
+
+	err = cmd.Songbook(&opts)
+	if err != nil {
+		err = simpleflag.WrapError(err, name)
+	}
+	return err
+}
+
+func cmdServe(name string, arguments []string) error {
+
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	var opts cmd.Options
+
+	fs.Usage = usageServe
+	simpleflag.AliasedStringVar(fs, &opts.Addr, "addr,a", cmd.DefaultAddr, "")
+	simpleflag.AliasedStringVar(fs, &opts.Format, "format", defaultFormat, "")
+	simpleflag.AliasedStringVar(fs, &opts.Template, "template,t", "", "")
+	simpleflag.AliasedStringVar(fs, &opts.Theme, "theme", defaultTheme, "")
+	simpleflag.AliasedStringVar(fs, &opts.CSS, "css", "", "")
+
+	err := fs.Parse(arguments)
+	if err != nil {
+		return err
+	}
+	opts.Input = fs.Arg(0)
+	opts.Width = defaultWidth
+
+	err = cmd.Serve(&opts)
+	if err != nil {
+		err = simpleflag.WrapError(err, name)
+	}
+	return err
+}
+
+func cmdClean(name string, arguments []string) error {
+
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	var opts cmd.Options
+
+	fs.Usage = usageClean
+	simpleflag.AliasedBoolVar(fs, &opts.Index, "index,i", false, "")
+	simpleflag.AliasedBoolVar(fs, &opts.DryRun, "dry-run", false, "")
+	simpleflag.AliasedBoolVar(fs, &opts.Yes, "yes,y", false, "")
+
+	err := fs.Parse(arguments)
+	if err != nil {
+		return err
+	}
+	opts.Input = fs.Arg(0)
+	opts.Output = fs.Arg(1)
+
+	err = cmd.Clean(&opts)
+	if err != nil {
+		err = simpleflag.WrapError(err, name)
+	}
+	return err
+}
+
+func cmdLint(name string, arguments []string) error {
+
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	var opts cmd.Options
+
+	fs.Usage = usageLint
+	simpleflag.AliasedBoolVar(fs, &opts.JSON, "json", false, "")
+	simpleflag.AliasedStringVar(fs, &opts.FailOn, "fail-on", cmd.DefaultFailOn, "")
+
+	err := fs.Parse(arguments)
+	if err != nil {
+		return err
+	}
+	opts.Input = fs.Arg(0)
+
+	err = cmd.Lint(&opts)
+	if err != nil {
+		err = simpleflag.WrapError(err, name)
+	}
+	return err
+}
+
 func main() {
 	appname = path.Base(os.Args[0])
 
@@ -219,6 +828,27 @@ func main() {
 			cmdnameTranformHugo: {
 				ParseExec: cmdTransformHugo,
 			},
+			cmdnameExport: {
+				ParseExec: cmdExport,
+			},
+			cmdnameImport: {
+				ParseExec: cmdImport,
+			},
+			cmdnameConvert: {
+				ParseExec: cmdConvert,
+			},
+			cmdnameSongbook: {
+				ParseExec: cmdSongbook,
+			},
+			cmdnameServe: {
+				ParseExec: cmdServe,
+			},
+			cmdnameLint: {
+				ParseExec: cmdLint,
+			},
+			cmdnameClean + "," + cmdnameCleanAlias: {
+				ParseExec: cmdClean,
+			},
 		},
 	}
 
@@ -0,0 +1,156 @@
+package chordpro
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+)
+
+func init() {
+	RegisterFormatter("chordpro", func() Formatter { return ChordProFormatter{} })
+	RegisterImporter("chordpro", func() Importer { return ChordProImporter{} })
+}
+
+// ChordProFormatter is the Formatter of the "chordpro" format.
+// It writes the songs back to ChordPro source.
+type ChordProFormatter struct{}
+
+func (f ChordProFormatter) appendDirective(sb *strings.Builder, name, arg string) {
+	if arg == "" {
+		fmt.Fprintf(sb, "{%s}\n", name)
+	} else {
+		fmt.Fprintf(sb, "{%s: %s}\n", name, arg)
+	}
+}
+
+func (f ChordProFormatter) appendLines(sb *strings.Builder, lines []*Line) {
+	for _, lin := range lines {
+		for _, pair := range lin.Pairs {
+			sb.WriteString(pair.Chord)
+			sb.WriteString(pair.Lyric)
+		}
+		fmt.Fprintln(sb)
+	}
+}
+
+func (f ChordProFormatter) appendEnvironment(sb *strings.Builder, name string, p *Paragraph) {
+	f.appendDirective(sb, "start_of_"+name, p.Label)
+	f.appendLines(sb, p.Lines)
+	f.appendDirective(sb, "end_of_"+name, "")
+}
+
+// appendNode method writes the custom node of the paragraph
+// with its renderer, or back as a directive.
+func (f ChordProFormatter) appendNode(sb *strings.Builder, p *Paragraph) {
+	if s := renderNode("chordpro", p); s != "" {
+		sb.WriteString(s)
+		return
+	}
+	n := p.Node
+	if n == nil {
+		return
+	}
+	name := n.Name
+	if n.Selector != "" {
+		name += "-" + n.Selector
+	}
+	arg := n.Value
+	if arg == "" && len(n.Attrs) > 0 {
+		keys := make([]string, 0, len(n.Attrs))
+		for k := range n.Attrs {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		attrs := make([]string, len(keys))
+		for j, k := range keys {
+			attrs[j] = fmt.Sprintf("%s=%q", k, n.Attrs[k])
+		}
+		arg = strings.Join(attrs, " ")
+	}
+	f.appendDirective(sb, name, arg)
+}
+
+func (f ChordProFormatter) appendParagraph(sb *strings.Builder, p *Paragraph) {
+	switch p.ParagraphType {
+	case Comment:
+		for _, lin := range p.Lines {
+			var txt strings.Builder
+			for _, pair := range lin.Pairs {
+				txt.WriteString(pair.Lyric)
+			}
+			f.appendDirective(sb, "comment", strings.TrimSpace(txt.String()))
+		}
+	case ChorusRef:
+		f.appendDirective(sb, "chorus", p.Label)
+	case Tab:
+		f.appendEnvironment(sb, "tab", p)
+	case Chorus:
+		f.appendEnvironment(sb, "chorus", p)
+	case Bridge:
+		f.appendEnvironment(sb, "bridge", p)
+	case Custom:
+		f.appendNode(sb, p)
+	default:
+		if p.Label != "" {
+			f.appendEnvironment(sb, "verse", p)
+		} else {
+			f.appendLines(sb, trimBlankLines(p.Lines))
+		}
+	}
+}
+
+func (f ChordProFormatter) appendSong(sb *strings.Builder, s *Song) {
+	for _, mi := range s.meta {
+		f.appendDirective(sb, mi.name.String(), mi.value)
+	}
+	for _, p := range s.Paragraphs {
+		if p.ParagraphType == Verse && p.Label == "" && isBlank(p) {
+			continue
+		}
+		fmt.Fprintln(sb)
+		f.appendParagraph(sb, p)
+	}
+}
+
+// FormatSong writes the song as ChordPro source.
+func (f ChordProFormatter) FormatSong(w io.Writer, s *Song) error {
+	var sb strings.Builder
+	f.appendSong(&sb, s)
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// FormatSongs writes the songs as ChordPro source,
+// separated by the {new_song} directive.
+func (f ChordProFormatter) FormatSongs(w io.Writer, ss Songs) error {
+	var sb strings.Builder
+	for j, s := range ss {
+		if j > 0 {
+			fmt.Fprintln(&sb)
+			f.appendDirective(&sb, "new_song", "")
+		}
+		f.appendSong(&sb, s)
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// Extension returns the ".chopro" extension.
+func (ChordProFormatter) Extension() string { return ".chopro" }
+
+// MimeType returns the "text/x-chordpro" MIME type.
+func (ChordProFormatter) MimeType() string { return "text/x-chordpro" }
+
+// ChordProImporter is the Importer of the "chordpro" format.
+type ChordProImporter struct{}
+
+// Import reads the songs from the ChordPro source.
+func (ChordProImporter) Import(r io.Reader) (Songs, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return ParseText(string(data)), nil
+}
@@ -0,0 +1,46 @@
+package chordpro
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestChordProFormatter_FormatSongs(t *testing.T) {
+	src := `{title: Come Together}
+{artist: The Beatles}
+
+[Dm]Here come old flat top
+
+{start_of_chorus}
+[A]Come to[G]gether
+{end_of_chorus}
+
+{comment: Play riff}
+
+{start_of_tab: Riff}
+e|---0---|
+{end_of_tab}
+
+{chorus}
+
+{new_song}
+{title: Other}
+
+[C]do
+`
+	want := ParseText(src)
+
+	var sb strings.Builder
+	if err := (ChordProFormatter{}).FormatSongs(&sb, want); err != nil {
+		t.Fatalf("unexpected error %q", err.Error())
+	}
+	if got := sb.String(); got != src {
+		t.Errorf("expected %q, got %q", src, got)
+	}
+
+	got := ParseText(sb.String())
+	if !reflect.DeepEqual(got.String(), want.String()) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,128 @@
+package chordpro
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// ErrInvalidChord is returned when a string is not a valid chord.
+var ErrInvalidChord = errors.New("invalid chord")
+
+// Chord is a chord symbol, like "C#m7/G#".
+type Chord struct {
+	Root   string // root note: a letter from A to G, optionally followed by '#' or 'b'
+	Suffix string // quality and extensions, like "m", "7", "maj7" or "sus4"
+	Bass   string // bass note of a slash chord, or empty
+}
+
+// reChord matches root, suffix and bass of a chord.
+var reChord = regexp.MustCompile(`^([A-G][#b]?)((?:maj|min|mi|ma|dim|aug|sus|add|alt|no|m|M|[0-9]|[#b+\-()°øΔo^*])*)(?:/([A-G][#b]?))?$`)
+
+// ParseChord parses the chord symbol s.
+// The square brackets of the ChordPro notation, if any, are removed.
+// It returns ErrInvalidChord if s is not a valid chord.
+func ParseChord(s string) (*Chord, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, string(chordBegin)) && strings.HasSuffix(s, string(chordEnd)) {
+		s = strings.TrimSpace(trimDelim(s))
+	}
+	m := reChord.FindStringSubmatch(s)
+	if m == nil {
+		return nil, ErrInvalidChord
+	}
+	return &Chord{Root: m[1], Suffix: m[2], Bass: m[3]}, nil
+}
+
+// String returns the chord symbol.
+func (c *Chord) String() string {
+	s := c.Root + c.Suffix
+	if c.Bass != "" {
+		s += "/" + c.Bass
+	}
+	return s
+}
+
+var (
+	sharpNotes = []string{"C", "C#", "D", "D#", "E", "F", "F#", "G", "G#", "A", "A#", "B"}
+	flatNotes  = []string{"C", "Db", "D", "Eb", "E", "F", "Gb", "G", "Ab", "A", "Bb", "B"}
+)
+
+// noteIndex function returns the number of semitones of the note from C.
+func noteIndex(note string) int {
+	n := map[byte]int{'C': 0, 'D': 2, 'E': 4, 'F': 5, 'G': 7, 'A': 9, 'B': 11}[note[0]]
+	if len(note) > 1 {
+		switch note[1] {
+		case '#':
+			n++
+		case 'b':
+			n--
+		}
+	}
+	return (n + 12) % 12
+}
+
+// transposeNote function returns the note transposed by the given semitones,
+// with sharps or flats.
+func transposeNote(note string, semitones int, flats bool) string {
+	if note == "" {
+		return ""
+	}
+	n := ((noteIndex(note)+semitones)%12 + 12) % 12
+	if flats {
+		return flatNotes[n]
+	}
+	return sharpNotes[n]
+}
+
+// Transpose returns the chord transposed by the given number of semitones,
+// up if positive and down if negative.
+// The transposed notes are written with flats if the root of the chord
+// has a flat, and with sharps otherwise.
+func (c *Chord) Transpose(semitones int) *Chord {
+	flats := strings.HasSuffix(c.Root, "b")
+	return &Chord{
+		Root:   transposeNote(c.Root, semitones, flats),
+		Suffix: c.Suffix,
+		Bass:   transposeNote(c.Bass, semitones, flats),
+	}
+}
+
+// TransposeChord returns the chord symbol s transposed by the given number
+// of semitones. The square brackets of the ChordPro notation, if any, are kept.
+// If s is not a valid chord, like "N.C.", it is returned unchanged.
+func TransposeChord(s string, semitones int) string {
+	c, err := ParseChord(s)
+	if err != nil || semitones%12 == 0 {
+		return s
+	}
+	t := c.Transpose(semitones).String()
+	if strings.HasPrefix(s, string(chordBegin)) && strings.HasSuffix(s, string(chordEnd)) {
+		t = string(chordBegin) + t + string(chordEnd)
+	}
+	return t
+}
+
+// Transpose transposes the chords and the key of the song
+// by the given number of semitones.
+// The chords of the comments and of the tablatures are not changed.
+func (s *Song) Transpose(semitones int) {
+	if semitones%12 == 0 {
+		return
+	}
+	for _, mi := range s.meta {
+		if mi.name == metaKey {
+			mi.value = TransposeChord(mi.value, semitones)
+		}
+	}
+	for _, p := range s.Paragraphs {
+		if p.ParagraphType == Comment || p.ParagraphType == Tab {
+			continue
+		}
+		for _, lin := range p.Lines {
+			for _, pair := range lin.Pairs {
+				pair.Chord = TransposeChord(pair.Chord, semitones)
+			}
+		}
+	}
+}
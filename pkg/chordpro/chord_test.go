@@ -0,0 +1,104 @@
+package chordpro
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_ParseChord(t *testing.T) {
+	tests := []struct {
+		input string
+		want  Chord
+		err   error
+	}{
+		{input: "C", want: Chord{"C", "", ""}},
+		{input: "[Am]", want: Chord{"A", "m", ""}},
+		{input: "F#m7", want: Chord{"F#", "m7", ""}},
+		{input: "Bbmaj7", want: Chord{"Bb", "maj7", ""}},
+		{input: "Dsus4", want: Chord{"D", "sus4", ""}},
+		{input: "C/G", want: Chord{"C", "", "G"}},
+		{input: "C#m7b5/G#", want: Chord{"C#", "m7b5", "G#"}},
+		{input: "E7(#9)", want: Chord{"E", "7(#9)", ""}},
+		{input: "", err: ErrInvalidChord},
+		{input: "H", err: ErrInvalidChord},
+		{input: "Hello", err: ErrInvalidChord},
+		{input: "C/X", err: ErrInvalidChord},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseChord(tt.input)
+			if tt.err != nil {
+				if tt.err != err {
+					t.Errorf("expected %q error, got %q error", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("unexpected error %q", err.Error())
+				return
+			}
+			if *got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, *got)
+			}
+		})
+	}
+}
+
+func TestChord_String(t *testing.T) {
+	for _, s := range []string{"C", "F#m7", "C#m7b5/G#"} {
+		c, err := ParseChord(s)
+		if err != nil {
+			t.Errorf("unexpected error %q", err.Error())
+			continue
+		}
+		if got := c.String(); got != s {
+			t.Errorf("expected %q, got %q", s, got)
+		}
+	}
+}
+
+func Test_TransposeChord(t *testing.T) {
+	tests := []struct {
+		input     string
+		semitones int
+		want      string
+	}{
+		{"C", 2, "D"},
+		{"[Am]", 3, "[Cm]"},
+		{"B7", 1, "C7"},
+		{"C", -1, "B"},
+		{"Bbmaj7", 2, "Cmaj7"},
+		{"Bb", 1, "B"},
+		{"Eb", 2, "F"},
+		{"Ab", 1, "A"},
+		{"Db", 1, "D"},
+		{"Eb", 1, "E"},
+		{"F#m7b5/C#", 1, "Gm7b5/D"},
+		{"C/E", 13, "C#/F"},
+		{"Gb", 3, "A"},
+		{"G", 0, "G"},
+		{"N.C.", 2, "N.C."},
+	}
+	for _, tt := range tests {
+		if got := TransposeChord(tt.input, tt.semitones); got != tt.want {
+			t.Errorf("%q %+d: expected %q, got %q", tt.input, tt.semitones, tt.want, got)
+		}
+	}
+}
+
+func TestSong_Transpose(t *testing.T) {
+	s := ParseText("{key: Am}\n[Am]la [E7]la\n{c: [Am] here}\n")[0]
+	s.Transpose(2)
+
+	if got := s.Key(); got != "Bm" {
+		t.Errorf("expected key %q, got %q", "Bm", got)
+	}
+	pairs := s.Paragraphs[0].Lines[0].Pairs
+	var got []string
+	for _, pair := range pairs {
+		got = append(got, pair.Chord)
+	}
+	if strings.Join(got, " ") != "[Bm] [F#7]" {
+		t.Errorf("expected %q, got %q", "[Bm] [F#7]", strings.Join(got, " "))
+	}
+}
@@ -0,0 +1,140 @@
+package chordpro
+
+import (
+	"errors"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Formatter is the interface implemented by every output format.
+type Formatter interface {
+	// FormatSong writes a single song to w.
+	FormatSong(w io.Writer, s *Song) error
+	// FormatSongs writes all the songs to w, as a single document.
+	FormatSongs(w io.Writer, ss Songs) error
+	// Extension returns the file extension of the output, including the dot.
+	Extension() string
+	// MimeType returns the MIME type of the output.
+	MimeType() string
+}
+
+// ErrUnknownFormat is returned when the requested format is not registered.
+var ErrUnknownFormat = errors.New("unknown format")
+
+// ErrSingleSongFormat is returned by FormatSongs of the formats
+// whose documents can contain only one song.
+var ErrSingleSongFormat = errors.New("format supports a single song only")
+
+var formatters = map[string]func() Formatter{}
+
+// RegisterFormatter makes a formatter available by the given name.
+// The function fn is called to create a new formatter each time
+// NewFormatter is invoked with the same name.
+// If RegisterFormatter is called twice with the same name,
+// the last function wins.
+func RegisterFormatter(name string, fn func() Formatter) {
+	formatters[strings.ToLower(name)] = fn
+}
+
+// NewFormatter returns a new formatter of the given registered format name.
+// It returns ErrUnknownFormat if no formatter has been registered with that name.
+func NewFormatter(name string) (Formatter, error) {
+	fn, ok := formatters[strings.ToLower(name)]
+	if !ok {
+		return nil, ErrUnknownFormat
+	}
+	return fn(), nil
+}
+
+// FormatterNames returns the sorted names of the registered formatters.
+func FormatterNames() []string {
+	names := make([]string, 0, len(formatters))
+	for name := range formatters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NodeRenderer returns the output of the custom node in a format.
+type NodeRenderer func(n *CustomNode) string
+
+var nodeRenderers = map[string]NodeRenderer{}
+
+// RegisterNodeRenderer makes the formatter of the given format name
+// render the custom nodes with the given name, like "x_strum", by fn.
+// The output of fn is written as is, except by the "musicxml" format,
+// that writes each line as a direction of words, and the "pdf" format,
+// that writes each line as a row of text.
+// The nodes without renderer are not written, except by the "chordpro" format,
+// that writes them back as directives, and the "json" format.
+// The renderers of the "html" format are used by the formats based on it,
+// like "standalone", "songbook" and "template".
+// If RegisterNodeRenderer is called twice with the same names,
+// the last function wins.
+func RegisterNodeRenderer(format, name string, fn NodeRenderer) {
+	nodeRenderers[strings.ToLower(format)+"/"+strings.ToLower(name)] = fn
+}
+
+// renderNode function returns the output of the custom node of the paragraph
+// in the format, or an empty string if the paragraph is not Custom
+// or its node has no renderer.
+func renderNode(format string, p *Paragraph) string {
+	if p.ParagraphType != Custom || p.Node == nil {
+		return ""
+	}
+	fn, ok := nodeRenderers[format+"/"+strings.ToLower(p.Node.Name)]
+	if !ok {
+		return ""
+	}
+	return fn(p.Node)
+}
+
+// renderNodeLines function returns the lines of the output
+// of the custom node of the paragraph in the format,
+// for the formats that write it as text.
+func renderNodeLines(format string, p *Paragraph) []string {
+	out := strings.TrimRight(renderNode(format, p), "\n")
+	if out == "" {
+		return nil
+	}
+	return strings.Split(out, "\n")
+}
+
+// Importer is the interface implemented by every input format.
+type Importer interface {
+	// Import reads the songs from r.
+	Import(r io.Reader) (Songs, error)
+}
+
+var importers = map[string]func() Importer{}
+
+// RegisterImporter makes an importer available by the given name.
+// The function fn is called to create a new importer each time
+// NewImporter is invoked with the same name.
+// If RegisterImporter is called twice with the same name,
+// the last function wins.
+func RegisterImporter(name string, fn func() Importer) {
+	importers[strings.ToLower(name)] = fn
+}
+
+// NewImporter returns a new importer of the given registered format name.
+// It returns ErrUnknownFormat if no importer has been registered with that name.
+func NewImporter(name string) (Importer, error) {
+	fn, ok := importers[strings.ToLower(name)]
+	if !ok {
+		return nil, ErrUnknownFormat
+	}
+	return fn(), nil
+}
+
+// ImporterNames returns the sorted names of the registered importers.
+func ImporterNames() []string {
+	names := make([]string, 0, len(importers))
+	for name := range importers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
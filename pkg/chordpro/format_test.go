@@ -0,0 +1,125 @@
+package chordpro
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_NewFormatter(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		wantExt string
+		err     error
+	}{
+		{
+			name:    "ok-html",
+			format:  "html",
+			wantExt: ".html",
+		},
+		{
+			name:    "ok-HTML",
+			format:  "HTML",
+			wantExt: ".html",
+		},
+		{
+			name:   "err-xxx",
+			format: "xxx",
+			err:    ErrUnknownFormat,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewFormatter(tt.format)
+			if tt.err != nil {
+				if tt.err != err {
+					t.Errorf("expected %q error, got %q error", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("unexpected error %q", err.Error())
+				return
+			}
+			if ext := got.Extension(); ext != tt.wantExt {
+				t.Errorf("expected %v, got %v", tt.wantExt, ext)
+			}
+		})
+	}
+}
+
+func TestHtmlFormatter_FormatSongs(t *testing.T) {
+	ss := ParseText("[C]do {new_song} [D]re")
+
+	var sb strings.Builder
+	if err := (HtmlFormatter{}).FormatSongs(&sb, ss); err != nil {
+		t.Fatalf("unexpected error %q", err.Error())
+	}
+
+	want := 2
+	got := strings.Count(sb.String(), `<div class="chord-sheet">`)
+	if got != want {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestRegisterNodeRenderer(t *testing.T) {
+	p := NewParser(ParseOptions{})
+	p.HandleDirective("x_strum", func(s *Song, d *Directive) (*CustomNode, error) {
+		return &CustomNode{Name: d.Name, Value: d.Value}, nil
+	})
+	ss, err := p.Parse("[C]do\n{x_strum: D-DU}\n[D]re")
+	if err != nil {
+		t.Fatalf("unexpected error %q", err.Error())
+	}
+
+	strum := func(n *CustomNode) string { return "Strum: " + n.Value + "\n" }
+	tests := []struct {
+		format string
+		name   string
+		fn     NodeRenderer
+		want   string
+	}{
+		{"chordpro", "x_strum", nil, "{x_strum: D-DU}\n"},
+		{"text", "x_strum", strum, "C\ndo\nD\nre\n\nStrum: D-DU\n"},
+		{"markdown", "X_STRUM", strum, "Strum: D-DU\n"},
+		{"html", "x_strum", func(n *CustomNode) string { return "<p>" + n.Value + "</p>" }, "<p>D-DU</p>"},
+		{"onsong", "x_strum", strum, "re\n\nStrum: D-DU\n"},
+		{"opensong", "x_strum", func(n *CustomNode) string { return ";Strum: " + n.Value + "\n" }, "re\n;Strum: D-DU\n"},
+		{"openlyrics", "x_strum", func(n *CustomNode) string { return "    <verse name=\"s1\"><lines>" + n.Value + "</lines></verse>\n" }, "<verse name=\"s1\"><lines>D-DU</lines></verse>"},
+		{"musicxml", "x_strum", strum, "<words>Strum: D-DU</words>"},
+	}
+	for _, tt := range tests {
+		if tt.fn != nil {
+			RegisterNodeRenderer(tt.format, tt.name, tt.fn)
+		}
+	}
+	RegisterNodeRenderer("pdf", "x_strum", strum)
+	defer func() {
+		for _, tt := range tests {
+			delete(nodeRenderers, tt.format+"/x_strum")
+		}
+		delete(nodeRenderers, "pdf/x_strum")
+	}()
+
+	for _, tt := range tests {
+		f, _ := NewFormatter(tt.format)
+		var sb strings.Builder
+		if err := f.FormatSong(&sb, ss[0]); err != nil {
+			t.Errorf("%s: unexpected error %q", tt.format, err.Error())
+			continue
+		}
+		if !strings.Contains(sb.String(), tt.want) {
+			t.Errorf("%s: expected %q in %q", tt.format, tt.want, sb.String())
+		}
+	}
+
+	// the pdf output is compressed
+	pf := &PdfFormatter{}
+	fonts, _ := pf.loadFonts()
+	l := &pdfLayout{f: pf, fonts: fonts}
+	block := l.paragraphBlock(ss[0].Paragraphs[1])
+	if len(block) != 1 || block[0].text != "Strum: D-DU" {
+		t.Errorf("pdf: expected %q, got %v", "Strum: D-DU", block)
+	}
+}
@@ -37,6 +37,35 @@ const (
 	tagError        = "div"
 )
 
+func init() {
+	RegisterFormatter("html", func() Formatter { return HtmlFormatter{} })
+}
+
+// HtmlFormatter is the Formatter of the "html" format.
+// Each song is printed as a "chord-sheet" div fragment.
+type HtmlFormatter struct{}
+
+// FormatSong writes the song as a "chord-sheet" div.
+func (HtmlFormatter) FormatSong(w io.Writer, s *Song) error {
+	NewHtmlDivFormatter(w).FormatBody(s)
+	return nil
+}
+
+// FormatSongs writes the songs as a sequence of "chord-sheet" divs.
+func (HtmlFormatter) FormatSongs(w io.Writer, ss Songs) error {
+	f := NewHtmlDivFormatter(w)
+	for _, s := range ss {
+		f.FormatBody(s)
+	}
+	return nil
+}
+
+// Extension returns the ".html" extension.
+func (HtmlFormatter) Extension() string { return ".html" }
+
+// MimeType returns the "text/html" MIME type.
+func (HtmlFormatter) MimeType() string { return "text/html" }
+
 type HtmlDivFormatter struct {
 	w io.Writer
 }
@@ -117,9 +146,14 @@ func (f HtmlDivFormatter) appendLine(className string, lin *Line) {
 	f.appendTagClose(tagLine, true)
 }
 
+// paragraphClass function returns the html class of the paragraph type.
+func paragraphClass(pt ParagraphType) string {
+	return []string{"verse", "comment", "tablature", "chorus", "chorusref", "bridge", "custom"}[pt]
+}
+
 func (f HtmlDivFormatter) appendParagraph(p *Paragraph) {
 
-	className := []string{"verse", "comment", "tablature", "chorus", "chorusref", "bridge"}[p.ParagraphType]
+	className := paragraphClass(p.ParagraphType)
 
 	switch p.ParagraphType {
 	case Tab, Comment:
@@ -128,6 +162,8 @@ func (f HtmlDivFormatter) appendParagraph(p *Paragraph) {
 		f.appendTagOpen(tagParagraph, className, false)
 		fmt.Fprint(f.w, "Chorus")
 		f.appendTagClose(tagParagraph, true)
+	case Custom:
+		fmt.Fprint(f.w, renderNode("html", p))
 	default:
 		f.appendTagOpen(tagParagraph, className, true)
 		for _, lin := range p.Lines {
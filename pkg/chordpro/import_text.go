@@ -0,0 +1,227 @@
+package chordpro
+
+import (
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strings"
+)
+
+func init() {
+	RegisterImporter("text", func() Importer { return TextImporter{} })
+}
+
+// TextImporter is the Importer of the "text" format:
+// plain text with the chords on their own line above the lyrics,
+// as found on Ultimate Guitar and similar sites.
+//
+// The importer recognizes:
+//   - metadata lines at the beginning, like "Artist: The Beatles";
+//   - section headers, like "[Verse 1]" or "[Chorus]";
+//   - chord lines, whose chords are aligned to the following lyric line;
+//   - tablature lines, like "e|---0---|".
+type TextImporter struct{}
+
+var (
+	reTextMeta   = regexp.MustCompile(`^(?i)(title|subtitle|artist|composer|lyricist|album|year|key|capo|tempo|time)\s*:\s*(.*\S)\s*$`)
+	reTextHeader = regexp.MustCompile(`^\s*\[([^\]]+)\]\s*:?\s*$`)
+	reTextTab    = regexp.MustCompile(`^\s*[A-Ga-g]?[#b]?\s*[|:][-0-9|:a-z/\\~*()<>^. ]*-[-0-9|:a-z/\\~*()<>^. ]*$`)
+)
+
+// isChordToken function reports whether the word of a chord line is a chord.
+func isChordToken(s string) bool {
+	if _, err := ParseChord(s); err == nil {
+		return true
+	}
+	switch s {
+	case "N.C.", "NC", "|", "||", "-", "/", "%":
+		return true
+	}
+	return false
+}
+
+// textChord is a chord of a chord line and its column.
+type textChord struct {
+	col   int
+	chord string
+}
+
+// lineWords function returns the words of the line and their columns.
+func lineWords(line string) []textChord {
+	var words []textChord
+	rs := []rune(line)
+	for j := 0; j < len(rs); {
+		if rs[j] == ' ' || rs[j] == '\t' {
+			j++
+			continue
+		}
+		k := j
+		for k < len(rs) && rs[k] != ' ' && rs[k] != '\t' {
+			k++
+		}
+		words = append(words, textChord{j, string(rs[j:k])})
+		j = k
+	}
+	return words
+}
+
+// textChords function returns the chords of the line,
+// or nil if the line is not a chord line.
+func textChords(line string) []textChord {
+	chords := lineWords(line)
+	for _, c := range chords {
+		if !isChordToken(c.chord) {
+			return nil
+		}
+	}
+	return chords
+}
+
+// mergeChordsLyrics function returns the line with the chords
+// aligned to the columns of the lyric.
+// If the lyric is shorter than the chord line, the missing
+// columns are filled with spaces.
+func mergeChordsLyrics(chords []textChord, lyric string) *Line {
+	lin := new(Line)
+	rs := []rune(lyric)
+
+	if len(chords) == 0 || chords[0].col > 0 {
+		end := len(rs)
+		if len(chords) > 0 && chords[0].col < end {
+			end = chords[0].col
+		}
+		if end > 0 {
+			lin.Pairs = append(lin.Pairs, &ChordLyricPair{Lyric: string(rs[:end])})
+		}
+	}
+
+	for j, c := range chords {
+		end := len(rs)
+		if j < len(chords)-1 {
+			end = chords[j+1].col
+		}
+		var txt string
+		switch {
+		case c.col < len(rs) && end <= len(rs):
+			txt = string(rs[c.col:end])
+		case c.col < len(rs):
+			txt = string(rs[c.col:]) + strings.Repeat(" ", end-len(rs))
+		case j < len(chords)-1:
+			txt = strings.Repeat(" ", end-c.col)
+		}
+		lin.Pairs = append(lin.Pairs, &ChordLyricPair{
+			Chord: string(chordBegin) + c.chord + string(chordEnd),
+			Lyric: txt,
+		})
+	}
+	return lin
+}
+
+// textSection function returns the paragraph type and label of a section header.
+func textSection(name string) (ParagraphType, string) {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasPrefix(lower, "pre"):
+		// pre-chorus
+		return Verse, name
+	case strings.Contains(lower, "chorus") || strings.Contains(lower, "refrain"):
+		if lower == "chorus" {
+			return Chorus, ""
+		}
+		return Chorus, name
+	case strings.Contains(lower, "bridge"):
+		if lower == "bridge" {
+			return Bridge, ""
+		}
+		return Bridge, name
+	case strings.Contains(lower, "tab"):
+		return Tab, name
+	}
+	return Verse, name
+}
+
+// ImportText parses the songs of a plain text in "chords over lyrics" format.
+func ImportText(src string) Songs {
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	src = strings.ReplaceAll(src, "\r", "\n")
+	lines := strings.Split(src, "\n")
+
+	cur := cursor{}
+	cur.newSong()
+	header := true // still in the metadata lines
+
+	for j := 0; j < len(lines); j++ {
+		line := strings.TrimRight(lines[j], " \t")
+		line = strings.ReplaceAll(line, "\t", "    ")
+
+		if strings.TrimSpace(line) == "" {
+			cur.closeParagraph()
+			continue
+		}
+
+		if header {
+			if m := reTextMeta.FindStringSubmatch(line); m != nil {
+				cur.getSong().meta.append(parseMetaFieldName(m[1]), m[2])
+				continue
+			}
+			header = false
+		}
+
+		// section header
+		if m := reTextHeader.FindStringSubmatch(line); m != nil && !isChordToken(strings.TrimSpace(m[1])) {
+			pt, label := textSection(strings.TrimSpace(m[1]))
+			p := cur.newParagraph()
+			p.ParagraphType = pt
+			p.Label = label
+			continue
+		}
+
+		// tablature
+		if reTextTab.MatchString(line) {
+			switch {
+			case cur.par != nil && len(cur.par.Lines) == 0:
+				// tablature just after a section header
+				cur.par.ParagraphType = Tab
+			case cur.par == nil || cur.par.ParagraphType != Tab:
+				cur.newParagraph().ParagraphType = Tab
+			}
+			cur.newPair().Lyric = line
+			cur.closeLine()
+			continue
+		}
+		if cur.par != nil && cur.par.ParagraphType == Tab {
+			cur.closeParagraph()
+		}
+
+		if chords := textChords(line); chords != nil {
+			lyric := ""
+			if j+1 < len(lines) {
+				next := strings.ReplaceAll(strings.TrimRight(lines[j+1], " \t"), "\t", "    ")
+				if strings.TrimSpace(next) != "" && textChords(next) == nil &&
+					!reTextHeader.MatchString(next) && !reTextTab.MatchString(next) {
+					lyric = next
+					j++
+				}
+			}
+			lin := mergeChordsLyrics(chords, lyric)
+			cur.getParagraph()
+			cur.par.Lines = append(cur.par.Lines, lin)
+			cur.closeLine()
+			continue
+		}
+
+		cur.newPair().Lyric = line
+		cur.closeLine()
+	}
+
+	return cur.songs
+}
+
+// Import reads the songs from the plain text.
+func (TextImporter) Import(r io.Reader) (Songs, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return ImportText(string(data)), nil
+}
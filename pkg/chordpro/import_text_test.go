@@ -0,0 +1,99 @@
+package chordpro
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_textChords(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []textChord
+	}{
+		{"chords", "C    G/B  Am", []textChord{{0, "C"}, {5, "G/B"}, {10, "Am"}}},
+		{"indented", "   Em7", []textChord{{3, "Em7"}}},
+		{"bars", "| C | G |", []textChord{{0, "|"}, {2, "C"}, {4, "|"}, {6, "G"}, {8, "|"}}},
+		{"lyric", "A boy and a girl", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := textChords(tt.input)
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+			for j := range got {
+				if got[j] != tt.want[j] {
+					t.Errorf("expected %v, got %v", tt.want, got)
+				}
+			}
+		})
+	}
+}
+
+func Test_mergeChordsLyrics(t *testing.T) {
+	tests := []struct {
+		name   string
+		chords string
+		lyric  string
+		want   string
+	}{
+		{"aligned", "C     G", "Hello world", "[C]Hello [G]world"},
+		{"inside word", "   Am", "Beyond", "Bey[Am]ond"},
+		{"beyond lyric", "C        G    D", "Hello", "[C]Hello    [G]     [D]"},
+		{"chords only", "C   G", "", "[C]    [G]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lin := mergeChordsLyrics(textChords(tt.chords), tt.lyric)
+			var sb strings.Builder
+			for _, pair := range lin.Pairs {
+				sb.WriteString(pair.Chord + pair.Lyric)
+			}
+			if got := sb.String(); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func Test_ImportText(t *testing.T) {
+	src := `Title: Wonderwall
+Artist: Oasis
+
+[Verse 1]
+Em7            G
+Today is gonna be the day
+
+[Chorus]
+C    D     Em
+And all the roads
+
+[Solo]
+e|-----0-----|
+B|---3---3---|
+`
+	want := `{title: Wonderwall}
+{artist: Oasis}
+
+{start_of_verse: Verse 1}
+[Em7]Today is gonna [G]be the day
+{end_of_verse}
+
+{start_of_chorus}
+[C]And a[D]ll the[Em] roads
+{end_of_chorus}
+
+{start_of_tab: Solo}
+e|-----0-----|
+B|---3---3---|
+{end_of_tab}
+`
+	var sb strings.Builder
+	if err := (ChordProFormatter{}).FormatSongs(&sb, ImportText(src)); err != nil {
+		t.Fatalf("unexpected error %q", err.Error())
+	}
+	if got := sb.String(); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,292 @@
+package chordpro
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// JSONSchemaVersion is the version of the JSON documents
+// written by the "json" format.
+const JSONSchemaVersion = 1
+
+// JSONSchemaID is the identifier of the JSON Schema of the documents.
+const JSONSchemaID = "https://raw.githubusercontent.com/mmbros/chordpro/master/schema/songs.v1.schema.json"
+
+// ErrJSONVersion is returned when the version of a JSON document is not supported.
+var ErrJSONVersion = errors.New("unsupported json document version")
+
+func init() {
+	RegisterFormatter("json", func() Formatter { return JSONFormatter{} })
+	RegisterImporter("json", func() Importer { return JSONImporter{} })
+}
+
+var metaFieldNames = map[metaFieldName]string{
+	metaTitle:     "title",
+	metaSortTitle: "sorttitle",
+	metaSubtitle:  "subtitle",
+	metaArtist:    "artist",
+	metaComposer:  "composer",
+	metaLyricist:  "lyricist",
+	metaCopyright: "copyright",
+	metaAlbum:     "album",
+	metaYear:      "year",
+	metaKey:       "key",
+	metaTime:      "time",
+	metaTempo:     "tempo",
+	metaDuration:  "duration",
+	metaCapo:      "capo",
+}
+
+func (name metaFieldName) String() string {
+	if s, ok := metaFieldNames[name]; ok {
+		return s
+	}
+	return fmt.Sprintf("metaFieldName:%d", int(name))
+}
+
+// parseMetaFieldName function returns the metaFieldName of the string.
+// It returns metaInvalid in case of unknown name.
+func parseMetaFieldName(s string) metaFieldName {
+	s = strings.ToLower(s)
+	for name, str := range metaFieldNames {
+		if str == s {
+			return name
+		}
+	}
+	return metaInvalid
+}
+
+var paragraphTypeNames = []string{"verse", "comment", "tab", "chorus", "chorusref", "bridge", "custom"}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (pt ParagraphType) MarshalText() ([]byte, error) {
+	if pt < 0 || int(pt) >= len(paragraphTypeNames) {
+		return nil, fmt.Errorf("invalid paragraph type %d", int(pt))
+	}
+	return []byte(paragraphTypeNames[pt]), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (pt *ParagraphType) UnmarshalText(text []byte) error {
+	s := strings.ToLower(string(text))
+	for j, name := range paragraphTypeNames {
+		if name == s {
+			*pt = ParagraphType(j)
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid paragraph type %q", s)
+}
+
+type jsonMeta struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type jsonSong struct {
+	Meta       []jsonMeta        `json:"meta"`
+	Paragraphs []*Paragraph      `json:"paragraphs"`
+	Error      string            `json:"error,omitempty"`
+	Pos        *Position         `json:"pos,omitempty"`
+	Custom     map[string]string `json:"custom,omitempty"`
+}
+
+type jsonPair struct {
+	Chord string    `json:"chord,omitempty"`
+	Lyric string    `json:"lyric,omitempty"`
+	Pos   *Position `json:"pos,omitempty"`
+}
+
+type jsonParagraph struct {
+	Type  ParagraphType `json:"type"`
+	Label string        `json:"label,omitempty"`
+	Lines []*Line       `json:"lines"`
+	Node  *CustomNode   `json:"node,omitempty"`
+	Pos   *Position     `json:"pos,omitempty"`
+}
+
+type jsonLine struct {
+	Pairs []*ChordLyricPair `json:"pairs"`
+	Pos   *Position         `json:"pos,omitempty"`
+}
+
+// jsonPos function returns the position to write, or nil if not valid.
+func jsonPos(p Position) *Position {
+	if !p.IsValid() {
+		return nil
+	}
+	return &p
+}
+
+// position function returns the position read, or the zero position if nil.
+func position(p *Position) Position {
+	if p == nil {
+		return Position{}
+	}
+	return *p
+}
+
+type jsonDocument struct {
+	Schema  string `json:"$schema,omitempty"`
+	Version int    `json:"version"`
+	Songs   Songs  `json:"songs"`
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+// The metadata are written as an ordered list of name/value objects.
+func (s *Song) MarshalJSON() ([]byte, error) {
+	js := jsonSong{
+		Meta:       []jsonMeta{},
+		Paragraphs: s.Paragraphs,
+		Pos:        jsonPos(s.Pos),
+		Custom:     s.Custom,
+	}
+	if js.Paragraphs == nil {
+		js.Paragraphs = []*Paragraph{}
+	}
+	for _, mi := range s.meta {
+		js.Meta = append(js.Meta, jsonMeta{mi.name.String(), mi.value})
+	}
+	if s.Err != nil {
+		js.Error = s.Err.Error()
+	}
+	return json.Marshal(js)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (s *Song) UnmarshalJSON(data []byte) error {
+	var js jsonSong
+	if err := json.Unmarshal(data, &js); err != nil {
+		return err
+	}
+
+	*s = Song{meta: metaItems{}, Paragraphs: js.Paragraphs, Pos: position(js.Pos), Custom: js.Custom}
+	for _, m := range js.Meta {
+		name := parseMetaFieldName(m.Name)
+		if name == metaInvalid {
+			return fmt.Errorf("invalid meta name %q", m.Name)
+		}
+		s.meta.append(name, m.Value)
+	}
+	if js.Error != "" {
+		s.Err = errors.New(js.Error)
+	}
+	return nil
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+// The chord is written without the square brackets.
+func (p *ChordLyricPair) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonPair{trimDelim(p.Chord), p.Lyric, jsonPos(p.Pos)})
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (p *ChordLyricPair) UnmarshalJSON(data []byte) error {
+	var jp jsonPair
+	if err := json.Unmarshal(data, &jp); err != nil {
+		return err
+	}
+	p.Lyric = jp.Lyric
+	p.Pos = position(jp.Pos)
+	p.Chord = ""
+	if jp.Chord != "" {
+		p.Chord = string(chordBegin) + jp.Chord + string(chordEnd)
+	}
+	return nil
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+// The lines are written as an empty list if nil,
+// and the position if valid.
+func (p *Paragraph) MarshalJSON() ([]byte, error) {
+	jp := jsonParagraph{p.ParagraphType, p.Label, p.Lines, p.Node, jsonPos(p.Pos)}
+	if jp.Lines == nil {
+		jp.Lines = []*Line{}
+	}
+	return json.Marshal(jp)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+// An empty list of lines is read as nil.
+func (p *Paragraph) UnmarshalJSON(data []byte) error {
+	var jp jsonParagraph
+	if err := json.Unmarshal(data, &jp); err != nil {
+		return err
+	}
+	if len(jp.Lines) == 0 {
+		jp.Lines = nil
+	}
+	*p = Paragraph{jp.Type, jp.Label, jp.Lines, jp.Node, position(jp.Pos)}
+	return nil
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+// The pairs are written as an empty list if nil,
+// and the position if valid.
+func (l *Line) MarshalJSON() ([]byte, error) {
+	jl := jsonLine{l.Pairs, jsonPos(l.Pos)}
+	if jl.Pairs == nil {
+		jl.Pairs = []*ChordLyricPair{}
+	}
+	return json.Marshal(jl)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (l *Line) UnmarshalJSON(data []byte) error {
+	var jl jsonLine
+	if err := json.Unmarshal(data, &jl); err != nil {
+		return err
+	}
+	*l = Line{jl.Pairs, position(jl.Pos)}
+	return nil
+}
+
+// ParseJSON parses the songs of a JSON document written by the "json" format.
+func ParseJSON(r io.Reader) (Songs, error) {
+	var doc jsonDocument
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, err
+	}
+	if doc.Version != JSONSchemaVersion {
+		return nil, fmt.Errorf("%w: %d", ErrJSONVersion, doc.Version)
+	}
+	return doc.Songs, nil
+}
+
+// JSONImporter is the Importer of the "json" format.
+type JSONImporter struct{}
+
+// Import reads the songs from the JSON document.
+func (JSONImporter) Import(r io.Reader) (Songs, error) {
+	return ParseJSON(r)
+}
+
+// JSONFormatter is the Formatter of the "json" format.
+// The output is a document with the version of the schema
+// and the list of the songs.
+type JSONFormatter struct{}
+
+// FormatSong writes a JSON document with the song.
+func (f JSONFormatter) FormatSong(w io.Writer, s *Song) error {
+	return f.FormatSongs(w, Songs{s})
+}
+
+// FormatSongs writes a JSON document with the songs.
+func (f JSONFormatter) FormatSongs(w io.Writer, ss Songs) error {
+	if ss == nil {
+		ss = Songs{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(jsonDocument{JSONSchemaID, JSONSchemaVersion, ss})
+}
+
+// Extension returns the ".json" extension.
+func (JSONFormatter) Extension() string { return ".json" }
+
+// MimeType returns the "application/json" MIME type.
+func (JSONFormatter) MimeType() string { return "application/json" }
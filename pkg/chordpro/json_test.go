@@ -0,0 +1,97 @@
+package chordpro
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParagraphType_MarshalText(t *testing.T) {
+	for pt := Verse; pt <= Custom; pt++ {
+		text, err := pt.MarshalText()
+		if err != nil {
+			t.Errorf("%v: unexpected error %q", pt, err.Error())
+			continue
+		}
+		var got ParagraphType
+		if err := got.UnmarshalText(text); err != nil {
+			t.Errorf("%v: unexpected error %q", pt, err.Error())
+			continue
+		}
+		if got != pt {
+			t.Errorf("expected %v, got %v", pt, got)
+		}
+	}
+
+	if _, err := ParagraphType(99).MarshalText(); err == nil {
+		t.Errorf("expected error, got nil")
+	}
+}
+
+func TestJSONFormatter_roundtrip(t *testing.T) {
+	src := `{t:Come Together}{st:Beatles}{t:Second title}
+{soc: Chorus 1}
+[Dm]Here come old [A7]flat top
+{eoc}
+{c:Play riff}
+{ns}
+{t:Other}
+[C]do`
+	want := ParseText(src)
+
+	var buf bytes.Buffer
+	if err := (JSONFormatter{}).FormatSongs(&buf, want); err != nil {
+		t.Fatalf("unexpected error %q", err.Error())
+	}
+	if !strings.Contains(buf.String(), `"chord": "Dm"`) {
+		t.Errorf("chord must be written without brackets")
+	}
+	if strings.Contains(buf.String(), `null`) || !strings.Contains(buf.String(), `"lines": []`) {
+		t.Errorf("empty lines must be written as an empty list")
+	}
+
+	got, err := ParseJSON(&buf)
+	if err != nil {
+		t.Fatalf("unexpected error %q", err.Error())
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestParseJSON_errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"version", `{"version": 2, "songs": []}`},
+		{"meta", `{"version": 1, "songs": [{"meta": [{"name": "xxx", "value": ""}], "paragraphs": []}]}`},
+		{"type", `{"version": 1, "songs": [{"meta": [], "paragraphs": [{"type": "xxx"}]}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseJSON(strings.NewReader(tt.input)); err == nil {
+				t.Errorf("expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestJSONSchema(t *testing.T) {
+	data, err := ioutil.ReadFile("../../schema/songs.v1.schema.json")
+	if err != nil {
+		t.Fatalf("unexpected error %q", err.Error())
+	}
+	var schema struct {
+		ID string `json:"$id"`
+	}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatalf("unexpected error %q", err.Error())
+	}
+	if schema.ID != JSONSchemaID {
+		t.Errorf("expected %q, got %q", JSONSchemaID, schema.ID)
+	}
+}
@@ -0,0 +1,193 @@
+package chordpro
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+func init() {
+	RegisterFormatter("latex", func() Formatter { return LatexFormatter{} })
+}
+
+// LatexFormatter is the Formatter of the "latex" format.
+// The output uses the macros of the LaTeX "songs" package:
+// each song is a \beginsong ... \endsong block, with verses and choruses
+// as \beginverse and \beginchorus environments and inline \[C] chords.
+type LatexFormatter struct{}
+
+// latexEscaper escapes the LaTeX special characters of the text.
+var latexEscaper = strings.NewReplacer(
+	`\`, `\textbackslash{}`,
+	`{`, `\{`, `}`, `\}`,
+	`$`, `\$`, `&`, `\&`, `#`, `\#`, `%`, `\%`, `_`, `\_`,
+	`^`, `\^{}`, `~`, `\~{}`,
+)
+
+// latexChordEscaper escapes the LaTeX special characters of a chord.
+// The '#' and '&' characters are not escaped, since inside
+// the \[...] macro they are the sharp and flat signs.
+var latexChordEscaper = strings.NewReplacer(
+	`\`, `\textbackslash{}`,
+	`{`, `\{`, `}`, `\}`,
+	`$`, `\$`, `%`, `\%`, `_`, `\_`,
+	`^`, `\^{}`, `~`, `\~{}`,
+)
+
+func latexEscape(s string) string {
+	return latexEscaper.Replace(s)
+}
+
+func (f LatexFormatter) appendLine(sb *strings.Builder, lin *Line) {
+	for _, pair := range lin.Pairs {
+		if pair.Chord != "" {
+			fmt.Fprintf(sb, `\[%s]`, latexChordEscaper.Replace(trimDelim(pair.Chord)))
+		}
+		sb.WriteString(latexEscape(pair.Lyric))
+	}
+	fmt.Fprintln(sb)
+}
+
+func (f LatexFormatter) appendParagraph(sb *strings.Builder, p *Paragraph) {
+	switch p.ParagraphType {
+	case Comment:
+		for _, lin := range p.Lines {
+			var txt strings.Builder
+			for _, pair := range lin.Pairs {
+				txt.WriteString(pair.Lyric)
+			}
+			fmt.Fprintf(sb, "\\textnote{%s}\n", latexEscape(strings.TrimSpace(txt.String())))
+		}
+	case ChorusRef:
+		label := p.Label
+		if label == "" {
+			label = "Chorus"
+		}
+		fmt.Fprintf(sb, "\\textnote{%s}\n", latexEscape(label))
+	case Custom:
+		sb.WriteString(renderNode("latex", p))
+	case Tab:
+		fmt.Fprintln(sb, `\beginverse*`)
+		for _, lin := range p.Lines {
+			var txt strings.Builder
+			for _, pair := range lin.Pairs {
+				txt.WriteString(pair.Lyric)
+			}
+			// keep the spaces of the tablature
+			s := strings.ReplaceAll(latexEscape(txt.String()), " ", "~")
+			fmt.Fprintf(sb, "\\texttt{%s}\n", s)
+		}
+		fmt.Fprintln(sb, `\endverse`)
+	case Chorus:
+		if p.Label != "" {
+			fmt.Fprintf(sb, "\\textnote{%s}\n", latexEscape(p.Label))
+		}
+		fmt.Fprintln(sb, `\beginchorus`)
+		for _, lin := range trimBlankLines(p.Lines) {
+			f.appendLine(sb, lin)
+		}
+		fmt.Fprintln(sb, `\endchorus`)
+	default:
+		// verses and bridges
+		begin := `\beginverse`
+		if h := paragraphHeader(p); h != "" {
+			fmt.Fprintf(sb, "\\textnote{%s}\n", latexEscape(h))
+			begin = `\beginverse*`
+		}
+		fmt.Fprintln(sb, begin)
+		for _, lin := range trimBlankLines(p.Lines) {
+			f.appendLine(sb, lin)
+		}
+		fmt.Fprintln(sb, `\endverse`)
+	}
+}
+
+// latexSongOptions function returns the optional arguments
+// of the \beginsong macro.
+func latexSongOptions(s *Song) string {
+	var opts []string
+
+	by := s.Artist()
+	if by == "" {
+		by = s.Composer()
+	}
+	if by != "" {
+		opts = append(opts, fmt.Sprintf("by={%s}", latexEscape(by)))
+	}
+	if cr := s.Copyright(); cr != "" {
+		opts = append(opts, fmt.Sprintf("cr={%s}", latexEscape(cr)))
+	}
+	if len(opts) == 0 {
+		return ""
+	}
+	return "[" + strings.Join(opts, ",") + "]"
+}
+
+func (f LatexFormatter) appendSong(sb *strings.Builder, s *Song) {
+	title := latexEscape(s.Title())
+	if st := s.SubTitle(); st != "" && st != s.Artist() {
+		// alternative titles are separated by \\
+		title += `\\` + latexEscape(st)
+	}
+	fmt.Fprintf(sb, "\\beginsong{%s}%s\n", title, latexSongOptions(s))
+	if capo := s.Capo(); capo != "" {
+		fmt.Fprintf(sb, "\\capo{%s}\n", latexEscape(capo))
+	}
+
+	for _, p := range s.Paragraphs {
+		if p.ParagraphType != ChorusRef && isBlank(p) && renderNode("latex", p) == "" {
+			continue
+		}
+		f.appendParagraph(sb, p)
+	}
+
+	if s.Err != nil {
+		fmt.Fprintf(sb, "%% error: %s\n", strings.ReplaceAll(s.Err.Error(), "\n", " "))
+	}
+	fmt.Fprintln(sb, `\endsong`)
+}
+
+// FormatSong writes the song as a \beginsong ... \endsong block.
+// The block must be placed inside a "songs" environment.
+func (f LatexFormatter) FormatSong(w io.Writer, s *Song) error {
+	var sb strings.Builder
+	f.appendSong(&sb, s)
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// FormatSongs writes the songs as a complete LaTeX songbook document,
+// with the index of the titles and the index of the authors.
+func (f LatexFormatter) FormatSongs(w io.Writer, ss Songs) error {
+	var sb strings.Builder
+
+	sb.WriteString(`\documentclass{article}
+\usepackage[utf8]{inputenc}
+\usepackage[T1]{fontenc}
+\usepackage[chorded]{songs}
+\newindex{titleidx}{titleidx}
+\newauthorindex{authidx}{authidx}
+
+\begin{document}
+\showindex{Index of Song Titles}{titleidx}
+\showindex{Index of Authors}{authidx}
+
+\begin{songs}{titleidx,authidx}
+`)
+	for _, s := range ss {
+		fmt.Fprintln(&sb)
+		f.appendSong(&sb, s)
+	}
+	sb.WriteString(`
+\end{songs}
+\end{document}
+`)
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// Extension returns the ".tex" extension.
+func (LatexFormatter) Extension() string { return ".tex" }
+
+// MimeType returns the "application/x-tex" MIME type.
+func (LatexFormatter) MimeType() string { return "application/x-tex" }
@@ -0,0 +1,53 @@
+package chordpro
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_latexEscape(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"plain text", "plain text"},
+		{"50% & $5", `50\% \& \$5`},
+		{`a\b`, `a\textbackslash{}b`},
+		{"{x_1^2}", `\{x\_1\^{}2\}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := latexEscape(tt.input); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestLatexFormatter_FormatSong(t *testing.T) {
+	src := `{t:Come Together}{artist:The Beatles}
+[Dm]Here come old [F#]flat top 100%
+
+{soc}
+[A]Come to[G]gether
+{eoc}
+{c:Play riff}`
+
+	want := `\beginsong{Come Together}[by={The Beatles}]
+\beginverse
+\[Dm]Here come old \[F#]flat top 100\%
+\endverse
+\beginchorus
+\[A]Come to\[G]gether
+\endchorus
+\textnote{Play riff}
+\endsong
+`
+	var sb strings.Builder
+	if err := (LatexFormatter{}).FormatSong(&sb, ParseText(src)[0]); err != nil {
+		t.Fatalf("unexpected error %q", err.Error())
+	}
+	if got := sb.String(); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
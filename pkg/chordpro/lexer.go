@@ -114,8 +114,16 @@ func stateChord(l *lexer.L) lexer.StateFunc {
 			if r == lexer.EOFRune {
 				ch = "EOF"
 			}
-			l.Error(fmt.Sprintf("ChordState Invalid token: expected %q, got %q", chordEnd, ch))
-			return nil
+			// the error is at the start of the unterminated token
+			l.ErrorAt(l.TokenPosition(), fmt.Sprintf("ChordState Invalid token: expected %q, got %q", chordEnd, ch))
+
+			// resync at the next line, dropping the unterminated chord
+			l.Rewind()
+			l.Ignore()
+			if r == lexer.EOFRune {
+				return nil
+			}
+			return stateNewline
 		}
 	}
 
@@ -143,14 +151,21 @@ func stateDirective(l *lexer.L) lexer.StateFunc {
 			// l.Ignore()
 			return stateText
 
-		// case '\n', '\r', lexer.EOFRune:
-		case lexer.EOFRune:
+		case '\n', '\r', lexer.EOFRune:
 			ch := string(r)
 			if r == lexer.EOFRune {
 				ch = "EOF"
 			}
-			l.Error(fmt.Sprintf("DirectiveState Invalid token: expected %q, got %q", directiveEnd, ch))
-			return nil
+			// the error is at the start of the unterminated token
+			l.ErrorAt(l.TokenPosition(), fmt.Sprintf("DirectiveState Invalid token: expected %q, got %q", directiveEnd, ch))
+
+			// resync at the next line, dropping the unterminated directive
+			l.Rewind()
+			l.Ignore()
+			if r == lexer.EOFRune {
+				return nil
+			}
+			return stateNewline
 		}
 	}
 
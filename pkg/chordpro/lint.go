@@ -0,0 +1,326 @@
+package chordpro
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mmbros/chordpro/internal/lexer"
+)
+
+// Severity is the severity of a Diagnostic.
+type Severity int
+
+// Severities of the diagnostics, from the lowest.
+const (
+	SeverityInfo Severity = iota
+	SeverityWarning
+	SeverityError
+)
+
+var severityNames = []string{"info", "warning", "error"}
+
+// String returns the name of the severity.
+func (s Severity) String() string {
+	if s < 0 || int(s) >= len(severityNames) {
+		return fmt.Sprintf("Severity:%d", s)
+	}
+	return severityNames[s]
+}
+
+// MarshalText returns the name of the severity.
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// ParseSeverity returns the severity of the name, whatever its case.
+func ParseSeverity(name string) (Severity, error) {
+	for j, s := range severityNames {
+		if strings.EqualFold(name, s) {
+			return Severity(j), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid severity: %q (valid severities: %s)", name, strings.Join(severityNames, ", "))
+}
+
+// Codes of the diagnostics.
+const (
+	CodeUnknownDirective      = "unknown-directive"
+	CodeUnsupportedDirective  = "unsupported-directive"
+	CodeUnclosedEnvironment   = "unclosed-environment"
+	CodeUnmatchedEnd          = "unmatched-end"
+	CodeMissingTitle          = "missing-title"
+	CodeDuplicateMeta         = "duplicate-meta"
+	CodeInvalidChord          = "invalid-chord"
+	CodeUnterminatedChord     = "unterminated-chord"
+	CodeUnterminatedDirective = "unterminated-directive"
+	CodeSyntaxError           = "syntax-error"
+	CodeDirectiveError        = "directive-error"
+)
+
+// Diagnostic is a problem of a ChordPro source.
+type Diagnostic struct {
+	Line     int      `json:"line"` // line number, starting from 1
+	Col      int      `json:"col"`  // column number in runes, starting from 1
+	Severity Severity `json:"severity"`
+	Code     string   `json:"code"`
+	Message  string   `json:"message"`
+}
+
+// String returns the diagnostic as "line:col: severity: message (code)".
+func (d *Diagnostic) String() string {
+	return fmt.Sprintf("%d:%d: %s: %s (%s)", d.Line, d.Col, d.Severity, d.Message, d.Code)
+}
+
+// environments are the start and end directives of the environments,
+// by their short name.
+var environments = []struct {
+	start, end         string
+	startLong, endLong string
+	ignoreChords       bool
+}{
+	{"sov", "eov", "start_of_verse", "end_of_verse", false},
+	{"soc", "eoc", "start_of_chorus", "end_of_chorus", false},
+	{"sob", "eob", "start_of_bridge", "end_of_bridge", false},
+	{"sot", "eot", "start_of_tab", "end_of_tab", true},
+}
+
+// environmentOf function returns the index in environments of the directive,
+// and whether it starts or ends the environment, or -1.
+func environmentOf(name string) (int, bool) {
+	for j, e := range environments {
+		switch name {
+		case e.start, e.startLong:
+			return j, true
+		case e.end, e.endLong:
+			return j, false
+		}
+	}
+	return -1, false
+}
+
+// otherDirectives are the directives of the parser that are not meta-data,
+// environments nor new_song.
+var otherDirectives = map[string]bool{
+	"comment": true, "c": true,
+	"chorus": true,
+}
+
+// unsupportedDirectives are the directives of the ChordPro format
+// that are ignored by the parser.
+var unsupportedDirectives = map[string]bool{
+	"comment_italic": true, "ci": true, "comment_box": true, "cb": true,
+	"highlight": true, "image": true, "define": true, "chord": true,
+	"start_of_grid": true, "sog": true, "end_of_grid": true, "eog": true,
+	"new_page": true, "np": true, "new_physical_page": true, "npp": true,
+	"column_break": true, "colb": true, "columns": true, "col": true,
+	"pagetype": true, "grid": true, "g": true, "no_grid": true, "ng": true,
+	"titles": true, "transpose": true, "arranger": true, "tag": true,
+	"textfont": true, "textsize": true, "textcolour": true,
+	"chordfont": true, "chordsize": true, "chordcolour": true,
+	"tabfont": true, "tabsize": true, "tabcolour": true,
+}
+
+// isKnownDirective function reports whether the name is a directive
+// of the ChordPro format, supported or not.
+func isKnownDirective(name string) bool {
+	if j, _ := environmentOf(name); j >= 0 {
+		return true
+	}
+	switch {
+	case name == "new_song", name == "ns", name == "meta":
+	case metaFieldByName(name) != metaNone:
+	case otherDirectives[name], unsupportedDirectives[name]:
+	default:
+		return false
+	}
+	return true
+}
+
+// singleMeta are the meta-data fields that a song has once at most.
+var singleMeta = map[metaFieldName]bool{
+	metaTitle:     true,
+	metaSortTitle: true,
+	metaYear:      true,
+	metaTempo:     true,
+	metaDuration:  true,
+	metaCapo:      true,
+}
+
+// isChordAnnotation function reports whether the chord is not a chord symbol
+// but an annotation, like "*Coda", or no chord, like "N.C.".
+func isChordAnnotation(s string) bool {
+	switch strings.ToUpper(s) {
+	case "N.C.", "NC", "N.C", "X", "":
+		return true
+	}
+	return strings.HasPrefix(s, "*")
+}
+
+// linter checks the songs of a source.
+type linter struct {
+	diags []Diagnostic
+
+	songLine, songCol int  // position of the current song
+	empty             bool // the current song has no text, chord or directive yet
+	title             bool // the current song has a title
+	meta              map[metaItem]bool
+	env               int // index of the open environment, or -1
+	envLine, envCol   int
+	envName           string
+}
+
+func (li *linter) report(line, col int, sev Severity, code, format string, args ...interface{}) {
+	li.diags = append(li.diags, Diagnostic{
+		Line:     line,
+		Col:      col,
+		Severity: sev,
+		Code:     code,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+func (li *linter) startSong(line, col int) {
+	li.songLine, li.songCol = line, col
+	li.empty, li.title = true, false
+	li.meta = map[metaItem]bool{}
+	li.env = -1
+}
+
+func (li *linter) endSong() {
+	if li.env >= 0 {
+		li.report(li.envLine, li.envCol, SeverityError, CodeUnclosedEnvironment,
+			"{%s} environment not closed", li.envName)
+		li.env = -1
+	}
+	if !li.title && !li.empty {
+		li.report(li.songLine, li.songCol, SeverityWarning, CodeMissingTitle, "song without {title}")
+	}
+}
+
+func (li *linter) directive(src string, line, col int) {
+	raw := strings.ToLower(strings.TrimSpace(strings.SplitN(trimDelim(src), directiveNameSep, 2)[0]))
+	name, arg := splitDirective(src)
+	name, _ = splitSelector(name, isKnownDirective)
+
+	if name == "new_song" || name == "ns" {
+		li.endSong()
+		li.startSong(line, col)
+		return
+	}
+	li.empty = false
+
+	if field := metaFieldByName(name); field != metaNone {
+		if field == metaTitle {
+			li.title = true
+		}
+		key := metaItem{name: field}
+		if !singleMeta[field] {
+			key.value = arg
+		}
+		if li.meta[key] {
+			li.report(line, col, SeverityWarning, CodeDuplicateMeta, "duplicate {%s} meta-data", name)
+		}
+		li.meta[key] = true
+		return
+	}
+	if raw == "meta" {
+		li.report(line, col, SeverityInfo, CodeUnsupportedDirective, "meta-data %q is ignored", name)
+		return
+	}
+
+	if j, start := environmentOf(name); j >= 0 {
+		switch {
+		case start && li.env >= 0:
+			li.report(li.envLine, li.envCol, SeverityError, CodeUnclosedEnvironment,
+				"{%s} environment not closed before {%s}", li.envName, name)
+			fallthrough
+		case start:
+			li.env, li.envLine, li.envCol, li.envName = j, line, col, name
+		case li.env != j:
+			li.report(line, col, SeverityError, CodeUnmatchedEnd,
+				"{%s} without matching {%s}", name, environments[j].start)
+		default:
+			li.env = -1
+		}
+		return
+	}
+
+	switch {
+	case otherDirectives[name]:
+	case unsupportedDirectives[name]:
+		li.report(line, col, SeverityInfo, CodeUnsupportedDirective, "directive {%s} is ignored", name)
+	case strings.HasPrefix(name, "x_"):
+		// custom directive
+	default:
+		li.report(line, col, SeverityWarning, CodeUnknownDirective, "unknown directive {%s}", name)
+	}
+}
+
+func (li *linter) chord(src string, line, col int) {
+	li.empty = false
+	if li.env >= 0 && environments[li.env].ignoreChords {
+		return
+	}
+	s := strings.TrimSpace(trimDelim(src))
+	if isChordAnnotation(s) {
+		return
+	}
+	if _, err := ParseChord(s); err != nil {
+		li.report(line, col, SeverityWarning, CodeInvalidChord, "invalid chord %q", s)
+	}
+}
+
+// parserDiagnostic function returns the diagnostic of the parser for the linter,
+// with the message of the unterminated chords and directives
+// in place of the one of the lexer.
+func parserDiagnostic(d Diagnostic) Diagnostic {
+	switch d.Code {
+	case CodeUnterminatedChord:
+		d.Message = fmt.Sprintf("unterminated chord: missing %q", chordEnd)
+	case CodeUnterminatedDirective:
+		d.Message = fmt.Sprintf("unterminated directive: missing %q", directiveEnd)
+	}
+	return d
+}
+
+// Lint checks the ChordPro source and returns its diagnostics,
+// in the order of the source: the errors recovered by the parser,
+// like the unterminated chords and directives, and the problems
+// found in the tokens of the lexer.
+func Lint(src string) []Diagnostic {
+	li := &linter{}
+	for _, song := range ParseText(src) {
+		for _, d := range song.Diagnostics {
+			li.diags = append(li.diags, parserDiagnostic(d))
+		}
+	}
+
+	l := lexer.New(src, stateText)
+	l.StartSync()
+
+	li.startSong(1, 1)
+	for {
+		tok, done := l.NextToken()
+		if done {
+			break
+		}
+		switch tok.Type {
+		case tokenChord:
+			li.chord(tok.Value, tok.Pos.Line, tok.Pos.Col)
+		case tokenDirective:
+			li.directive(tok.Value, tok.Pos.Line, tok.Pos.Col)
+		case tokenText:
+			if strings.TrimSpace(tok.Value) != "" {
+				li.empty = false
+			}
+		}
+	}
+	li.endSong()
+
+	sort.SliceStable(li.diags, func(a, b int) bool {
+		da, db := li.diags[a], li.diags[b]
+		return da.Line < db.Line || da.Line == db.Line && da.Col < db.Col
+	})
+	return li.diags
+}
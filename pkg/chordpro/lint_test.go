@@ -0,0 +1,103 @@
+package chordpro
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	tests := []struct {
+		name  string
+		src   string
+		diags []string
+	}{
+		{name: "ok", src: "{title: Yesterday}\n{soc}\n[C]do [Am7/G]re\n{eoc}\n# comment {x\n"},
+		{name: "empty"},
+		{
+			name:  "unknown directive",
+			src:   "{title: A}\n  {foo: bar}\n{x_custom}\n{textsize: 12}",
+			diags: []string{"2:3: warning: unknown directive {foo} (unknown-directive)", "4:1: info: directive {textsize} is ignored (unsupported-directive)"},
+		},
+		{
+			name:  "hyphen",
+			src:   "{title: A}\n{x-strum: D}\n{soc-guitar}\n{eoc}",
+			diags: []string{"2:1: warning: unknown directive {x-strum} (unknown-directive)"},
+		},
+		{
+			name:  "unclosed",
+			src:   "{title: A}\n{soc}\n[C]do\n{sov}\n{eov}\n{sot}",
+			diags: []string{"2:1: error: {soc} environment not closed before {sov} (unclosed-environment)", "6:1: error: {sot} environment not closed (unclosed-environment)"},
+		},
+		{
+			name:  "unmatched end",
+			src:   "{title: A}\n{soc}\n{eoc}\n{end_of_chorus}",
+			diags: []string{"4:1: error: {end_of_chorus} without matching {soc} (unmatched-end)"},
+		},
+		{
+			name:  "missing title",
+			src:   "{new_song}\n[C]do\n{ns}\n{t: B}\n{ns}\n",
+			diags: []string{"1:1: warning: song without {title} (missing-title)"},
+		},
+		{
+			name:  "duplicate meta",
+			src:   "{title: A}\n{artist: X}\n{artist: Y}\n{meta: artist X}\n{t: B}",
+			diags: []string{"4:1: warning: duplicate {artist} meta-data (duplicate-meta)", "5:1: warning: duplicate {t} meta-data (duplicate-meta)"},
+		},
+		{
+			name:  "invalid chord",
+			src:   "{title: A}\r\nla [H7]la [N.C.] [*Coda]\r\n{sot}\r\n[xx]\r\n{eot}",
+			diags: []string{"2:4: warning: invalid chord \"H7\" (invalid-chord)"},
+		},
+		{
+			name:  "unterminated",
+			src:   "{title: A}\nà [C\n[D]do {soc\n",
+			diags: []string{"2:3: error: unterminated chord: missing ']' (unterminated-chord)", "3:7: error: unterminated directive: missing '}' (unterminated-directive)"},
+		},
+		{
+			name: "resync",
+			src:  "{title: A}\n{soc\n[H7]la\n{eoc",
+			diags: []string{
+				"2:1: error: unterminated directive: missing '}' (unterminated-directive)",
+				"3:1: warning: invalid chord \"H7\" (invalid-chord)",
+				"4:1: error: unterminated directive: missing '}' (unterminated-directive)",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diags := Lint(tt.src)
+			if len(diags) != len(tt.diags) {
+				t.Fatalf("expected %d diagnostics, got %v", len(tt.diags), diags)
+			}
+			for j, d := range diags {
+				if got := d.String(); got != tt.diags[j] {
+					t.Errorf("expected %q, got %q", tt.diags[j], got)
+				}
+			}
+		})
+	}
+}
+
+func TestDiagnostic_JSON(t *testing.T) {
+	d := Diagnostic{Line: 2, Col: 3, Severity: SeverityWarning, Code: CodeInvalidChord, Message: "invalid chord"}
+	got, err := json.Marshal(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"line":2,"col":3,"severity":"warning","code":"invalid-chord","message":"invalid chord"}`
+	if string(got) != expected {
+		t.Errorf("expected %s, got %s", expected, got)
+	}
+}
+
+func TestParseSeverity(t *testing.T) {
+	for _, s := range []Severity{SeverityInfo, SeverityWarning, SeverityError} {
+		got, err := ParseSeverity(s.String())
+		if err != nil || got != s {
+			t.Errorf("expected %v, got %v (%v)", s, got, err)
+		}
+	}
+	if _, err := ParseSeverity("fatal"); err == nil {
+		t.Errorf("expected error, got nil")
+	}
+}
@@ -0,0 +1,139 @@
+package chordpro
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+func init() {
+	RegisterFormatter("markdown", func() Formatter { return &MarkdownFormatter{Width: DefaultTextWidth} })
+}
+
+// MarkdownFormatter is the Formatter of the "markdown" format.
+// The lines are printed chords over lyrics inside fenced code blocks,
+// so that the output doesn't contain raw HTML.
+// Labeled paragraphs, choruses and bridges have a section heading.
+type MarkdownFormatter struct {
+	// Width is the maximum number of columns of a row.
+	// Longer lines are wrapped. Zero or negative means no wrap.
+	Width int
+}
+
+// mdEscaper escapes the characters with a special meaning in Markdown text.
+var mdEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", `*`, `\*`, `_`, `\_`,
+	`[`, `\[`, `]`, `\]`, `<`, `\<`, `>`, `\>`, `#`, `\#`,
+)
+
+// mdFence function returns a code fence longer than
+// any sequence of backticks found in the text.
+func mdFence(txt string) string {
+	fence := "```"
+	for strings.Contains(txt, fence) {
+		fence += "`"
+	}
+	return fence
+}
+
+func (f *MarkdownFormatter) appendCodeBlock(sb *strings.Builder, txt string) {
+	fence := mdFence(txt)
+	fmt.Fprintf(sb, "%stext\n%s%s\n", fence, txt, fence)
+}
+
+func (f *MarkdownFormatter) appendParagraph(sb *strings.Builder, p *Paragraph) {
+	if h := paragraphHeader(p); h != "" {
+		fmt.Fprintf(sb, "## %s\n\n", mdEscaper.Replace(h))
+	}
+
+	var block strings.Builder
+
+	switch p.ParagraphType {
+	case Comment:
+		for _, lin := range p.Lines {
+			var txt strings.Builder
+			for _, pair := range lin.Pairs {
+				txt.WriteString(pair.Lyric)
+			}
+			fmt.Fprintf(sb, "*%s*\n", mdEscaper.Replace(strings.TrimSpace(txt.String())))
+		}
+		return
+	case ChorusRef:
+		label := p.Label
+		if label == "" {
+			label = "Chorus"
+		}
+		fmt.Fprintf(sb, "**%s**\n", mdEscaper.Replace(label))
+		return
+	case Custom:
+		sb.WriteString(renderNode("markdown", p))
+		return
+	case Tab:
+		for _, lin := range p.Lines {
+			for _, pair := range lin.Pairs {
+				block.WriteString(pair.Lyric)
+			}
+			fmt.Fprintln(&block)
+		}
+	default:
+		tf := TextFormatter{Width: f.Width}
+		for _, lin := range trimBlankLines(p.Lines) {
+			tf.appendLine(&block, lin)
+		}
+	}
+	f.appendCodeBlock(sb, block.String())
+}
+
+func (f *MarkdownFormatter) appendBody(sb *strings.Builder, s *Song) {
+	first := true
+	for _, p := range s.Paragraphs {
+		if p.ParagraphType != ChorusRef && isBlank(p) && renderNode("markdown", p) == "" {
+			continue
+		}
+		if !first {
+			fmt.Fprintln(sb)
+		}
+		f.appendParagraph(sb, p)
+		first = false
+	}
+
+	if s.Err != nil {
+		fmt.Fprintf(sb, "\n> **error:** %s\n", mdEscaper.Replace(s.Err.Error()))
+	}
+}
+
+// FormatSong writes the body of the song as Markdown.
+// The title of the song is not printed,
+// since it is expected to be in the front matter.
+func (f *MarkdownFormatter) FormatSong(w io.Writer, s *Song) error {
+	var sb strings.Builder
+	f.appendBody(&sb, s)
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// FormatSongs writes the songs as Markdown.
+// Each song begins with a title heading followed by the artist.
+func (f *MarkdownFormatter) FormatSongs(w io.Writer, ss Songs) error {
+	var sb strings.Builder
+	for j, s := range ss {
+		if j > 0 {
+			fmt.Fprintln(&sb)
+		}
+		fmt.Fprintf(&sb, "# %s\n\n", mdEscaper.Replace(s.Title()))
+		for _, st := range []string{s.SubTitle(), s.Artist()} {
+			if st != "" {
+				fmt.Fprintf(&sb, "*%s*\n\n", mdEscaper.Replace(st))
+			}
+		}
+		f.appendBody(&sb, s)
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// Extension returns the ".md" extension.
+func (f *MarkdownFormatter) Extension() string { return ".md" }
+
+// MimeType returns the "text/markdown" MIME type.
+func (f *MarkdownFormatter) MimeType() string { return "text/markdown" }
@@ -0,0 +1,51 @@
+package chordpro
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_mdFence(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"no backticks", "abc", "```"},
+		{"two backticks", "a``b", "```"},
+		{"three backticks", "a```b", "````"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mdFence(tt.input); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestMarkdownFormatter_FormatSong(t *testing.T) {
+	src := `{t:Come Together}
+{soc: Chorus 1}
+[Dm]Here come old flat top
+{eoc}
+{c:Play *riff*}
+
+[G]Shoot me
+{chorus}`
+
+	want := "## Chorus 1\n\n" +
+		"```text\nDm\nHere come old flat top\n```\n" +
+		"\n*Play \\*riff\\**\n" +
+		"\n```text\nG\nShoot me\n```\n" +
+		"\n**Chorus**\n"
+
+	var sb strings.Builder
+	f := &MarkdownFormatter{Width: DefaultTextWidth}
+	if err := f.FormatSong(&sb, ParseText(src)[0]); err != nil {
+		t.Fatalf("unexpected error %q", err.Error())
+	}
+	if got := sb.String(); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
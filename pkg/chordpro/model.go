@@ -42,10 +42,48 @@ type metaItems []*metaItem
 
 type Songs []*Song
 
+// Position is a position in the ChordPro source.
+// The zero Position is not valid: the node was not parsed from a source.
+type Position struct {
+	Offset int `json:"offset"` // byte offset, starting from 0
+	Line   int `json:"line"`   // line number, starting from 1
+	Col    int `json:"col"`    // column number in runes, starting from 1
+}
+
+// IsValid reports whether the position is set.
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+// String returns the position as "line:col", or "-" if not valid.
+func (p Position) String() string {
+	if !p.IsValid() {
+		return "-"
+	}
+	return fmt.Sprintf("%d:%d", p.Line, p.Col)
+}
+
+// ParseError is an error of the ChordPro source, at the position Pos.
+type ParseError struct {
+	Pos Position
+	Msg string
+}
+
+// Error returns the error as "line:col: message".
+func (e *ParseError) Error() string {
+	return e.Pos.String() + ": " + e.Msg
+}
+
 type Song struct {
-	meta       metaItems
-	Paragraphs []*Paragraph
-	Err        error
+	meta        metaItems
+	Pos         Position // position of the first token of the song
+	Paragraphs  []*Paragraph
+	Err         error        // first error of the song, if any
+	Diagnostics []Diagnostic // errors of the song, in source order
+
+	// Custom are the custom meta-data of the song,
+	// set by the directive handlers of the parser.
+	Custom map[string]string
 }
 
 type ParagraphType int
@@ -57,6 +95,7 @@ const (
 	Chorus
 	ChorusRef
 	Bridge
+	Custom // custom node of a directive handler
 )
 
 func (mis *metaItems) append(name metaFieldName, value string) {
@@ -89,15 +128,29 @@ type Paragraph struct {
 	ParagraphType ParagraphType
 	Label         string
 	Lines         []*Line
+	Node          *CustomNode // node of the Custom paragraph
+	Pos           Position    // position of the first token of the paragraph
+}
+
+// CustomNode is a node of the song added by a DirectiveHandler,
+// in a paragraph of type Custom.
+type CustomNode struct {
+	Name     string            `json:"name"`               // name of the node, usually of the directive
+	Selector string            `json:"selector,omitempty"` // selector of the directive, if any
+	Value    string            `json:"value,omitempty"`    // argument of the directive
+	Attrs    map[string]string `json:"attrs,omitempty"`    // attributes of the directive, if any
+	Data     interface{}       `json:"-"`                  // data of the handler
 }
 
 type Line struct {
 	Pairs []*ChordLyricPair
+	Pos   Position // position of the first token of the line
 }
 
 type ChordLyricPair struct {
 	Chord string
 	Lyric string
+	Pos   Position // position of the chord, or of the lyric without chord
 }
 
 func (pt ParagraphType) String() string {
@@ -115,6 +168,8 @@ func (pt ParagraphType) String() string {
 		return "ChorusRef"
 	case Bridge:
 		return "Bridge"
+	case Custom:
+		return "Custom"
 	default:
 		return fmt.Sprintf("ParagraphType:%d", pt)
 	}
@@ -163,6 +218,14 @@ func (ss Songs) String() string {
 	return sb.String()
 }
 
+// SetCustom sets the value of the custom meta-data name of the song.
+func (s *Song) SetCustom(name, value string) {
+	if s.Custom == nil {
+		s.Custom = map[string]string{}
+	}
+	s.Custom[name] = value
+}
+
 func (s *Song) Title() string {
 	return s.meta.byFieldName1(metaTitle)
 }
@@ -187,11 +250,34 @@ func (s *Song) Year() string {
 	return s.meta.byFieldName1(metaYear)
 }
 
-// metaComposer
-// metaLyricist
-// metaCopyright
-// metaKey
-// metaTime
-// metaTempo
-// metaDuration
-// metaCapo
+func (s *Song) Composer() string {
+	return s.meta.byFieldName1(metaComposer)
+}
+
+func (s *Song) Lyricist() string {
+	return s.meta.byFieldName1(metaLyricist)
+}
+
+func (s *Song) Copyright() string {
+	return s.meta.byFieldName1(metaCopyright)
+}
+
+func (s *Song) Key() string {
+	return s.meta.byFieldName1(metaKey)
+}
+
+func (s *Song) Time() string {
+	return s.meta.byFieldName1(metaTime)
+}
+
+func (s *Song) Tempo() string {
+	return s.meta.byFieldName1(metaTempo)
+}
+
+func (s *Song) Duration() string {
+	return s.meta.byFieldName1(metaDuration)
+}
+
+func (s *Song) Capo() string {
+	return s.meta.byFieldName1(metaCapo)
+}
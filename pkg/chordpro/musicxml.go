@@ -0,0 +1,455 @@
+package chordpro
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	RegisterFormatter("musicxml", func() Formatter { return MusicXMLFormatter{} })
+}
+
+// MusicXMLFormatter is the Formatter of the "musicxml" format:
+// a MusicXML 4.0 lead sheet that can be opened by MuseScore and
+// other notation software.
+// Each word of the lyrics is attached to a placeholder note of one beat,
+// and the chords are written as <harmony> elements before their note.
+// The lines are split in measures of the time signature of the {time}
+// metadata, 4/4 by default, and every line begins a new system.
+type MusicXMLFormatter struct{}
+
+// musicXMLDivisions is the number of divisions of a quarter note.
+const musicXMLDivisions = 2
+
+// musicXMLKinds maps the chord suffixes to the MusicXML kind values.
+var musicXMLKinds = map[string]string{
+	"":      "major",
+	"M":     "major",
+	"maj":   "major",
+	"m":     "minor",
+	"mi":    "minor",
+	"min":   "minor",
+	"-":     "minor",
+	"aug":   "augmented",
+	"+":     "augmented",
+	"dim":   "diminished",
+	"°":     "diminished",
+	"o":     "diminished",
+	"7":     "dominant",
+	"maj7":  "major-seventh",
+	"M7":    "major-seventh",
+	"ma7":   "major-seventh",
+	"Δ":     "major-seventh",
+	"Δ7":    "major-seventh",
+	"m7":    "minor-seventh",
+	"mi7":   "minor-seventh",
+	"min7":  "minor-seventh",
+	"-7":    "minor-seventh",
+	"dim7":  "diminished-seventh",
+	"°7":    "diminished-seventh",
+	"o7":    "diminished-seventh",
+	"aug7":  "augmented-seventh",
+	"+7":    "augmented-seventh",
+	"7#5":   "augmented-seventh",
+	"m7b5":  "half-diminished",
+	"ø":     "half-diminished",
+	"ø7":    "half-diminished",
+	"mmaj7": "major-minor",
+	"mM7":   "major-minor",
+	"6":     "major-sixth",
+	"m6":    "minor-sixth",
+	"9":     "dominant-ninth",
+	"maj9":  "major-ninth",
+	"M9":    "major-ninth",
+	"m9":    "minor-ninth",
+	"11":    "dominant-11th",
+	"m11":   "minor-11th",
+	"13":    "dominant-13th",
+	"maj13": "major-13th",
+	"m13":   "minor-13th",
+	"sus2":  "suspended-second",
+	"sus":   "suspended-fourth",
+	"sus4":  "suspended-fourth",
+	"5":     "power",
+}
+
+// musicXMLKind function returns the MusicXML kind of the chord suffix.
+// The suffixes without a matching kind are reported as "other".
+func musicXMLKind(suffix string) string {
+	if kind, ok := musicXMLKinds[suffix]; ok {
+		return kind
+	}
+	return "other"
+}
+
+// musicXMLStep function returns the step and the alteration of a note name.
+func musicXMLStep(note string) (string, int) {
+	step, alter := note[:1], 0
+	switch note[1:] {
+	case "#":
+		alter = 1
+	case "b":
+		alter = -1
+	}
+	return step, alter
+}
+
+// musicXMLFifths maps the major keys to the number of sharps (or flats, if negative).
+var musicXMLFifths = map[string]int{
+	"Cb": -7, "Gb": -6, "Db": -5, "Ab": -4, "Eb": -3, "Bb": -2, "F": -1,
+	"C": 0, "G": 1, "D": 2, "A": 3, "E": 4, "B": 5, "F#": 6, "C#": 7,
+}
+
+// musicXMLKey function returns the fifths and the mode of the key.
+// It returns false if the key is not valid.
+func musicXMLKey(key string) (int, string, bool) {
+	c, err := ParseChord(key)
+	if err != nil || c.Bass != "" {
+		return 0, "", false
+	}
+	fifths, ok := musicXMLFifths[c.Root]
+	switch c.Suffix {
+	case "":
+		return fifths, "major", ok
+	case "m", "mi", "min":
+		fifths -= 3
+		if fifths < -7 {
+			fifths += 12
+		}
+		return fifths, "minor", ok
+	}
+	return 0, "", false
+}
+
+// musicXMLTime function returns the beats and the beat type
+// of the time signature, 4/4 if it is not valid.
+func musicXMLTime(time string) (int, int) {
+	parts := strings.Split(strings.TrimSpace(time), "/")
+	if len(parts) == 2 {
+		beats, err1 := strconv.Atoi(parts[0])
+		beatType, err2 := strconv.Atoi(parts[1])
+		if err1 == nil && err2 == nil && beats > 0 && musicXMLNoteType(beatType) != "" {
+			return beats, beatType
+		}
+	}
+	return 4, 4
+}
+
+// musicXMLNoteType function returns the note type of the beat type.
+func musicXMLNoteType(beatType int) string {
+	switch beatType {
+	case 1:
+		return "whole"
+	case 2:
+		return "half"
+	case 4:
+		return "quarter"
+	case 8:
+		return "eighth"
+	case 16:
+		return "16th"
+	}
+	return ""
+}
+
+// musicXMLNote is a placeholder note of the lead sheet.
+type musicXMLNote struct {
+	chord    string // chord symbol with the brackets, or empty
+	text     string // lyric syllable, or empty
+	syllabic string // "single", "begin", "middle" or "end"
+}
+
+// musicXMLNotes function returns the notes of the line:
+// a note for each word of the lyrics, and a note for each chord without lyric.
+func musicXMLNotes(lin *Line) []musicXMLNote {
+	var notes []musicXMLNote
+	inWord := false // the previous note ends in the middle of a word
+
+	for _, pair := range lin.Pairs {
+		words := strings.Fields(pair.Lyric)
+		if len(words) == 0 {
+			if pair.Chord != "" {
+				notes = append(notes, musicXMLNote{chord: pair.Chord})
+			}
+			inWord = false
+			continue
+		}
+		startsWord := pair.Lyric[0] == ' ' || !inWord
+		endsWord := strings.HasSuffix(pair.Lyric, " ")
+
+		for j, w := range words {
+			n := musicXMLNote{text: w, syllabic: "single"}
+			if j == 0 {
+				n.chord = pair.Chord
+				if !startsWord {
+					n.syllabic = "end"
+				}
+			}
+			if j == len(words)-1 && !endsWord {
+				// the word may go on in the next pair
+				if n.syllabic == "end" {
+					n.syllabic = "middle"
+				} else {
+					n.syllabic = "begin"
+				}
+			}
+			notes = append(notes, n)
+		}
+		inWord = !endsWord
+	}
+
+	// the last syllable of the line ends its word
+	if k := len(notes) - 1; k >= 0 {
+		switch notes[k].syllabic {
+		case "begin":
+			notes[k].syllabic = "single"
+		case "middle":
+			notes[k].syllabic = "end"
+		}
+	}
+	return notes
+}
+
+// musicXMLWriter writes the measures of a MusicXML part.
+type musicXMLWriter struct {
+	sb       *strings.Builder
+	beats    int
+	noteType string
+	duration int
+	measure  int      // number of the current measure
+	beat     int      // beats already written in the current measure
+	newLine  bool     // the next measure begins a new system
+	attrs    string   // attributes of the first measure
+	words    []string // directions of the next measure
+}
+
+func (w *musicXMLWriter) beginMeasure() {
+	w.measure++
+	fmt.Fprintf(w.sb, "    <measure number=\"%d\">\n", w.measure)
+	if w.newLine && w.measure > 1 {
+		fmt.Fprintln(w.sb, `      <print new-system="yes"/>`)
+	}
+	w.newLine = false
+	w.sb.WriteString(w.attrs)
+	w.attrs = ""
+	for _, words := range w.words {
+		fmt.Fprintln(w.sb, `      <direction placement="above">`)
+		fmt.Fprintf(w.sb, "        <direction-type><words>%s</words></direction-type>\n", xmlEscape(words))
+		fmt.Fprintln(w.sb, `      </direction>`)
+	}
+	w.words = nil
+}
+
+func (w *musicXMLWriter) endMeasure() {
+	fmt.Fprintln(w.sb, "    </measure>")
+	w.beat = 0
+}
+
+// fill writes the rests of the rest of the current measure.
+func (w *musicXMLWriter) fill() {
+	if w.beat == 0 {
+		return
+	}
+	for ; w.beat < w.beats; w.beat++ {
+		fmt.Fprintf(w.sb, "      <note><rest/><duration>%d</duration><type>%s</type></note>\n", w.duration, w.noteType)
+	}
+	w.endMeasure()
+}
+
+func (w *musicXMLWriter) harmony(chord string) {
+	c, err := ParseChord(chord)
+	if err != nil {
+		// not a chord symbol, like "N.C."
+		fmt.Fprintln(w.sb, `      <direction placement="above">`)
+		fmt.Fprintf(w.sb, "        <direction-type><words>%s</words></direction-type>\n", xmlEscape(trimDelim(chord)))
+		fmt.Fprintln(w.sb, `      </direction>`)
+		return
+	}
+
+	fmt.Fprintln(w.sb, "      <harmony>")
+	step, alter := musicXMLStep(c.Root)
+	fmt.Fprintf(w.sb, "        <root><root-step>%s</root-step>", step)
+	if alter != 0 {
+		fmt.Fprintf(w.sb, "<root-alter>%d</root-alter>", alter)
+	}
+	fmt.Fprintln(w.sb, "</root>")
+	if c.Suffix == "" {
+		fmt.Fprintf(w.sb, "        <kind>%s</kind>\n", musicXMLKind(c.Suffix))
+	} else {
+		fmt.Fprintf(w.sb, "        <kind text=\"%s\">%s</kind>\n", xmlEscape(c.Suffix), musicXMLKind(c.Suffix))
+	}
+	if c.Bass != "" {
+		step, alter := musicXMLStep(c.Bass)
+		fmt.Fprintf(w.sb, "        <bass><bass-step>%s</bass-step>", step)
+		if alter != 0 {
+			fmt.Fprintf(w.sb, "<bass-alter>%d</bass-alter>", alter)
+		}
+		fmt.Fprintln(w.sb, "</bass>")
+	}
+	fmt.Fprintln(w.sb, "      </harmony>")
+}
+
+func (w *musicXMLWriter) note(n musicXMLNote) {
+	if w.beat == 0 {
+		w.beginMeasure()
+	}
+	if n.chord != "" {
+		w.harmony(n.chord)
+	}
+	fmt.Fprintln(w.sb, "      <note>")
+	fmt.Fprintln(w.sb, "        <pitch><step>B</step><octave>4</octave></pitch>")
+	fmt.Fprintf(w.sb, "        <duration>%d</duration>\n", w.duration)
+	fmt.Fprintf(w.sb, "        <type>%s</type>\n", w.noteType)
+	if n.text != "" {
+		fmt.Fprintf(w.sb, "        <lyric number=\"1\"><syllabic>%s</syllabic><text>%s</text></lyric>\n", n.syllabic, xmlEscape(n.text))
+	}
+	fmt.Fprintln(w.sb, "      </note>")
+
+	w.beat++
+	if w.beat == w.beats {
+		w.endMeasure()
+	}
+}
+
+func (f MusicXMLFormatter) appendAttributes(sb *strings.Builder, s *Song, beats, beatType int) {
+	fmt.Fprintln(sb, "      <attributes>")
+	fmt.Fprintf(sb, "        <divisions>%d</divisions>\n", musicXMLDivisions)
+	if fifths, mode, ok := musicXMLKey(s.Key()); ok {
+		fmt.Fprintf(sb, "        <key><fifths>%d</fifths><mode>%s</mode></key>\n", fifths, mode)
+	} else {
+		fmt.Fprintln(sb, "        <key><fifths>0</fifths></key>")
+	}
+	fmt.Fprintf(sb, "        <time><beats>%d</beats><beat-type>%d</beat-type></time>\n", beats, beatType)
+	fmt.Fprintln(sb, "        <clef><sign>G</sign><line>2</line></clef>")
+	fmt.Fprintln(sb, "      </attributes>")
+
+	if tempo, err := strconv.Atoi(strings.TrimSpace(s.Tempo())); err == nil && tempo > 0 {
+		fmt.Fprintln(sb, `      <direction placement="above">`)
+		fmt.Fprintf(sb, "        <direction-type><metronome><beat-unit>quarter</beat-unit><per-minute>%d</per-minute></metronome></direction-type>\n", tempo)
+		fmt.Fprintf(sb, "        <sound tempo=\"%d\"/>\n", tempo)
+		fmt.Fprintln(sb, "      </direction>")
+	}
+}
+
+func (f MusicXMLFormatter) appendPart(sb *strings.Builder, s *Song) {
+	beats, beatType := musicXMLTime(s.Time())
+	w := &musicXMLWriter{
+		sb:       sb,
+		beats:    beats,
+		noteType: musicXMLNoteType(beatType),
+		duration: musicXMLDivisions * 4 / beatType,
+	}
+	if w.duration == 0 {
+		// sixteenth beats
+		w.duration = 1
+	}
+
+	// the attributes go in the first measure
+	var attrs strings.Builder
+	f.appendAttributes(&attrs, s, beats, beatType)
+	w.attrs = attrs.String()
+
+	for _, p := range s.Paragraphs {
+		switch p.ParagraphType {
+		case Tab:
+			continue
+		case ChorusRef:
+			w.words = append(w.words, "Chorus")
+			continue
+		case Custom:
+			w.words = append(w.words, renderNodeLines("musicxml", p)...)
+			continue
+		case Comment:
+			for _, lin := range p.Lines {
+				var txt strings.Builder
+				for _, pair := range lin.Pairs {
+					txt.WriteString(pair.Lyric)
+				}
+				if t := strings.TrimSpace(txt.String()); t != "" {
+					w.words = append(w.words, t)
+				}
+			}
+			continue
+		}
+		if header := paragraphHeader(p); header != "" {
+			w.words = append(w.words, header)
+		}
+
+		for _, lin := range p.Lines {
+			notes := musicXMLNotes(lin)
+			if len(notes) == 0 {
+				continue
+			}
+			w.fill()
+			w.newLine = true
+			for _, n := range notes {
+				w.note(n)
+			}
+		}
+	}
+
+	w.fill()
+	if w.measure == 0 || len(w.words) > 0 {
+		// a measure of rest for a song without notes,
+		// or for the directions after the last note
+		w.beginMeasure()
+		fmt.Fprintf(sb, "      <note><rest measure=\"yes\"/><duration>%d</duration></note>\n", w.duration*beats)
+		w.endMeasure()
+	}
+}
+
+// FormatSong writes the song as a MusicXML score.
+func (f MusicXMLFormatter) FormatSong(w io.Writer, s *Song) error {
+	var sb strings.Builder
+
+	fmt.Fprintln(&sb, `<?xml version="1.0" encoding="UTF-8" standalone="no"?>`)
+	fmt.Fprintln(&sb, `<!DOCTYPE score-partwise PUBLIC "-//Recordare//DTD MusicXML 4.0 Partwise//EN" "http://www.musicxml.org/dtds/partwise.dtd">`)
+	fmt.Fprintln(&sb, `<score-partwise version="4.0">`)
+	fmt.Fprintf(&sb, "  <work><work-title>%s</work-title></work>\n", xmlEscape(s.Title()))
+
+	fmt.Fprintln(&sb, "  <identification>")
+	composer := s.Composer()
+	if composer == "" {
+		composer = s.Artist()
+	}
+	if composer != "" {
+		fmt.Fprintf(&sb, "    <creator type=\"composer\">%s</creator>\n", xmlEscape(composer))
+	}
+	if v := s.Lyricist(); v != "" {
+		fmt.Fprintf(&sb, "    <creator type=\"lyricist\">%s</creator>\n", xmlEscape(v))
+	}
+	if v := s.Copyright(); v != "" {
+		fmt.Fprintf(&sb, "    <rights>%s</rights>\n", xmlEscape(v))
+	}
+	fmt.Fprintln(&sb, "    <encoding><software>chordpro</software></encoding>")
+	fmt.Fprintln(&sb, "  </identification>")
+
+	fmt.Fprintln(&sb, "  <part-list>")
+	fmt.Fprintln(&sb, `    <score-part id="P1"><part-name>Voice</part-name></score-part>`)
+	fmt.Fprintln(&sb, "  </part-list>")
+	fmt.Fprintln(&sb, `  <part id="P1">`)
+	f.appendPart(&sb, s)
+	fmt.Fprintln(&sb, "  </part>")
+	fmt.Fprintln(&sb, "</score-partwise>")
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// FormatSongs writes the song as a MusicXML score.
+// It returns ErrSingleSongFormat if there isn't exactly one song,
+// since a MusicXML score contains a single song.
+func (f MusicXMLFormatter) FormatSongs(w io.Writer, ss Songs) error {
+	if len(ss) != 1 {
+		return ErrSingleSongFormat
+	}
+	return f.FormatSong(w, ss[0])
+}
+
+// Extension returns the ".musicxml" extension.
+func (MusicXMLFormatter) Extension() string { return ".musicxml" }
+
+// MimeType returns the "application/vnd.recordare.musicxml+xml" MIME type.
+func (MusicXMLFormatter) MimeType() string { return "application/vnd.recordare.musicxml+xml" }
@@ -0,0 +1,122 @@
+package chordpro
+
+import (
+	"encoding/xml"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_musicXMLKind(t *testing.T) {
+	tests := []struct {
+		chord string
+		want  string
+	}{
+		{"C", "major"},
+		{"Am", "minor"},
+		{"G7", "dominant"},
+		{"Fmaj7", "major-seventh"},
+		{"Em7", "minor-seventh"},
+		{"Bm7b5", "half-diminished"},
+		{"Dsus4", "suspended-fourth"},
+		{"Cadd9", "other"},
+	}
+	for _, tt := range tests {
+		c, err := ParseChord(tt.chord)
+		if err != nil {
+			t.Fatalf("%q: unexpected error %q", tt.chord, err.Error())
+		}
+		if got := musicXMLKind(c.Suffix); got != tt.want {
+			t.Errorf("%q: expected %q, got %q", tt.chord, tt.want, got)
+		}
+	}
+}
+
+func Test_musicXMLKey(t *testing.T) {
+	tests := []struct {
+		key    string
+		fifths int
+		mode   string
+		ok     bool
+	}{
+		{"C", 0, "major", true},
+		{"Bb", -2, "major", true},
+		{"Em", 1, "minor", true},
+		{"Cm", -3, "minor", true},
+		{"Ab7", 0, "", false},
+		{"", 0, "", false},
+	}
+	for _, tt := range tests {
+		fifths, mode, ok := musicXMLKey(tt.key)
+		if fifths != tt.fifths || mode != tt.mode || ok != tt.ok {
+			t.Errorf("%q: expected %v %v %v, got %v %v %v", tt.key, tt.fifths, tt.mode, tt.ok, fifths, mode, ok)
+		}
+	}
+}
+
+func Test_musicXMLNotes(t *testing.T) {
+	lin := ParseText("[C]Hal[D]le[G]lujah my [Am]Lord [F]")[0].Paragraphs[0].Lines[0]
+	want := []musicXMLNote{
+		{"[C]", "Hal", "begin"},
+		{"[D]", "le", "middle"},
+		{"[G]", "lujah", "end"},
+		{"", "my", "single"},
+		{"[Am]", "Lord", "single"},
+		{"[F]", "", ""},
+	}
+	if got := musicXMLNotes(lin); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestMusicXMLFormatter_FormatSong(t *testing.T) {
+	src := `{title: Song & Dance}
+{time: 3/4}
+{tempo: 96}
+{key: Em}
+
+{start_of_chorus}
+[Em]one two [C/B]three four
+{end_of_chorus}
+{chorus}
+`
+	var sb strings.Builder
+	if err := (MusicXMLFormatter{}).FormatSong(&sb, ParseText(src)[0]); err != nil {
+		t.Fatalf("unexpected error %q", err.Error())
+	}
+	got := sb.String()
+
+	// well formed
+	d := xml.NewDecoder(strings.NewReader(got))
+	for {
+		_, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("unexpected error %q", err.Error())
+		}
+	}
+
+	for _, want := range []string{
+		`<work-title>Song &amp; Dance</work-title>`,
+		`<key><fifths>1</fifths><mode>minor</mode></key>`,
+		`<time><beats>3</beats><beat-type>4</beat-type></time>`,
+		`<sound tempo="96"/>`,
+		`<words>Chorus</words>`,
+		`<root><root-step>C</root-step></root>`,
+		`<bass><bass-step>B</bass-step></bass>`,
+		`<kind text="m">minor</kind>`,
+		`<text>four</text>`,
+		`<measure number="2">`,
+		`<rest measure="yes"/>`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected %q in output, got %q", want, got)
+		}
+	}
+	if strings.Contains(got, `<measure number="4">`) {
+		t.Errorf("expected 3 measures, got %q", got)
+	}
+}
@@ -0,0 +1,278 @@
+package chordpro
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strings"
+)
+
+func init() {
+	RegisterFormatter("onsong", func() Formatter { return OnSongFormatter{} })
+	RegisterImporter("onsong", func() Importer { return OnSongImporter{} })
+}
+
+// OnSongFormatter is the Formatter of the "onsong" format,
+// the text format of the OnSong application.
+// The title and the artist are written on the first two lines,
+// followed by the metadata like "Key: G".
+// Each section begins with its name, like "Verse 1:" or "Chorus:",
+// and the chords are inline in square brackets.
+// A chorus reference is written as an empty "Chorus:" section.
+type OnSongFormatter struct{}
+
+// OnSongImporter is the Importer of the "onsong" format.
+type OnSongImporter struct{}
+
+var (
+	reOnSongMeta    = regexp.MustCompile(`^(?i)(title|subtitle|artist|author|composer|lyricist|album|year|key|capo|tempo|time|duration|copyright)\s*:\s*(.*\S)\s*$`)
+	reOnSongSection = regexp.MustCompile(`^([A-Za-z][A-Za-z0-9 \-]{0,30}):\s*$`)
+	reOnSongVerse   = regexp.MustCompile(`^(?i)verse( \d+)?$`)
+	reOnSongComment = regexp.MustCompile(`^\{(?:c|ci|comment|comment_italic)\s*:\s*(.*?)\s*\}$`)
+)
+
+func (f OnSongFormatter) appendSong(sb *strings.Builder, s *Song) {
+	if v := s.Title(); v != "" {
+		fmt.Fprintln(sb, v)
+	}
+	if v := s.Artist(); v != "" {
+		fmt.Fprintln(sb, v)
+	}
+	for _, mi := range []struct {
+		name  string
+		value string
+	}{
+		{"Key", s.Key()},
+		{"Capo", s.Capo()},
+		{"Tempo", s.Tempo()},
+		{"Time", s.Time()},
+		{"Duration", s.Duration()},
+		{"Composer", s.Composer()},
+		{"Lyricist", s.Lyricist()},
+		{"Album", s.Album()},
+		{"Year", s.Year()},
+		{"Copyright", s.Copyright()},
+	} {
+		if mi.value != "" {
+			fmt.Fprintf(sb, "%s: %s\n", mi.name, mi.value)
+		}
+	}
+
+	verses := 0
+	for _, p := range s.Paragraphs {
+		if p.ParagraphType == Custom {
+			if out := renderNode("onsong", p); out != "" {
+				fmt.Fprintln(sb)
+				sb.WriteString(out)
+			}
+			continue
+		}
+		if p.ParagraphType != ChorusRef && isBlank(p) {
+			continue
+		}
+		fmt.Fprintln(sb)
+
+		label := p.Label
+		switch p.ParagraphType {
+		case Comment:
+			for _, lin := range p.Lines {
+				var txt strings.Builder
+				for _, pair := range lin.Pairs {
+					txt.WriteString(pair.Lyric)
+				}
+				fmt.Fprintf(sb, "{comment: %s}\n", strings.TrimSpace(txt.String()))
+			}
+			continue
+		case ChorusRef:
+			fmt.Fprintln(sb, "Chorus:")
+			continue
+		case Verse:
+			if label == "" {
+				verses++
+				label = fmt.Sprintf("Verse %d", verses)
+			}
+		default:
+			if label == "" {
+				label = p.ParagraphType.String()
+			}
+		}
+
+		fmt.Fprintf(sb, "%s:\n", label)
+		for _, lin := range trimBlankLines(p.Lines) {
+			for _, pair := range lin.Pairs {
+				sb.WriteString(pair.Chord)
+				sb.WriteString(pair.Lyric)
+			}
+			fmt.Fprintln(sb)
+		}
+	}
+}
+
+// FormatSong writes the song as an OnSong text.
+func (f OnSongFormatter) FormatSong(w io.Writer, s *Song) error {
+	var sb strings.Builder
+	f.appendSong(&sb, s)
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// FormatSongs writes the song as an OnSong text.
+// It returns ErrSingleSongFormat if there isn't exactly one song,
+// since an OnSong file contains a single song.
+func (f OnSongFormatter) FormatSongs(w io.Writer, ss Songs) error {
+	if len(ss) != 1 {
+		return ErrSingleSongFormat
+	}
+	return f.FormatSong(w, ss[0])
+}
+
+// Extension returns the ".onsong" extension.
+func (OnSongFormatter) Extension() string { return ".onsong" }
+
+// MimeType returns the "text/plain" MIME type.
+func (OnSongFormatter) MimeType() string { return "text/plain" }
+
+// inlineChordLine function returns the line with the chords
+// in square brackets inline with the lyric.
+func inlineChordLine(s string) *Line {
+	lin := new(Line)
+	for s != "" {
+		pair := new(ChordLyricPair)
+		if s[0] == chordBegin {
+			if k := strings.IndexByte(s, chordEnd); k > 0 {
+				pair.Chord = s[:k+1]
+				s = s[k+1:]
+			}
+		}
+		k := strings.IndexByte(s, chordBegin)
+		if k < 0 || (k == 0 && pair.Chord == "") {
+			k = len(s)
+		}
+		pair.Lyric = s[:k]
+		s = s[k:]
+		lin.Pairs = append(lin.Pairs, pair)
+	}
+	return lin
+}
+
+// ImportOnSong parses the song of an OnSong text.
+func ImportOnSong(src string) *Song {
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	src = strings.ReplaceAll(src, "\r", "\n")
+	lines := strings.Split(src, "\n")
+
+	cur := cursor{}
+	song := cur.newSong()
+
+	// closePar closes the current paragraph:
+	// an empty chorus is a chorus reference.
+	closePar := func() {
+		if p := cur.par; p != nil && len(p.Lines) == 0 && p.ParagraphType == Chorus {
+			p.ParagraphType = ChorusRef
+			if strings.EqualFold(p.Label, "chorus") {
+				p.Label = ""
+			}
+		}
+		cur.closeParagraph()
+	}
+
+	// header lines: title, artist and metadata
+	j, n := 0, 0
+	for ; j < len(lines); j++ {
+		line := strings.TrimSpace(lines[j])
+		if line == "" {
+			if n == 0 {
+				continue
+			}
+			break
+		}
+		if m := reOnSongMeta.FindStringSubmatch(line); m != nil {
+			name := parseMetaFieldName(m[1])
+			if strings.EqualFold(m[1], "author") {
+				name = metaArtist
+			}
+			song.meta.append(name, m[2])
+		} else if reOnSongSection.MatchString(line) || strings.ContainsRune(line, chordBegin) || textChords(line) != nil {
+			break
+		} else {
+			switch n {
+			case 0:
+				song.meta.append(metaTitle, line)
+			case 1:
+				song.meta.append(metaArtist, line)
+			default:
+				song.meta.append(metaSubtitle, line)
+			}
+		}
+		n++
+	}
+
+	for ; j < len(lines); j++ {
+		line := strings.TrimRight(lines[j], " \t")
+
+		if strings.TrimSpace(line) == "" {
+			if cur.par != nil && len(cur.par.Lines) > 0 {
+				closePar()
+			}
+			continue
+		}
+
+		if m := reOnSongSection.FindStringSubmatch(line); m != nil {
+			closePar()
+			pt, label := textSection(strings.TrimSpace(m[1]))
+			if pt == Verse && reOnSongVerse.MatchString(label) {
+				label = ""
+			}
+			p := cur.newParagraph()
+			p.ParagraphType = pt
+			p.Label = label
+			continue
+		}
+
+		if m := reOnSongComment.FindStringSubmatch(strings.TrimSpace(line)); m != nil {
+			closePar()
+			cur.newParagraph().ParagraphType = Comment
+			cur.newPair().Lyric = m[1]
+			closePar()
+			continue
+		}
+
+		if strings.ContainsRune(line, chordBegin) {
+			cur.getParagraph()
+			cur.par.Lines = append(cur.par.Lines, inlineChordLine(line))
+			cur.closeLine()
+			continue
+		}
+
+		if chords := textChords(line); chords != nil {
+			lyric := ""
+			if j+1 < len(lines) {
+				next := strings.TrimRight(lines[j+1], " \t")
+				if strings.TrimSpace(next) != "" && textChords(next) == nil && !reOnSongSection.MatchString(next) {
+					lyric = next
+					j++
+				}
+			}
+			cur.getParagraph()
+			cur.par.Lines = append(cur.par.Lines, mergeChordsLyrics(chords, lyric))
+			cur.closeLine()
+			continue
+		}
+
+		cur.newPair().Lyric = line
+		cur.closeLine()
+	}
+	closePar()
+
+	return song
+}
+
+// Import reads the song from the OnSong text.
+func (OnSongImporter) Import(r io.Reader) (Songs, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return Songs{ImportOnSong(string(data))}, nil
+}
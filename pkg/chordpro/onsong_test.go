@@ -0,0 +1,119 @@
+package chordpro
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_inlineChordLine(t *testing.T) {
+	tests := []struct {
+		src  string
+		want []*ChordLyricPair
+	}{
+		{"[G]Amazing [C]grace", []*ChordLyricPair{{Chord: "[G]", Lyric: "Amazing "}, {Chord: "[C]", Lyric: "grace"}}},
+		{"Amazing [C]grace", []*ChordLyricPair{{Chord: "", Lyric: "Amazing "}, {Chord: "[C]", Lyric: "grace"}}},
+		{"[G][C]", []*ChordLyricPair{{Chord: "[G]", Lyric: ""}, {Chord: "[C]", Lyric: ""}}},
+		{"a [b", []*ChordLyricPair{{Chord: "", Lyric: "a "}, {Chord: "", Lyric: "[b"}}},
+	}
+	for _, tt := range tests {
+		got := inlineChordLine(tt.src).Pairs
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: expected %v, got %v", tt.src, tt.want, got)
+		}
+	}
+}
+
+func TestOnSongFormatter_FormatSong(t *testing.T) {
+	src := `{title: Amazing Grace}
+{artist: John Newton}
+{key: G}
+{capo: 2}
+
+[G]Amazing [C]grace
+
+{start_of_chorus}
+[C]Halle[G]lujah
+{end_of_chorus}
+
+{comment: Slowly}
+
+{start_of_bridge: Outro}
+[D]End
+{end_of_bridge}
+
+{chorus}
+`
+	want := `Amazing Grace
+John Newton
+Key: G
+Capo: 2
+
+Verse 1:
+[G]Amazing [C]grace
+
+Chorus:
+[C]Halle[G]lujah
+
+{comment: Slowly}
+
+Outro:
+[D]End
+
+Chorus:
+`
+	ss := ParseText(src)
+	var sb strings.Builder
+	if err := (OnSongFormatter{}).FormatSong(&sb, ss[0]); err != nil {
+		t.Fatalf("unexpected error %q", err.Error())
+	}
+	if got := sb.String(); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestImportOnSong(t *testing.T) {
+	src := `Amazing Grace
+John Newton
+Key: G
+Copyright: Public domain
+
+Verse 1:
+[G]Amazing [C]grace
+
+Chorus:
+C     G
+Halle lujah
+
+Pre-Chorus:
+plain lyric
+
+Chorus:
+
+{c: Slowly}
+`
+	want := `{title: Amazing Grace}
+{artist: John Newton}
+{key: G}
+{copyright: Public domain}
+
+[G]Amazing [C]grace
+
+{start_of_chorus}
+[C]Halle [G]lujah
+{end_of_chorus}
+
+{start_of_verse: Pre-Chorus}
+plain lyric
+{end_of_verse}
+
+{chorus}
+
+{comment: Slowly}
+`
+	var sb strings.Builder
+	(ChordProFormatter{}).FormatSong(&sb, ImportOnSong(src))
+	if got := sb.String(); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,499 @@
+package chordpro
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// OpenLyricsNamespace is the XML namespace of the OpenLyrics documents.
+const OpenLyricsNamespace = "http://openlyrics.info/namespace/2009/song"
+
+func init() {
+	RegisterFormatter("openlyrics", func() Formatter { return OpenLyricsFormatter{} })
+	RegisterImporter("openlyrics", func() Importer { return OpenLyricsImporter{} })
+}
+
+// OpenLyricsFormatter is the Formatter of the "openlyrics" format,
+// the OpenLyrics 0.9 XML song format used by OpenLP and other
+// worship software.
+// The metadata are written in the <properties> element and
+// the paragraphs as <verse> elements named "v1", "c1", "b1" and so on,
+// with inline <chord name="..."/> elements.
+type OpenLyricsFormatter struct{}
+
+// OpenLyricsImporter is the Importer of the "openlyrics" format.
+type OpenLyricsImporter struct{}
+
+// xmlEscaper escapes the text and the attribute values of the XML formats.
+// Unlike xml.EscapeText, the newlines are kept.
+var xmlEscaper = strings.NewReplacer(
+	"&", "&amp;",
+	"<", "&lt;",
+	">", "&gt;",
+	`"`, "&quot;",
+)
+
+func xmlEscape(s string) string {
+	return xmlEscaper.Replace(s)
+}
+
+// versePrefix function returns the prefix of the verse names
+// of the paragraph type.
+func versePrefix(pt ParagraphType) string {
+	switch pt {
+	case Chorus, ChorusRef:
+		return "c"
+	case Bridge:
+		return "b"
+	case Verse:
+		return "v"
+	}
+	return "o"
+}
+
+// verseNames function returns the names of the verses of the paragraphs,
+// like "v1", "c1" or "b1", and the order in which they are sung.
+// The chorus references are sung as the last chorus.
+// The name of the blank paragraphs and of the chorus references is empty.
+func verseNames(ps []*Paragraph) (names, order []string) {
+	names = make([]string, len(ps))
+	counters := map[string]int{}
+	lastChorus := ""
+
+	for j, p := range ps {
+		if p.ParagraphType == ChorusRef {
+			if lastChorus == "" {
+				lastChorus = "c1"
+			}
+			order = append(order, lastChorus)
+			continue
+		}
+		if isBlank(p) {
+			continue
+		}
+		prefix := versePrefix(p.ParagraphType)
+		counters[prefix]++
+		names[j] = fmt.Sprintf("%s%d", prefix, counters[prefix])
+		order = append(order, names[j])
+		if p.ParagraphType == Chorus {
+			lastChorus = names[j]
+		}
+	}
+	return names, order
+}
+
+func (f OpenLyricsFormatter) appendProperties(sb *strings.Builder, s *Song, order []string) {
+	fmt.Fprintln(sb, "  <properties>")
+
+	titles := s.meta.byFieldName(metaTitle)
+	if len(titles) == 0 {
+		titles = []string{""}
+	}
+	fmt.Fprintln(sb, "    <titles>")
+	for _, t := range titles {
+		fmt.Fprintf(sb, "      <title>%s</title>\n", xmlEscape(t))
+	}
+	fmt.Fprintln(sb, "    </titles>")
+
+	var authors []string
+	for _, a := range s.meta.byFieldName(metaArtist) {
+		authors = append(authors, fmt.Sprintf("<author>%s</author>", xmlEscape(a)))
+	}
+	for _, a := range s.meta.byFieldName(metaComposer) {
+		authors = append(authors, fmt.Sprintf(`<author type="music">%s</author>`, xmlEscape(a)))
+	}
+	for _, a := range s.meta.byFieldName(metaLyricist) {
+		authors = append(authors, fmt.Sprintf(`<author type="words">%s</author>`, xmlEscape(a)))
+	}
+	if len(authors) > 0 {
+		fmt.Fprintln(sb, "    <authors>")
+		for _, a := range authors {
+			fmt.Fprintf(sb, "      %s\n", a)
+		}
+		fmt.Fprintln(sb, "    </authors>")
+	}
+
+	if v := s.Copyright(); v != "" {
+		fmt.Fprintf(sb, "    <copyright>%s</copyright>\n", xmlEscape(v))
+	}
+	if v := s.Year(); v != "" {
+		fmt.Fprintf(sb, "    <released>%s</released>\n", xmlEscape(v))
+	}
+	if v := s.Key(); v != "" {
+		fmt.Fprintf(sb, "    <key>%s</key>\n", xmlEscape(v))
+	}
+	if v := s.Time(); v != "" {
+		fmt.Fprintf(sb, "    <timeSignature>%s</timeSignature>\n", xmlEscape(v))
+	}
+	if v := s.Tempo(); v != "" {
+		typ := "text"
+		if _, err := strconv.Atoi(v); err == nil {
+			typ = "bpm"
+		}
+		fmt.Fprintf(sb, "    <tempo type=%q>%s</tempo>\n", typ, xmlEscape(v))
+	}
+	if len(order) > 0 {
+		fmt.Fprintf(sb, "    <verseOrder>%s</verseOrder>\n", strings.Join(order, " "))
+	}
+	if v := s.Album(); v != "" {
+		fmt.Fprintln(sb, "    <songbooks>")
+		fmt.Fprintf(sb, "      <songbook name=\"%s\"/>\n", xmlEscape(v))
+		fmt.Fprintln(sb, "    </songbooks>")
+	}
+
+	fmt.Fprintln(sb, "  </properties>")
+}
+
+func (f OpenLyricsFormatter) appendLines(sb *strings.Builder, p *Paragraph) {
+	sb.WriteString("      <lines>")
+	lines := p.Lines
+	if p.ParagraphType != Tab {
+		lines = trimBlankLines(lines)
+	}
+	for j, lin := range lines {
+		if j > 0 {
+			sb.WriteString("<br/>")
+		}
+		for _, pair := range lin.Pairs {
+			if pair.Chord != "" && p.ParagraphType != Tab {
+				fmt.Fprintf(sb, "<chord name=\"%s\"/>", xmlEscape(trimDelim(pair.Chord)))
+			}
+			sb.WriteString(xmlEscape(pair.Lyric))
+		}
+	}
+	sb.WriteString("</lines>\n")
+}
+
+// FormatSong writes the song as an OpenLyrics document.
+func (f OpenLyricsFormatter) FormatSong(w io.Writer, s *Song) error {
+	var verses strings.Builder
+	names, order := verseNames(s.Paragraphs)
+
+	for j, p := range s.Paragraphs {
+		if p.ParagraphType == Custom {
+			verses.WriteString(renderNode("openlyrics", p))
+			continue
+		}
+		name := names[j]
+		if name == "" {
+			continue
+		}
+
+		fmt.Fprintf(&verses, "    <verse name=\"%s\">\n", name)
+		if p.ParagraphType == Comment {
+			verses.WriteString("      <lines>")
+			for j, lin := range p.Lines {
+				if j > 0 {
+					verses.WriteString("<br/>")
+				}
+				var txt strings.Builder
+				for _, pair := range lin.Pairs {
+					txt.WriteString(pair.Lyric)
+				}
+				fmt.Fprintf(&verses, "<comment>%s</comment>", xmlEscape(strings.TrimSpace(txt.String())))
+			}
+			verses.WriteString("</lines>\n")
+		} else {
+			f.appendLines(&verses, p)
+		}
+		fmt.Fprintln(&verses, "    </verse>")
+	}
+
+	var sb strings.Builder
+	fmt.Fprintln(&sb, `<?xml version="1.0" encoding="UTF-8"?>`)
+	fmt.Fprintf(&sb, "<song xmlns=\"%s\" version=\"0.9\" createdIn=\"chordpro\" modifiedIn=\"chordpro\">\n", OpenLyricsNamespace)
+	f.appendProperties(&sb, s, order)
+	fmt.Fprintln(&sb, "  <lyrics>")
+	sb.WriteString(verses.String())
+	fmt.Fprintln(&sb, "  </lyrics>")
+	fmt.Fprintln(&sb, "</song>")
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// FormatSongs writes the song as an OpenLyrics document.
+// It returns ErrSingleSongFormat if there isn't exactly one song,
+// since an OpenLyrics document contains a single song.
+func (f OpenLyricsFormatter) FormatSongs(w io.Writer, ss Songs) error {
+	if len(ss) != 1 {
+		return ErrSingleSongFormat
+	}
+	return f.FormatSong(w, ss[0])
+}
+
+// Extension returns the ".xml" extension.
+func (OpenLyricsFormatter) Extension() string { return ".xml" }
+
+// MimeType returns the "application/xml" MIME type.
+func (OpenLyricsFormatter) MimeType() string { return "application/xml" }
+
+// reXMLNewline matches the indentation of the XML text.
+var reXMLNewline = regexp.MustCompile(`[ \t]*[\r\n][ \t\r\n]*`)
+
+// verseParagraph function returns the paragraph of the verse name.
+func verseParagraph(name string) *Paragraph {
+	p := new(Paragraph)
+	var prefix string
+	if name != "" {
+		prefix = strings.ToLower(name[:1])
+	}
+	switch prefix {
+	case "c":
+		p.ParagraphType = Chorus
+	case "b":
+		p.ParagraphType = Bridge
+	case "v":
+		p.ParagraphType = Verse
+	case "p":
+		p.Label = "Pre-Chorus"
+	case "i":
+		p.Label = "Intro"
+	case "e":
+		p.Label = "Ending"
+	default:
+		p.Label = name
+	}
+	return p
+}
+
+// namedVerse is a named verse of the songs formats, like
+// OpenLyrics and OpenSong, that give the order of the verses by name.
+// The comments of the verse precede its paragraph.
+type namedVerse struct {
+	name     string
+	par      *Paragraph
+	comments []string
+}
+
+// arrangeVerses function returns the paragraphs of the verses
+// in the given order of names, or in document order if no order is given.
+// A chorus sung again becomes a chorus reference.
+func arrangeVerses(verses []*namedVerse, order []string) []*Paragraph {
+	var ps []*Paragraph
+
+	byName := map[string]*namedVerse{}
+	for _, v := range verses {
+		byName[v.name] = v
+	}
+	if len(order) == 0 {
+		for _, v := range verses {
+			order = append(order, v.name)
+		}
+	}
+
+	used := map[string]bool{}
+	for _, name := range order {
+		v, ok := byName[name]
+		if !ok {
+			continue
+		}
+		if used[name] && v.par.ParagraphType == Chorus {
+			ps = append(ps, &Paragraph{ParagraphType: ChorusRef})
+			continue
+		}
+		if !used[name] {
+			for _, c := range v.comments {
+				ps = append(ps, &Paragraph{
+					ParagraphType: Comment,
+					Lines:         []*Line{{Pairs: []*ChordLyricPair{{Lyric: c}}}},
+				})
+			}
+		}
+		used[name] = true
+		if len(v.par.Lines) > 0 {
+			ps = append(ps, v.par)
+		}
+	}
+	return ps
+}
+
+// olReadLines reads the content of a <lines> element into the paragraph.
+func olReadLines(d *xml.Decoder, v *namedVerse) error {
+	p := v.par
+	lin := new(Line)
+	p.Lines = append(p.Lines, lin)
+	var pair *ChordLyricPair
+	depth := 1
+
+	for depth > 0 {
+		tok, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "br":
+				lin = new(Line)
+				p.Lines = append(p.Lines, lin)
+				pair = nil
+			case "chord":
+				name := ""
+				for _, a := range t.Attr {
+					switch a.Name.Local {
+					case "name":
+						name = a.Value
+					case "root":
+						if name == "" {
+							name = a.Value
+						}
+					}
+				}
+				if name != "" {
+					pair = &ChordLyricPair{Chord: string(chordBegin) + name + string(chordEnd)}
+					lin.Pairs = append(lin.Pairs, pair)
+				}
+			case "comment":
+				var txt string
+				if err := d.DecodeElement(&txt, &t); err != nil {
+					return err
+				}
+				v.comments = append(v.comments, strings.TrimSpace(txt))
+				continue
+			}
+			depth++
+		case xml.EndElement:
+			depth--
+		case xml.CharData:
+			txt := reXMLNewline.ReplaceAllString(string(t), "")
+			if txt == "" {
+				continue
+			}
+			if pair == nil {
+				pair = new(ChordLyricPair)
+				lin.Pairs = append(lin.Pairs, pair)
+			}
+			pair.Lyric += txt
+		}
+	}
+
+	// remove the empty lines of the comments
+	if len(lin.Pairs) == 0 {
+		p.Lines = p.Lines[:len(p.Lines)-1]
+	}
+	return nil
+}
+
+// ParseOpenLyrics parses a song from an OpenLyrics document.
+func ParseOpenLyrics(r io.Reader) (*Song, error) {
+	song := &Song{meta: metaItems{}}
+	var verses []*namedVerse
+	var order []string
+	var path []string
+	var cur *namedVerse
+
+	d := xml.NewDecoder(r)
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			name := t.Name.Local
+			parent := ""
+			if len(path) > 0 {
+				parent = path[len(path)-1]
+			}
+			attr := func(key string) string {
+				for _, a := range t.Attr {
+					if a.Name.Local == key {
+						return a.Value
+					}
+				}
+				return ""
+			}
+			text := func() (string, error) {
+				var s string
+				err := d.DecodeElement(&s, &t)
+				return strings.TrimSpace(s), err
+			}
+
+			var field metaFieldName
+			switch {
+			case name == "title" && parent == "titles":
+				field = metaTitle
+			case name == "author" && parent == "authors":
+				switch attr("type") {
+				case "words":
+					field = metaLyricist
+				case "music":
+					field = metaComposer
+				default:
+					field = metaArtist
+				}
+			case name == "copyright" && parent == "properties":
+				field = metaCopyright
+			case name == "released" && parent == "properties":
+				field = metaYear
+			case name == "key" && parent == "properties":
+				field = metaKey
+			case name == "timeSignature" && parent == "properties":
+				field = metaTime
+			case name == "tempo" && parent == "properties":
+				field = metaTempo
+			case name == "verseOrder" && parent == "properties":
+				s, err := text()
+				if err != nil {
+					return nil, err
+				}
+				order = strings.Fields(s)
+				continue
+			case name == "songbook" && parent == "songbooks":
+				if v := attr("name"); v != "" {
+					song.meta.append(metaAlbum, v)
+				}
+			case name == "verse" && parent == "lyrics":
+				cur = &namedVerse{name: attr("name"), par: verseParagraph(attr("name"))}
+				verses = append(verses, cur)
+			case name == "lines" && cur != nil:
+				if err := olReadLines(d, cur); err != nil {
+					return nil, err
+				}
+				continue
+			}
+
+			if field != metaNone {
+				s, err := text()
+				if err != nil {
+					return nil, err
+				}
+				// the empty elements, like the title of a song without title
+				if s != "" {
+					song.meta.append(field, s)
+				}
+				continue
+			}
+			path = append(path, name)
+
+		case xml.EndElement:
+			if len(path) > 0 {
+				path = path[:len(path)-1]
+			}
+			if t.Name.Local == "verse" {
+				cur = nil
+			}
+		}
+	}
+
+	song.Paragraphs = arrangeVerses(verses, order)
+	return song, nil
+}
+
+// Import reads the song from the OpenLyrics document.
+func (OpenLyricsImporter) Import(r io.Reader) (Songs, error) {
+	s, err := ParseOpenLyrics(r)
+	if err != nil {
+		return nil, err
+	}
+	return Songs{s}, nil
+}
@@ -0,0 +1,140 @@
+package chordpro
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOpenLyricsFormatter_FormatSong(t *testing.T) {
+	src := `{title: Amazing Grace}
+{composer: Traditional}
+{lyricist: John Newton}
+{key: G}
+{tempo: 90}
+
+[G]Amazing [C]grace & [G]love
+
+{start_of_chorus}
+[D]Sing <it>
+{end_of_chorus}
+
+{chorus}
+`
+	ss := ParseText(src)
+
+	var sb strings.Builder
+	if err := (OpenLyricsFormatter{}).FormatSong(&sb, ss[0]); err != nil {
+		t.Fatalf("unexpected error %q", err.Error())
+	}
+	got := sb.String()
+	for _, want := range []string{
+		`<title>Amazing Grace</title>`,
+		`<author type="music">Traditional</author>`,
+		`<author type="words">John Newton</author>`,
+		`<key>G</key>`,
+		`<tempo type="bpm">90</tempo>`,
+		`<verseOrder>v1 c1 c1</verseOrder>`,
+		`<verse name="v1">`,
+		`<lines><chord name="G"/>Amazing <chord name="C"/>grace &amp; <chord name="G"/>love</lines>`,
+		`<lines><chord name="D"/>Sing &lt;it&gt;</lines>`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected %q in output, got %q", want, got)
+		}
+	}
+
+	// round trip
+	s, err := ParseOpenLyrics(strings.NewReader(got))
+	if err != nil {
+		t.Fatalf("unexpected error %q", err.Error())
+	}
+	var want, back strings.Builder
+	(ChordProFormatter{}).FormatSong(&want, ss[0])
+	(ChordProFormatter{}).FormatSong(&back, s)
+	if want.String() != back.String() {
+		t.Errorf("expected %q, got %q", want.String(), back.String())
+	}
+
+	// round trip of a song without title, written with an empty title
+	song := ParseText("[C]do")[0]
+	sb.Reset()
+	if err := (OpenLyricsFormatter{}).FormatSong(&sb, song); err != nil {
+		t.Fatalf("unexpected error %q", err.Error())
+	}
+	if !strings.Contains(sb.String(), "<title></title>") {
+		t.Errorf("expected %q in output, got %q", "<title></title>", sb.String())
+	}
+	s, err = ParseOpenLyrics(strings.NewReader(sb.String()))
+	if err != nil {
+		t.Fatalf("unexpected error %q", err.Error())
+	}
+	want.Reset()
+	back.Reset()
+	(ChordProFormatter{}).FormatSong(&want, song)
+	(ChordProFormatter{}).FormatSong(&back, s)
+	if want.String() != back.String() {
+		t.Errorf("expected %q, got %q", want.String(), back.String())
+	}
+}
+
+func TestOpenLyricsFormatter_FormatSongs(t *testing.T) {
+	ss := ParseText("{title: a}\n{new_song}\n{title: b}\n")
+	var sb strings.Builder
+	if err := (OpenLyricsFormatter{}).FormatSongs(&sb, ss); err != ErrSingleSongFormat {
+		t.Errorf("expected %v, got %v", ErrSingleSongFormat, err)
+	}
+}
+
+func TestParseOpenLyrics(t *testing.T) {
+	src := `<?xml version="1.0" encoding="UTF-8"?>
+<song xmlns="http://openlyrics.info/namespace/2009/song" version="0.8">
+  <properties>
+    <titles>
+      <title>Amazing Grace</title>
+    </titles>
+    <authors>
+      <author>John Newton</author>
+    </authors>
+    <released>1779</released>
+    <songbooks>
+      <songbook name="Hymns" entry="48"/>
+    </songbooks>
+  </properties>
+  <lyrics>
+    <verse name="v1">
+      <lines>
+        <comment>Slowly</comment>
+        <chord name="G"/>Amazing grace how <chord name="C"/>sweet<br/>
+        that saved a wretch
+      </lines>
+    </verse>
+    <verse name="p1">
+      <lines>I once was lost</lines>
+    </verse>
+  </lyrics>
+</song>
+`
+	want := `{title: Amazing Grace}
+{artist: John Newton}
+{year: 1779}
+{album: Hymns}
+
+{comment: Slowly}
+
+[G]Amazing grace how [C]sweet
+that saved a wretch
+
+{start_of_verse: Pre-Chorus}
+I once was lost
+{end_of_verse}
+`
+	ss, err := (OpenLyricsImporter{}).Import(strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("unexpected error %q", err.Error())
+	}
+	var sb strings.Builder
+	(ChordProFormatter{}).FormatSongs(&sb, ss)
+	if got := sb.String(); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,280 @@
+package chordpro
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+func init() {
+	RegisterFormatter("opensong", func() Formatter { return OpenSongFormatter{} })
+	RegisterImporter("opensong", func() Importer { return OpenSongImporter{} })
+}
+
+// OpenSongFormatter is the Formatter of the "opensong" format,
+// the XML song format of the OpenSong application.
+// The lyrics are written as lines prefixed by '.' for the chords,
+// ' ' for the lyrics and ';' for the comments, with the sections
+// introduced by markers like "[V1]" or "[C1]".
+// The chords are aligned to the lyrics,
+// with '_' extending the words shorter than their chord.
+type OpenSongFormatter struct{}
+
+// OpenSongImporter is the Importer of the "opensong" format.
+type OpenSongImporter struct{}
+
+// openSongLine function returns the chord row and the lyric row of the line.
+func openSongLine(lin *Line) (string, string) {
+	var chords, lyrics strings.Builder
+	for j, pair := range lin.Pairs {
+		chord := trimDelim(pair.Chord)
+		cw := runeLen(chord)
+		if cw > 0 && j < len(lin.Pairs)-1 {
+			// keep a space between consecutive chords
+			cw++
+		}
+		lw := runeLen(pair.Lyric)
+		w := lw
+		if cw > w {
+			w = cw
+		}
+
+		padding := " "
+		if pair.Lyric != "" && !strings.HasSuffix(pair.Lyric, " ") && j < len(lin.Pairs)-1 {
+			if next := lin.Pairs[j+1].Lyric; next != "" && next[0] != ' ' {
+				padding = "_"
+			}
+		}
+
+		chords.WriteString(chord)
+		chords.WriteString(strings.Repeat(" ", w-runeLen(chord)))
+		lyrics.WriteString(pair.Lyric)
+		lyrics.WriteString(strings.Repeat(padding, w-lw))
+	}
+	return strings.TrimRight(chords.String(), " "), strings.TrimRight(lyrics.String(), " ")
+}
+
+func (f OpenSongFormatter) appendLyrics(sb *strings.Builder, s *Song) []string {
+	names, order := verseNames(s.Paragraphs)
+	repeated := false
+
+	for j, p := range s.Paragraphs {
+		if p.ParagraphType == ChorusRef {
+			repeated = true
+		}
+		if p.ParagraphType == Custom {
+			sb.WriteString(renderNode("opensong", p))
+			continue
+		}
+		name := strings.ToUpper(names[j])
+		if name == "" {
+			continue
+		}
+		if sb.Len() > 0 {
+			sb.WriteString("\n")
+		}
+		fmt.Fprintf(sb, "[%s]\n", name)
+
+		for _, lin := range trimBlankLines(p.Lines) {
+			if p.ParagraphType == Comment {
+				var txt strings.Builder
+				for _, pair := range lin.Pairs {
+					txt.WriteString(pair.Lyric)
+				}
+				fmt.Fprintf(sb, ";%s\n", strings.TrimSpace(txt.String()))
+				continue
+			}
+			if p.ParagraphType == Tab {
+				for _, pair := range lin.Pairs {
+					fmt.Fprintf(sb, " %s", pair.Lyric)
+				}
+				sb.WriteString("\n")
+				continue
+			}
+			chords, lyrics := openSongLine(lin)
+			if chords != "" {
+				fmt.Fprintf(sb, ".%s\n", chords)
+			}
+			if lyrics != "" || chords == "" {
+				fmt.Fprintf(sb, " %s\n", lyrics)
+			}
+		}
+	}
+
+	// the presentation order is needed only to repeat the chorus
+	if !repeated {
+		return nil
+	}
+	for j := range order {
+		order[j] = strings.ToUpper(order[j])
+	}
+	return order
+}
+
+// FormatSong writes the song as an OpenSong document.
+func (f OpenSongFormatter) FormatSong(w io.Writer, s *Song) error {
+	var lyrics strings.Builder
+	order := f.appendLyrics(&lyrics, s)
+
+	var sb strings.Builder
+	element := func(name, value string) {
+		if value != "" {
+			fmt.Fprintf(&sb, "  <%s>%s</%s>\n", name, xmlEscape(value), name)
+		}
+	}
+
+	fmt.Fprintln(&sb, `<?xml version="1.0" encoding="UTF-8"?>`)
+	fmt.Fprintln(&sb, "<song>")
+	fmt.Fprintf(&sb, "  <title>%s</title>\n", xmlEscape(s.Title()))
+	element("aka", s.SubTitle())
+	element("author", s.Artist())
+	element("copyright", s.Copyright())
+	element("presentation", strings.Join(order, " "))
+	element("key", s.Key())
+	element("capo", s.Capo())
+	element("tempo", s.Tempo())
+	element("time_sig", s.Time())
+	fmt.Fprintf(&sb, "  <lyrics>%s</lyrics>\n", xmlEscape(lyrics.String()))
+	fmt.Fprintln(&sb, "</song>")
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// FormatSongs writes the song as an OpenSong document.
+// It returns ErrSingleSongFormat if there isn't exactly one song,
+// since an OpenSong document contains a single song.
+func (f OpenSongFormatter) FormatSongs(w io.Writer, ss Songs) error {
+	if len(ss) != 1 {
+		return ErrSingleSongFormat
+	}
+	return f.FormatSong(w, ss[0])
+}
+
+// Extension returns the ".xml" extension.
+func (OpenSongFormatter) Extension() string { return ".xml" }
+
+// MimeType returns the "application/xml" MIME type.
+func (OpenSongFormatter) MimeType() string { return "application/xml" }
+
+// parseOpenSongLyrics function returns the verses of the lyrics
+// of an OpenSong document.
+func parseOpenSongLyrics(src string) []*namedVerse {
+	var verses []*namedVerse
+	var cur *namedVerse
+	getVerse := func() *namedVerse {
+		if cur == nil {
+			cur = &namedVerse{par: new(Paragraph)}
+			verses = append(verses, cur)
+		}
+		return cur
+	}
+
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	lines := strings.Split(src, "\n")
+	for j := 0; j < len(lines); j++ {
+		line := strings.TrimRight(lines[j], " \t\r")
+		if line == "" {
+			continue
+		}
+		switch line[0] {
+		case '[':
+			name := strings.TrimSpace(strings.Trim(line, "[]"))
+			cur = &namedVerse{name: name, par: verseParagraph(name)}
+			verses = append(verses, cur)
+
+		case ';':
+			v := getVerse()
+			v.comments = append(v.comments, strings.TrimSpace(line[1:]))
+
+		case '.':
+			lyric := ""
+			if j+1 < len(lines) && strings.HasPrefix(lines[j+1], " ") {
+				lyric = openSongLyric(lines[j+1])
+				j++
+			}
+			lin := mergeChordsLyrics(lineWords(line[1:]), lyric)
+			for _, pair := range lin.Pairs {
+				pair.Lyric = openSongMarkers.Replace(pair.Lyric)
+			}
+			p := getVerse().par
+			p.Lines = append(p.Lines, lin)
+
+		default:
+			// lyric line, or verse line prefixed by the verse number
+			p := getVerse().par
+			lyric := openSongMarkers.Replace(openSongLyric(line))
+			if reTextTab.MatchString(line[1:]) {
+				// tablature keeps its '|' markers
+				p.ParagraphType = Tab
+				p.Label = ""
+				lyric = line[1:]
+			}
+			p.Lines = append(p.Lines, &Line{Pairs: []*ChordLyricPair{{Lyric: lyric}}})
+		}
+	}
+	return verses
+}
+
+// openSongLyric function returns the lyric of a lyric line,
+// without the line prefix.
+func openSongLyric(line string) string {
+	return strings.TrimRight(line[1:], " \t\r")
+}
+
+// openSongMarkers removes the presentation markers of the lyrics:
+// the '|' and '||' line and page breaks and the '_' word extenders.
+// It must be applied after the chords are aligned to the lyric.
+var openSongMarkers = strings.NewReplacer("||", "", "|", "", "_", "")
+
+// ParseOpenSong parses a song from an OpenSong document.
+func ParseOpenSong(r io.Reader) (*Song, error) {
+	var doc struct {
+		Title        string `xml:"title"`
+		Aka          string `xml:"aka"`
+		Author       string `xml:"author"`
+		Copyright    string `xml:"copyright"`
+		Presentation string `xml:"presentation"`
+		Key          string `xml:"key"`
+		Capo         string `xml:"capo"`
+		Tempo        string `xml:"tempo"`
+		TimeSig      string `xml:"time_sig"`
+		Lyrics       string `xml:"lyrics"`
+	}
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	song := &Song{meta: metaItems{}}
+	for _, mi := range []struct {
+		name  metaFieldName
+		value string
+	}{
+		{metaTitle, doc.Title},
+		{metaSubtitle, doc.Aka},
+		{metaArtist, doc.Author},
+		{metaCopyright, doc.Copyright},
+		{metaKey, doc.Key},
+		{metaCapo, doc.Capo},
+		{metaTempo, doc.Tempo},
+		{metaTime, doc.TimeSig},
+	} {
+		if v := strings.TrimSpace(mi.value); v != "" {
+			song.meta.append(mi.name, v)
+		}
+	}
+
+	verses := parseOpenSongLyrics(doc.Lyrics)
+	song.Paragraphs = arrangeVerses(verses, strings.Fields(doc.Presentation))
+	return song, nil
+}
+
+// Import reads the song from the OpenSong document.
+func (OpenSongImporter) Import(r io.Reader) (Songs, error) {
+	s, err := ParseOpenSong(r)
+	if err != nil {
+		return nil, err
+	}
+	return Songs{s}, nil
+}
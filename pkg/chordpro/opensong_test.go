@@ -0,0 +1,125 @@
+package chordpro
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_openSongLine(t *testing.T) {
+	tests := []struct {
+		src    string
+		chords string
+		lyrics string
+	}{
+		{"[G]Amazing [C]grace", "G       C", "Amazing grace"},
+		{"[C]Hal[Dsus4]le[G]lujah", "C  Dsus4 G", "Halle____lujah"},
+		{"no chords", "", "no chords"},
+	}
+	for _, tt := range tests {
+		lin := ParseText(tt.src)[0].Paragraphs[0].Lines[0]
+		chords, lyrics := openSongLine(lin)
+		if chords != tt.chords {
+			t.Errorf("%q: expected chords %q, got %q", tt.src, tt.chords, chords)
+		}
+		if lyrics != tt.lyrics {
+			t.Errorf("%q: expected lyrics %q, got %q", tt.src, tt.lyrics, lyrics)
+		}
+	}
+}
+
+func TestOpenSongFormatter_FormatSong(t *testing.T) {
+	src := `{title: Amazing Grace}
+{artist: John Newton}
+{key: G}
+
+[G]Amazing [C]grace & [G]love
+
+{start_of_chorus}
+[C]Hal[Dsus4]le[G]lujah
+{end_of_chorus}
+
+{comment: Slowly}
+
+{chorus}
+`
+	want := `<?xml version="1.0" encoding="UTF-8"?>
+<song>
+  <title>Amazing Grace</title>
+  <author>John Newton</author>
+  <presentation>V1 C1 O1 C1</presentation>
+  <key>G</key>
+  <lyrics>[V1]
+.G       C       G
+ Amazing grace &amp; love
+
+[C1]
+.C  Dsus4 G
+ Halle____lujah
+
+[O1]
+;Slowly
+</lyrics>
+</song>
+`
+	ss := ParseText(src)
+	var sb strings.Builder
+	if err := (OpenSongFormatter{}).FormatSong(&sb, ss[0]); err != nil {
+		t.Fatalf("unexpected error %q", err.Error())
+	}
+	if got := sb.String(); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	// round trip
+	s, err := ParseOpenSong(strings.NewReader(want))
+	if err != nil {
+		t.Fatalf("unexpected error %q", err.Error())
+	}
+	var a, b strings.Builder
+	(ChordProFormatter{}).FormatSong(&a, ss[0])
+	(ChordProFormatter{}).FormatSong(&b, s)
+	if a.String() != b.String() {
+		t.Errorf("expected %q, got %q", a.String(), b.String())
+	}
+}
+
+func TestParseOpenSong(t *testing.T) {
+	src := `<?xml version="1.0" encoding="UTF-8"?>
+<song>
+  <title>Song</title>
+  <capo print="false">2</capo>
+  <time_sig>3/4</time_sig>
+  <lyrics>;intro
+[V1]
+.D      A
+ First |line|| here
+ second line
+
+[T]
+ e|---0---2---|
+</lyrics>
+</song>
+`
+	want := `{title: Song}
+{capo: 2}
+{time: 3/4}
+
+{comment: intro}
+
+[D]First [A]line here
+second line
+
+{start_of_tab}
+e|---0---2---|
+{end_of_tab}
+`
+	ss, err := (OpenSongImporter{}).Import(strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("unexpected error %q", err.Error())
+	}
+	var sb strings.Builder
+	(ChordProFormatter{}).FormatSongs(&sb, ss)
+	if got := sb.String(); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
@@ -1,9 +1,11 @@
 package chordpro
 
 import (
+	"errors"
 	"fmt"
-	"os"
+	"io"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/mmbros/chordpro/internal/lexer"
 )
@@ -16,6 +18,12 @@ type cursor struct {
 	pair  *ChordLyricPair
 
 	onlyText bool
+	pos      Position // position of the current token
+
+	// directiveName resolves the name and the selector of a directive, if not nil
+	directiveName func(name string) (string, string, bool)
+	handlers      map[string]DirectiveHandler
+	handlerErr    *ParseError // error of the last directive handler
 }
 
 func (c *cursor) newSong() *Song {
@@ -26,6 +34,7 @@ func (c *cursor) newSong() *Song {
 	}
 	c.song = new(Song)
 	c.song.meta = metaItems{}
+	c.song.Pos = c.pos
 	c.songs = append(c.songs, c.song)
 	return c.song
 }
@@ -37,6 +46,7 @@ func (c *cursor) newParagraph() *Paragraph {
 		c.closeLine()
 	}
 	c.par = new(Paragraph)
+	c.par.Pos = c.pos
 	c.song.Paragraphs = append(c.song.Paragraphs, c.par)
 
 	return c.par
@@ -47,6 +57,7 @@ func (c *cursor) newLine() *Line {
 		c.newParagraph()
 	}
 	c.line = new(Line)
+	c.line.Pos = c.pos
 	c.par.Lines = append(c.par.Lines, c.line)
 
 	return c.line
@@ -57,6 +68,7 @@ func (c *cursor) newPair() *ChordLyricPair {
 		c.newLine()
 	}
 	c.pair = new(ChordLyricPair)
+	c.pair.Pos = c.pos
 	c.line.Pairs = append(c.line.Pairs, c.pair)
 
 	return c.pair
@@ -101,10 +113,9 @@ func (c *cursor) closeLine() {
 	c.line = nil
 }
 
-func (c *cursor) parseDirective(src string) {
-
-	var name, arg string
-
+// splitDirective function returns the name and the argument of the directive,
+// given with its braces. The name of a "meta" directive is the name of its field.
+func splitDirective(src string) (name, arg string) {
 	s := trimDelim(src)
 
 	v := strings.SplitN(s, directiveNameSep, 2)
@@ -115,9 +126,6 @@ func (c *cursor) parseDirective(src string) {
 
 	if name == "meta" {
 		v = strings.SplitN(arg, directiveMetaSep, 2)
-		if len(v) == 0 {
-			return
-		}
 		name = v[0]
 		if len(v) > 1 {
 			arg = v[1]
@@ -125,39 +133,70 @@ func (c *cursor) parseDirective(src string) {
 			arg = ""
 		}
 	}
+	return name, arg
+}
 
-	fieldName := metaNone
-
+// metaFieldByName function returns the meta-data field of the directive name,
+// or metaNone if the directive is not a meta-data directive.
+func metaFieldByName(name string) metaFieldName {
 	switch name {
 	case "title", "t":
-		fieldName = metaTitle
+		return metaTitle
 	case "sorttitle":
-		fieldName = metaSortTitle
+		return metaSortTitle
 	case "subtitle", "st":
-		fieldName = metaSubtitle
+		return metaSubtitle
 	case "artist":
-		fieldName = metaArtist
+		return metaArtist
 	case "composer":
-		fieldName = metaComposer
+		return metaComposer
 	case "lyricist":
-		fieldName = metaLyricist
+		return metaLyricist
 	case "copyright":
-		fieldName = metaCopyright
+		return metaCopyright
 	case "album":
-		fieldName = metaAlbum
+		return metaAlbum
 	case "year":
-		fieldName = metaYear
+		return metaYear
 	case "key":
-		fieldName = metaKey
+		return metaKey
 	case "time":
-		fieldName = metaTime
+		return metaTime
 	case "tempo":
-		fieldName = metaTempo
+		return metaTempo
 	case "duration":
-		fieldName = metaDuration
+		return metaDuration
 	case "capo":
-		fieldName = metaCapo
+		return metaCapo
+	}
+	return metaNone
+}
 
+func (c *cursor) parseDirective(src string) {
+
+	name, arg := splitDirective(src)
+	sel := ""
+	if c.directiveName != nil {
+		var ok bool
+		if name, sel, ok = c.directiveName(name); !ok {
+			return
+		}
+	}
+
+	if h, ok := c.handlers[name]; ok {
+		c.handleDirective(h, &Directive{
+			Name:     name,
+			Selector: sel,
+			Value:    arg,
+			Attrs:    parseAttrs(arg),
+			Pos:      c.pos,
+		})
+		return
+	}
+
+	fieldName := metaFieldByName(name)
+
+	switch name {
 	case "comment", "c":
 		c.closeParagraph()
 		c.newPair().Lyric = arg
@@ -209,18 +248,310 @@ func (c *cursor) parseDirective(src string) {
 
 }
 
-func ParseText(src string) Songs {
+// Directive is a directive of the ChordPro source, as given to a DirectiveHandler.
+type Directive struct {
+	Name     string            // name of the directive, without the selector
+	Selector string            // selector of the directive, like "guitar", if any
+	Value    string            // argument of the directive
+	Attrs    map[string]string // attributes of the argument, like {x_video: id="abc" start=10}, if any
+	Pos      Position          // position of the directive
+}
+
+// DirectiveHandler handles a directive of the song.
+// It can set the custom meta-data of the song,
+// and return a node to add to the song in a new Custom paragraph, or nil.
+// The paragraph of the directive is not split by the node,
+// that is added after it, or before it if it has no lines yet.
+// An error is reported as a diagnostic of the song, or as the error of Parse in strict mode.
+type DirectiveHandler func(s *Song, d *Directive) (*CustomNode, error)
+
+// parseAttrs function returns the attributes of the directive argument,
+// given as space separated name=value or name="value" pairs,
+// or nil if the argument is not made of attributes.
+func parseAttrs(arg string) map[string]string {
+	attrs := map[string]string{}
+	s := strings.TrimSpace(arg)
+	for s != "" {
+		j := strings.IndexByte(s, '=')
+		if j <= 0 || strings.ContainsAny(s[:j], " \t\"") {
+			return nil
+		}
+		name := strings.ToLower(s[:j])
+		s = s[j+1:]
+
+		var value string
+		if strings.HasPrefix(s, `"`) {
+			k := strings.IndexByte(s[1:], '"')
+			if k < 0 {
+				return nil
+			}
+			value, s = s[1:k+1], s[k+2:]
+		} else if k := strings.IndexAny(s, " \t"); k >= 0 {
+			value, s = s[:k], s[k:]
+		} else {
+			value, s = s, ""
+		}
+		if s != "" && s[0] != ' ' && s[0] != '\t' {
+			return nil
+		}
+		attrs[name] = value
+		s = strings.TrimSpace(s)
+	}
+	if len(attrs) == 0 {
+		return nil
+	}
+	return attrs
+}
+
+// handleDirective method calls the handler of the directive,
+// adding the node it returns in a new Custom paragraph.
+func (c *cursor) handleDirective(h DirectiveHandler, d *Directive) {
+	node, err := h(c.getSong(), d)
+	if err != nil {
+		c.handlerErr = &ParseError{Pos: d.Pos, Msg: fmt.Sprintf("directive {%s}: %v", d.Name, err)}
+		return
+	}
+	if node == nil {
+		return
+	}
+
+	// the current paragraph is not closed
+	song := c.getSong()
+	p := &Paragraph{ParagraphType: Custom, Node: node, Pos: c.pos}
+	if c.par != nil && len(c.par.Lines) == 0 {
+		// the empty paragraph is the last one
+		n := len(song.Paragraphs)
+		song.Paragraphs = append(song.Paragraphs[:n-1], p, c.par)
+		return
+	}
+	song.Paragraphs = append(song.Paragraphs, p)
+}
+
+// Encodings of the ChordPro sources.
+const (
+	EncodingUTF8   = "utf-8"
+	EncodingLatin1 = "iso-8859-1"
+	EncodingAuto   = "auto" // utf-8 if valid, iso-8859-1 otherwise
+)
+
+// ErrInvalidEncoding is returned when the encoding of the parser is not supported.
+var ErrInvalidEncoding = errors.New("invalid encoding")
+
+// ParseOptions are the options of the Parser.
+// The zero value parses utf-8 sources, recovering from the errors.
+type ParseOptions struct {
+	// Strict makes the parser fail at the first error of the source.
+	// Otherwise, the parser resyncs at the next line, and the errors
+	// are collected in the Diagnostics of their songs.
+	Strict bool
+
+	// Selectors are the selectors of the conditional directives,
+	// like "guitar" for {title-guitar: ...} or {soc-guitar}.
+	// A directive with a selector is ignored if the selector is not in the set,
+	// and a directive with a negated selector, like {soc-!guitar}, if it is.
+	Selectors []string
+
+	// Encoding is the encoding of the source of ParseBytes:
+	// EncodingUTF8, the default, EncodingLatin1 or EncodingAuto.
+	Encoding string
+
+	// Warnings receives the errors recovered by the parser,
+	// one for each line, as "line:col: severity: message (code)".
+	// Nothing is written if nil.
+	Warnings io.Writer
+
+	// Directives are the custom directive names, like "refrain",
+	// with the name of the directive they stand for, like "soc".
+	// The names are matched whatever their case.
+	Directives map[string]string
+}
+
+// Parser parses ChordPro sources with its options.
+// It never writes to the standard error, nor panics on the errors of the source.
+type Parser struct {
+	opts     ParseOptions
+	handlers map[string]DirectiveHandler
+}
+
+// NewParser returns a new Parser with the options.
+func NewParser(opts ParseOptions) *Parser {
+	if opts.Directives != nil {
+		// the directive names are looked up in lower case
+		directives := make(map[string]string, len(opts.Directives))
+		for name, alias := range opts.Directives {
+			directives[strings.ToLower(name)] = strings.ToLower(alias)
+		}
+		opts.Directives = directives
+	}
+	return &Parser{opts: opts}
+}
+
+// HandleDirective registers the handler of the directive name, like "x_strum".
+// The handler of a directive of the parser replaces it.
+// If HandleDirective is called twice with the same name, the last handler wins.
+func (p *Parser) HandleDirective(name string, h DirectiveHandler) {
+	if p.handlers == nil {
+		p.handlers = map[string]DirectiveHandler{}
+	}
+	p.handlers[strings.ToLower(name)] = h
+}
+
+// splitSelector function returns the directive name without its selector,
+// and the selector, if any.
+// The name is split at its last "-" only if it is not a known directive
+// and the part before is, or is a custom "x_" directive,
+// so that the names with a "-" are kept whole.
+func splitSelector(name string, known func(string) bool) (string, string) {
+	if known(name) {
+		return name, ""
+	}
+	j := strings.LastIndex(name, "-")
+	if j <= 0 || !(known(name[:j]) || strings.HasPrefix(name[:j], "x_")) {
+		return name, ""
+	}
+	return name[:j], name[j+1:]
+}
+
+// isDirective method reports whether the name is a directive of the parser:
+// a directive with a handler, a custom directive name or a known directive.
+func (p *Parser) isDirective(name string) bool {
+	if _, ok := p.handlers[name]; ok {
+		return true
+	}
+	if _, ok := p.opts.Directives[name]; ok {
+		return true
+	}
+	return isKnownDirective(name)
+}
+
+// directiveName method returns the name of the directive to apply and its selector,
+// resolving the selector and the custom directive names,
+// and false if the directive is ignored.
+func (p *Parser) directiveName(name string) (string, string, bool) {
+	name, sel := splitSelector(name, p.isDirective)
+	if sel != "" {
+		negated := strings.HasPrefix(sel, "!")
+		sel = strings.TrimPrefix(sel, "!")
+		found := false
+		for _, s := range p.opts.Selectors {
+			if strings.EqualFold(s, sel) {
+				found = true
+				break
+			}
+		}
+		if found == negated {
+			return "", "", false
+		}
+	}
+	if alias, ok := p.opts.Directives[name]; ok {
+		name = alias
+	}
+	return name, sel, true
+}
+
+// Decode returns the source decoded with the encoding of the options,
+// as read by ParseBytes.
+// It returns ErrInvalidEncoding if the encoding is not known.
+func (p *Parser) Decode(data []byte) (string, error) {
+	enc := strings.ToLower(p.opts.Encoding)
+	if enc == EncodingAuto {
+		enc = EncodingLatin1
+		if utf8.Valid(data) {
+			enc = EncodingUTF8
+		}
+	}
+	switch enc {
+	case "", EncodingUTF8, "utf8":
+		return strings.TrimPrefix(string(data), "\uFEFF"), nil
+	case EncodingLatin1, "latin1":
+		buf := make([]rune, len(data))
+		for j, b := range data {
+			buf[j] = rune(b)
+		}
+		return string(buf), nil
+	}
+	return "", fmt.Errorf("%w: %q", ErrInvalidEncoding, p.opts.Encoding)
+}
+
+// ParseBytes parses the songs of the ChordPro source,
+// decoded with the encoding of the options.
+func (p *Parser) ParseBytes(data []byte) (Songs, error) {
+	src, err := p.Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	return p.Parse(src)
+}
+
+// lexErrorCode function returns the diagnostic code of the lexer error message.
+func lexErrorCode(msg string) string {
+	switch {
+	case strings.HasPrefix(msg, "ChordState"):
+		return CodeUnterminatedChord
+	case strings.HasPrefix(msg, "DirectiveState"):
+		return CodeUnterminatedDirective
+	}
+	return CodeSyntaxError
+}
+
+// Parse parses the songs of the ChordPro source with the options.
+func Parse(src string, opts ParseOptions) (Songs, error) {
+	return NewParser(opts).Parse(src)
+}
+
+// Parse parses the songs of the ChordPro source.
+// In strict mode, it returns a *ParseError at the first error of the source.
+// Otherwise, the errors are returned as Diagnostics of their songs,
+// and the first of them as the Err of the song.
+func (p *Parser) Parse(src string) (Songs, error) {
 
 	var newlineCounter int
-	cur := cursor{}
+	cur := cursor{directiveName: p.directiveName, handlers: p.handlers}
 
+	// the lexer runs to the end before the parser,
+	// so that its errors are queued to be given to the songs in source order
+	var errs []*ParseError
 	l := lexer.New(src, stateText)
 	l.ErrorHandler = func(l *lexer.L) {
-		cur.getSong().Err = l.Err
-		fmt.Fprintln(os.Stderr, l.Err)
+		err := &ParseError{Msg: l.Err.Error()}
+		if lerr, ok := l.Err.(*lexer.Error); ok {
+			err.Pos = Position(lerr.Pos)
+		}
+		errs = append(errs, err)
 	}
 	l.StartSync()
 
+	if p.opts.Strict && len(errs) > 0 {
+		return nil, errs[0]
+	}
+
+	// addError function gives the error to the current song.
+	addError := func(err *ParseError, code string) {
+		song := cur.getSong()
+		if song.Err == nil {
+			song.Err = err
+		}
+		d := Diagnostic{
+			Line:     err.Pos.Line,
+			Col:      err.Pos.Col,
+			Severity: SeverityError,
+			Code:     code,
+			Message:  err.Msg,
+		}
+		song.Diagnostics = append(song.Diagnostics, d)
+		if p.opts.Warnings != nil {
+			fmt.Fprintln(p.opts.Warnings, &d)
+		}
+	}
+
+	// addErrors function gives the errors before the offset to the current song.
+	addErrors := func(offset int) {
+		for len(errs) > 0 && errs[0].Pos.Offset < offset {
+			addError(errs[0], lexErrorCode(errs[0].Msg))
+			errs = errs[1:]
+		}
+	}
+
 	for {
 
 		tok, done := l.NextToken()
@@ -228,6 +559,9 @@ func ParseText(src string) Songs {
 			break
 		}
 
+		cur.pos = Position(tok.Pos)
+		addErrors(tok.Pos.Offset)
+
 		if tok.Type == tokenNewline {
 			newlineCounter++
 		} else {
@@ -263,11 +597,24 @@ func ParseText(src string) Songs {
 			}
 		case tokenDirective:
 			cur.parseDirective(tok.Value)
+			if err := cur.handlerErr; err != nil {
+				if p.opts.Strict {
+					return nil, err
+				}
+				addError(err, CodeDirectiveError)
+				cur.handlerErr = nil
+			}
 		}
-
-		//		fmt.Printf("%v: %q\n", tok.Type, tok.Value)
-
 	}
 
-	return cur.songs
+	addErrors(len(src) + 1)
+	return cur.songs, nil
+}
+
+// ParseText parses the songs of the ChordPro source,
+// resyncing at the next line after an error.
+// The errors are returned as Diagnostics of their songs.
+func ParseText(src string) Songs {
+	songs, _ := Parse(src, ParseOptions{})
+	return songs
 }
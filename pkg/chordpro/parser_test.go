@@ -1,6 +1,9 @@
 package chordpro
 
 import (
+	"errors"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -86,3 +89,327 @@ func Test_ParseSimple(t *testing.T) {
 
 	t.FailNow()
 }
+
+func Test_ParsePositions(t *testing.T) {
+	src := "{t: A}\n{soc}\n[C]do [D]re\n{eoc}\n{ns}\nmi [E"
+	ss := ParseText(src)
+	if len(ss) != 2 {
+		t.Fatalf("expected 2 songs, got %d", len(ss))
+	}
+
+	chorus := ss[0].Paragraphs[1]
+	pairs := chorus.Lines[0].Pairs
+	tests := []struct {
+		name     string
+		got      Position
+		expected string
+	}{
+		{"song", ss[0].Pos, "1:1"},
+		{"chorus", chorus.Pos, "2:1"},
+		{"line", chorus.Lines[0].Pos, "3:1"},
+		{"chord", pairs[0].Pos, "3:1"},
+		{"second chord", pairs[1].Pos, "3:7"},
+		{"new song", ss[1].Pos, "5:1"},
+	}
+	for _, tt := range tests {
+		if got := tt.got.String(); got != tt.expected {
+			t.Errorf("%s: expected %s, got %s", tt.name, tt.expected, got)
+		}
+	}
+
+	// the lexer errors say where they happened
+	err, ok := ss[1].Err.(*ParseError)
+	if !ok {
+		t.Fatalf("expected parse error, got %v", ss[1].Err)
+	}
+	if expected := "6:4"; err.Pos.String() != expected {
+		t.Errorf("expected error at %s, got %s", expected, err.Pos)
+	}
+}
+
+func Test_ParseRecovery(t *testing.T) {
+	src := "{t: A}\n[C]do [D\n[E]mi\n{ns}\n{t: B\n[F]fa {x\n[G]sol"
+
+	ss, err := Parse(src, ParseOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error %q", err.Error())
+	}
+	if len(ss) != 2 {
+		t.Fatalf("expected 2 songs, got %d", len(ss))
+	}
+
+	// the lines after the errors are not lost
+	var chords []string
+	for _, s := range ss {
+		for _, p := range s.Paragraphs {
+			for _, l := range p.Lines {
+				for _, pair := range l.Pairs {
+					chords = append(chords, pair.Chord)
+				}
+			}
+		}
+	}
+	if got, expected := strings.Join(chords, ""), "[C][E][F][G]"; got != expected {
+		t.Errorf("expected chords %s, got %s", expected, got)
+	}
+
+	tests := []struct {
+		song  int
+		diags []string
+	}{
+		{0, []string{`2:7: error: ChordState Invalid token: expected ']', got "\n" (unterminated-chord)`}},
+		{1, []string{
+			`5:1: error: DirectiveState Invalid token: expected '}', got "\n" (unterminated-directive)`,
+			`6:7: error: DirectiveState Invalid token: expected '}', got "\n" (unterminated-directive)`,
+		}},
+	}
+	for _, tt := range tests {
+		s := ss[tt.song]
+		if len(s.Diagnostics) != len(tt.diags) {
+			t.Fatalf("song %d: expected %d diagnostics, got %v", tt.song, len(tt.diags), s.Diagnostics)
+		}
+		for j, d := range s.Diagnostics {
+			if got := d.String(); got != tt.diags[j] {
+				t.Errorf("song %d: expected %q, got %q", tt.song, tt.diags[j], got)
+			}
+		}
+		if s.Err == nil {
+			t.Errorf("song %d: expected error, got nil", tt.song)
+		}
+	}
+
+	// strict mode
+	if _, err := Parse(src, ParseOptions{Strict: true}); err == nil {
+		t.Errorf("expected error, got nil")
+	} else if expected := "2:7: " + ss[0].Diagnostics[0].Message; err.Error() != expected {
+		t.Errorf("expected %q, got %q", expected, err.Error())
+	}
+	if _, err := Parse("{t: A}\n[C]do", ParseOptions{Strict: true}); err != nil {
+		t.Errorf("unexpected error %q", err.Error())
+	}
+
+	// the directive unterminated at the end of the source
+	_, err = Parse("{t: A}\n{c: x", ParseOptions{Strict: true})
+	if expected := `2:1: DirectiveState Invalid token: expected '}', got "EOF"`; err == nil || err.Error() != expected {
+		t.Errorf("expected %q, got %v", expected, err)
+	}
+}
+
+func TestParser_options(t *testing.T) {
+	src := "{title-guitar: G}\n{title-!guitar: P}\n{refrain}\n[C]do\n{end_refrain}\n[D"
+
+	tests := []struct {
+		name     string
+		opts     ParseOptions
+		title    string
+		chorus   bool
+		warnings string
+	}{
+		{name: "default", title: "P"},
+		{
+			name:  "selectors",
+			opts:  ParseOptions{Selectors: []string{"Guitar"}},
+			title: "G",
+		},
+		{
+			name:   "directives",
+			opts:   ParseOptions{Directives: map[string]string{"Refrain": "SOC", "END_refrain": "eoc"}},
+			title:  "P",
+			chorus: true,
+		},
+		{
+			name:     "warnings",
+			opts:     ParseOptions{Warnings: &strings.Builder{}},
+			title:    "P",
+			warnings: "6:1: error: ChordState Invalid token: expected ']', got \"EOF\" (unterminated-chord)\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ss, err := NewParser(tt.opts).Parse(src)
+			if err != nil {
+				t.Fatalf("unexpected error %q", err.Error())
+			}
+			if got := ss[0].Title(); got != tt.title {
+				t.Errorf("expected title %q, got %q", tt.title, got)
+			}
+			chorus := false
+			for _, p := range ss[0].Paragraphs {
+				chorus = chorus || p.ParagraphType == Chorus
+			}
+			if chorus != tt.chorus {
+				t.Errorf("expected chorus %v, got %v", tt.chorus, chorus)
+			}
+			if sb, ok := tt.opts.Warnings.(*strings.Builder); ok && sb.String() != tt.warnings {
+				t.Errorf("expected warnings %q, got %q", tt.warnings, sb.String())
+			}
+		})
+	}
+}
+
+func TestParser_ParseBytes(t *testing.T) {
+	tests := []struct {
+		encoding string
+		data     []byte
+		title    string
+		err      error
+	}{
+		{"", []byte("\xef\xbb\xbf{t: Perché}"), "Perché", nil},
+		{EncodingLatin1, []byte("{t: Perch\xe9}"), "Perché", nil},
+		{EncodingAuto, []byte("{t: Perch\xe9}"), "Perché", nil},
+		{EncodingAuto, []byte("{t: Perché}"), "Perché", nil},
+		{"ebcdic", []byte("{t: A}"), "", ErrInvalidEncoding},
+	}
+	for _, tt := range tests {
+		ss, err := NewParser(ParseOptions{Encoding: tt.encoding}).ParseBytes(tt.data)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%s: expected error %v, got %v", tt.encoding, tt.err, err)
+			continue
+		}
+		if err == nil && ss[0].Title() != tt.title {
+			t.Errorf("%s: expected title %q, got %q", tt.encoding, tt.title, ss[0].Title())
+		}
+	}
+}
+
+func Test_parseAttrs(t *testing.T) {
+	tests := []struct {
+		arg  string
+		want map[string]string
+	}{
+		{"", nil},
+		{"D-DU-UDU", nil},
+		{`id=abc`, map[string]string{"id": "abc"}},
+		{`ID="a b" start=10`, map[string]string{"id": "a b", "start": "10"}},
+		{`id="abc`, nil},
+		{`id="a"b`, nil},
+		{`a b=c`, nil},
+	}
+	for _, tt := range tests {
+		if got := parseAttrs(tt.arg); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: expected %v, got %v", tt.arg, tt.want, got)
+		}
+	}
+}
+
+func TestParser_HandleDirective(t *testing.T) {
+	src := "{t: A}\n[C]do\n{x_strum-guitar: D-DU-UDU}\n{x_youtube: id=\"abc\" start=10}\n{x_bad}\n[D]re"
+
+	p := NewParser(ParseOptions{Selectors: []string{"guitar"}})
+	p.HandleDirective("x_strum", func(s *Song, d *Directive) (*CustomNode, error) {
+		return &CustomNode{Name: d.Name, Selector: d.Selector, Value: d.Value}, nil
+	})
+	p.HandleDirective("X_YOUTUBE", func(s *Song, d *Directive) (*CustomNode, error) {
+		s.SetCustom("youtube", d.Attrs["id"])
+		return nil, nil
+	})
+	p.HandleDirective("x_bad", func(s *Song, d *Directive) (*CustomNode, error) {
+		return nil, errors.New("bad")
+	})
+
+	ss, err := p.Parse(src)
+	if err != nil {
+		t.Fatalf("unexpected error %q", err.Error())
+	}
+	s := ss[0]
+
+	var types []ParagraphType
+	for _, par := range s.Paragraphs {
+		types = append(types, par.ParagraphType)
+	}
+	// the node doesn't split the verse
+	if want := []ParagraphType{Verse, Custom}; !reflect.DeepEqual(types, want) {
+		t.Fatalf("expected %v, got %v", want, types)
+	}
+	want := &CustomNode{Name: "x_strum", Selector: "guitar", Value: "D-DU-UDU"}
+	if got := s.Paragraphs[1].Node; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if got := s.Paragraphs[1].Pos.String(); got != "3:1" {
+		t.Errorf("expected %v, got %v", "3:1", got)
+	}
+	if got := s.Custom["youtube"]; got != "abc" {
+		t.Errorf("expected %v, got %v", "abc", got)
+	}
+	if len(s.Diagnostics) != 1 || s.Diagnostics[0].Code != CodeDirectiveError || s.Diagnostics[0].Line != 5 {
+		t.Errorf("expected a %s diagnostic at line 5, got %v", CodeDirectiveError, s.Diagnostics)
+	}
+
+	p.opts.Strict = true
+	_, err = p.Parse(src)
+	if want := "5:1: directive {x_bad}: bad"; err == nil || err.Error() != want {
+		t.Errorf("expected error %q, got %v", want, err)
+	}
+}
+
+func TestParser_HandleDirectiveParagraph(t *testing.T) {
+	tests := []struct {
+		src   string
+		types []ParagraphType
+		lines int
+	}{
+		{"{soc}\n{x_strum: D}\n[C]do\n[D]re\n{eoc}", []ParagraphType{Custom, Chorus}, 2},
+		{"{soc}\n[C]do\n{x_strum: D}\n[D]re\n{eoc}", []ParagraphType{Chorus, Custom}, 2},
+		{"{soc}\n[C]do\n[D]re\n{eoc}\n{x_strum: D}", []ParagraphType{Chorus, Custom, Verse}, 2},
+	}
+	p := NewParser(ParseOptions{})
+	p.HandleDirective("x_strum", func(s *Song, d *Directive) (*CustomNode, error) {
+		return &CustomNode{Name: d.Name, Value: d.Value}, nil
+	})
+	for _, tt := range tests {
+		ss, err := p.Parse(tt.src)
+		if err != nil {
+			t.Fatalf("unexpected error %q", err.Error())
+		}
+		var types []ParagraphType
+		lines := 0
+		for _, par := range ss[0].Paragraphs {
+			types = append(types, par.ParagraphType)
+			for _, lin := range par.Lines {
+				if !isBlankLine(lin) {
+					lines++
+				}
+			}
+		}
+		if !reflect.DeepEqual(types, tt.types) {
+			t.Errorf("%q: expected %v, got %v", tt.src, tt.types, types)
+		}
+		if lines != tt.lines {
+			t.Errorf("%q: expected %v lines, got %v", tt.src, tt.lines, lines)
+		}
+	}
+}
+
+func TestParser_hyphenDirectives(t *testing.T) {
+	p := NewParser(ParseOptions{
+		Selectors:  []string{"guitar"},
+		Directives: map[string]string{"my-refrain": "soc", "end-refrain": "eoc"},
+	})
+	p.HandleDirective("x-strum", func(s *Song, d *Directive) (*CustomNode, error) {
+		return &CustomNode{Name: d.Name, Selector: d.Selector, Value: d.Value}, nil
+	})
+	ss, err := p.Parse("{title-guitar: G}\n{x-strum: D}\n{x-strum-guitar: U}\n{my-refrain}\n[C]do\n{end-refrain}")
+	if err != nil {
+		t.Fatalf("unexpected error %q", err.Error())
+	}
+	s := ss[0]
+	if got := s.Title(); got != "G" {
+		t.Errorf("expected title %q, got %q", "G", got)
+	}
+
+	var nodes []CustomNode
+	chorus := false
+	for _, par := range s.Paragraphs {
+		if par.Node != nil {
+			nodes = append(nodes, *par.Node)
+		}
+		chorus = chorus || par.ParagraphType == Chorus
+	}
+	want := []CustomNode{{Name: "x-strum", Value: "D"}, {Name: "x-strum", Selector: "guitar", Value: "U"}}
+	if !reflect.DeepEqual(nodes, want) {
+		t.Errorf("expected %v, got %v", want, nodes)
+	}
+	if !chorus {
+		t.Errorf("expected chorus, got %v", s.Paragraphs)
+	}
+}
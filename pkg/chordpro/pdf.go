@@ -0,0 +1,486 @@
+package chordpro
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/mmbros/chordpro/internal/pdf"
+)
+
+// Defaults of the PdfFormatter.
+const (
+	DefaultPdfColumns  = 1
+	DefaultPdfFontSize = 10.0
+)
+
+// ErrPdfCharacter is returned by the PdfFormatter without a FontFile
+// when the text has a character that the standard fonts don't have.
+var ErrPdfCharacter = errors.New("character not in the standard pdf fonts, use a font file")
+
+func init() {
+	RegisterFormatter("pdf", func() Formatter {
+		return &PdfFormatter{Columns: DefaultPdfColumns, FontSize: DefaultPdfFontSize}
+	})
+}
+
+// PdfFormatter is the Formatter of the "pdf" format.
+// The songs are laid out chords over lyrics on A4 pages,
+// with headers and footers. Many songs are preceded by
+// a table of contents.
+//
+// By default the text is written in the standard Courier and Helvetica fonts,
+// that are not embedded and only have the Latin-1 characters:
+// for the other characters ErrPdfCharacter is returned.
+// The FontFile option embeds a TrueType font instead, with the glyphs of the text.
+// The font should be monospaced, like DejaVu Sans Mono,
+// to keep the chords over their syllables.
+type PdfFormatter struct {
+	// Title is the title of the table of contents of a songbook.
+	Title string
+	// Columns is the number of columns of each page.
+	Columns int
+	// FontSize is the size in points of the chords and lyrics.
+	FontSize float64
+	// FontFile is the TrueType font file (.ttf) of the text, if not empty.
+	FontFile string
+	// BoldFontFile is the TrueType font file of the chords and titles.
+	// If empty, the font of FontFile is used.
+	BoldFontFile string
+}
+
+// page geometry, in points
+const (
+	pdfMarginX      = 48.0
+	pdfMarginTop    = 60.0
+	pdfMarginBottom = 54.0
+	pdfGutter       = 24.0
+	pdfHeaderSize   = 9.0
+	pdfTitleSize    = 16.0
+	pdfArtistSize   = 11.0
+)
+
+// pdfRow is a single line of text.
+type pdfRow struct {
+	font pdf.Font
+	size float64
+	text string
+	link int // index of the linked song, or -1
+}
+
+func (r pdfRow) height() float64 {
+	return r.size * 1.2
+}
+
+// pdfPlaced is a row placed in a page.
+type pdfPlaced struct {
+	x, y float64 // baseline position
+	row  pdfRow
+}
+
+// pdfPage is a page of the layout.
+type pdfPage struct {
+	song  *Song // nil for the table of contents
+	first bool  // first page of the song
+	rows  []pdfPlaced
+}
+
+// pdfFonts are the embedded fonts of a document, nil for the standard fonts,
+// with the width of their characters, in units of the font size.
+type pdfFonts struct {
+	regular, bold *pdf.TrueType
+	charWidth     float64
+}
+
+// loadFonts method returns the fonts of the FontFile and BoldFontFile options.
+func (f *PdfFormatter) loadFonts() (*pdfFonts, error) {
+	fonts := &pdfFonts{charWidth: pdf.CourierWidth}
+	if f.FontFile == "" {
+		return fonts, nil
+	}
+	load := func(path string) (*pdf.TrueType, error) {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		t, err := pdf.ParseTrueType(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return t, nil
+	}
+
+	var err error
+	if fonts.regular, err = load(f.FontFile); err != nil {
+		return nil, err
+	}
+	fonts.bold = fonts.regular
+	if f.BoldFontFile != "" {
+		if fonts.bold, err = load(f.BoldFontFile); err != nil {
+			return nil, err
+		}
+	}
+	fonts.charWidth = fonts.regular.Advance('0')
+	return fonts, nil
+}
+
+// setFonts method embeds the fonts in the document, in place of the standard ones.
+func (fonts *pdfFonts) setFonts(doc *pdf.Document) {
+	if fonts.regular == nil {
+		return
+	}
+	for _, font := range []pdf.Font{pdf.Courier, pdf.CourierOblique, pdf.Helvetica, pdf.HelveticaOblique} {
+		doc.SetFont(font, fonts.regular)
+	}
+	for _, font := range []pdf.Font{pdf.CourierBold, pdf.HelveticaBold} {
+		doc.SetFont(font, fonts.bold)
+	}
+}
+
+// pdfLayout places the rows in the pages and columns.
+type pdfLayout struct {
+	f     *PdfFormatter
+	fonts *pdfFonts
+	pages []*pdfPage
+	page  *pdfPage
+	col   int
+	top   float64 // top of the columns of the current page
+	y     float64 // top of the next row
+}
+
+func (f *PdfFormatter) columns() int {
+	if f.Columns < 1 {
+		return 1
+	}
+	return f.Columns
+}
+
+func (f *PdfFormatter) fontSize() float64 {
+	if f.FontSize <= 0 {
+		return DefaultPdfFontSize
+	}
+	return f.FontSize
+}
+
+func (f *PdfFormatter) columnWidth() float64 {
+	cols := float64(f.columns())
+	return (pdf.A4Width - 2*pdfMarginX - (cols-1)*pdfGutter) / cols
+}
+
+// columnChars returns the number of monospaced characters of a column.
+func (l *pdfLayout) columnChars() int {
+	return int(l.f.columnWidth() / (l.f.fontSize() * l.fonts.charWidth))
+}
+
+func (l *pdfLayout) newPage(song *Song, first bool) {
+	l.page = &pdfPage{song: song, first: first}
+	l.pages = append(l.pages, l.page)
+	l.col = 0
+	l.top = pdf.A4Height - pdfMarginTop
+	l.y = l.top
+}
+
+func (l *pdfLayout) nextColumn() {
+	if l.col < l.f.columns()-1 {
+		l.col++
+		l.y = l.top
+		return
+	}
+	l.newPage(l.page.song, false)
+}
+
+// placeWide places the row across all the columns of the page.
+func (l *pdfLayout) placeWide(row pdfRow) {
+	l.page.rows = append(l.page.rows, pdfPlaced{pdfMarginX, l.y - row.size, row})
+	l.y -= row.height()
+	l.top = l.y
+}
+
+// place places the block of rows in the current column.
+// If the block doesn't fit the rest of the column but fits
+// an empty one, it's moved to the next column.
+func (l *pdfLayout) place(block []pdfRow) {
+	var h float64
+	for _, row := range block {
+		h += row.height()
+	}
+	if h > l.y-pdfMarginBottom && h <= l.top-pdfMarginBottom && l.y < l.top {
+		l.nextColumn()
+	}
+
+	x0 := pdfMarginX + float64(l.col)*(l.f.columnWidth()+pdfGutter)
+	for _, row := range block {
+		if row.height() > l.y-pdfMarginBottom && l.y < l.top {
+			l.nextColumn()
+			x0 = pdfMarginX + float64(l.col)*(l.f.columnWidth()+pdfGutter)
+		}
+		l.page.rows = append(l.page.rows, pdfPlaced{x0, l.y - row.size, row})
+		l.y -= row.height()
+	}
+}
+
+// space adds a vertical space, unless at the top of a column.
+func (l *pdfLayout) space(h float64) {
+	if l.y < l.top {
+		l.y -= h
+	}
+}
+
+// pdfChunks splits the string in chunks of at most n runes.
+func pdfChunks(s string, n int) []string {
+	rs := []rune(s)
+	if n <= 0 || len(rs) <= n {
+		return []string{s}
+	}
+	var chunks []string
+	for len(rs) > n {
+		chunks = append(chunks, string(rs[:n]))
+		rs = rs[n:]
+	}
+	return append(chunks, string(rs))
+}
+
+// paragraphBlock returns the rows of the paragraph.
+func (l *pdfLayout) paragraphBlock(p *Paragraph) []pdfRow {
+	var block []pdfRow
+	size := l.f.fontSize()
+	chars := l.columnChars()
+	row := func(font pdf.Font, txt string) pdfRow {
+		return pdfRow{font: font, size: size, text: txt, link: -1}
+	}
+
+	if h := paragraphHeader(p); h != "" {
+		block = append(block, row(pdf.HelveticaBold, h))
+	}
+
+	switch p.ParagraphType {
+	case Comment:
+		for _, lin := range p.Lines {
+			var txt strings.Builder
+			for _, pair := range lin.Pairs {
+				txt.WriteString(pair.Lyric)
+			}
+			comment := &Line{Pairs: []*ChordLyricPair{{Lyric: strings.TrimSpace(txt.String())}}}
+			for _, r := range textLine(comment, chars) {
+				block = append(block, row(pdf.CourierOblique, r.lyrics))
+			}
+		}
+	case ChorusRef:
+		label := p.Label
+		if label == "" {
+			label = "Chorus"
+		}
+		block = append(block, row(pdf.HelveticaOblique, label))
+	case Custom:
+		for _, lin := range renderNodeLines("pdf", p) {
+			for _, chunk := range pdfChunks(lin, chars) {
+				block = append(block, row(pdf.Courier, chunk))
+			}
+		}
+	case Tab:
+		for _, lin := range p.Lines {
+			var txt strings.Builder
+			for _, pair := range lin.Pairs {
+				txt.WriteString(pair.Lyric)
+			}
+			for _, chunk := range pdfChunks(txt.String(), chars) {
+				block = append(block, row(pdf.Courier, chunk))
+			}
+		}
+	default:
+		for _, lin := range trimBlankLines(p.Lines) {
+			for _, r := range textLine(lin, chars) {
+				if r.chords != "" {
+					block = append(block, row(pdf.CourierBold, r.chords))
+				}
+				if r.lyrics != "" || r.chords == "" {
+					block = append(block, row(pdf.Courier, r.lyrics))
+				}
+			}
+		}
+	}
+	return block
+}
+
+func (l *pdfLayout) appendSong(s *Song) {
+	l.newPage(s, true)
+
+	l.placeWide(pdfRow{font: pdf.HelveticaBold, size: pdfTitleSize, text: s.Title(), link: -1})
+	for _, st := range []string{s.SubTitle(), s.Artist()} {
+		if st != "" {
+			l.placeWide(pdfRow{font: pdf.Helvetica, size: pdfArtistSize, text: st, link: -1})
+		}
+	}
+	l.y -= pdfArtistSize
+	l.top = l.y
+
+	for _, p := range s.Paragraphs {
+		if p.ParagraphType != ChorusRef && p.ParagraphType != Custom && isBlank(p) {
+			continue
+		}
+		block := l.paragraphBlock(p)
+		if len(block) == 0 {
+			continue
+		}
+		l.space(l.f.fontSize() * 0.8)
+		l.place(block)
+	}
+
+	if s.Err != nil {
+		l.space(l.f.fontSize() * 0.8)
+		l.place([]pdfRow{{font: pdf.CourierOblique, size: l.f.fontSize(), text: "error: " + s.Err.Error(), link: -1}})
+	}
+}
+
+// tocPages returns the pages of the table of contents.
+// The page of each song is shifted by the number of pages of the table.
+func (f *PdfFormatter) tocPages(ss Songs, songPage []int, fonts *pdfFonts) []*pdfPage {
+	size := 11.0
+	title := f.Title
+	if title == "" {
+		title = "Contents"
+	}
+	chars := int((pdf.A4Width - 2*pdfMarginX) / (size * fonts.charWidth))
+	lineHeight := size * 1.2
+	perPage := int((pdf.A4Height - pdfMarginTop - pdfMarginBottom - 2*pdfTitleSize) / lineHeight)
+	numPages := (len(ss) + perPage - 1) / perPage
+
+	var pages []*pdfPage
+	var page *pdfPage
+	var y float64
+	for j, s := range ss {
+		if j%perPage == 0 {
+			page = &pdfPage{first: j == 0}
+			pages = append(pages, page)
+			y = pdf.A4Height - pdfMarginTop
+			if j == 0 {
+				page.rows = append(page.rows, pdfPlaced{pdfMarginX, y - pdfTitleSize, pdfRow{font: pdf.HelveticaBold, size: pdfTitleSize, text: title, link: -1}})
+			}
+			y -= 2 * pdfTitleSize
+		}
+
+		num := fmt.Sprint(songPage[j] + numPages + 1)
+		name := s.Title()
+		if a := s.Artist(); a != "" {
+			name += " - " + a
+		}
+		if n := chars - len(num) - 2; runeLen(name) > n {
+			name = string([]rune(name)[:n-1]) + "…"
+		}
+		dots := strings.Repeat(".", chars-runeLen(name)-len(num)-2)
+		txt := name + " " + dots + " " + num
+
+		page.rows = append(page.rows, pdfPlaced{pdfMarginX, y - size, pdfRow{font: pdf.Courier, size: size, text: txt, link: j}})
+		y -= lineHeight
+	}
+	return pages
+}
+
+// render writes the pages to a new PDF document.
+func (f *PdfFormatter) render(w io.Writer, pages []*pdfPage, songPage []int, title string, fonts *pdfFonts) error {
+	doc := pdf.New()
+	doc.Title = title
+	fonts.setFonts(doc)
+
+	total := len(pages)
+	for j, p := range pages {
+		page := doc.AddPage(pdf.A4Width, pdf.A4Height)
+
+		// header
+		if p.song != nil && !p.first {
+			txt := p.song.Title()
+			if a := p.song.Artist(); a != "" {
+				txt += " - " + a
+			}
+			y := pdf.A4Height - pdfMarginTop/2
+			page.Text(pdfMarginX, y, pdf.Helvetica, pdfHeaderSize, txt)
+			page.Line(pdfMarginX, y-4, pdf.A4Width-pdfMarginX, y-4, 0.5)
+		}
+
+		for _, r := range p.rows {
+			page.Text(r.x, r.y, r.row.font, r.row.size, r.row.text)
+			if r.row.link >= 0 {
+				x2 := r.x + float64(runeLen(r.row.text))*r.row.size*fonts.charWidth
+				page.Link(r.x, r.y-r.row.size*0.25, x2, r.y+r.row.size, songPage[r.row.link])
+			}
+		}
+
+		// footer
+		txt := fmt.Sprintf("%d / %d", j+1, total)
+		x := pdf.A4Width - pdfMarginX - float64(len(txt))*pdfHeaderSize*fonts.charWidth
+		page.Text(x, pdfMarginBottom/2, pdf.Courier, pdfHeaderSize, txt)
+	}
+
+	for j, p := range pages {
+		if p.song != nil && p.first {
+			doc.AddOutline(p.song.Title(), j)
+		}
+	}
+
+	_, err := doc.WriteTo(w)
+	return err
+}
+
+// checkPdfCharacters function returns ErrPdfCharacter
+// if the text of the pages has a character that the standard fonts don't have.
+// The headers repeat the text of the pages.
+func checkPdfCharacters(pages []*pdfPage) error {
+	for _, p := range pages {
+		for _, r := range p.rows {
+			for _, c := range r.row.text {
+				if !pdf.Encodable(c) {
+					return fmt.Errorf("%w: %q", ErrPdfCharacter, c)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (f *PdfFormatter) format(w io.Writer, ss Songs, toc bool, title string) error {
+	fonts, err := f.loadFonts()
+	if err != nil {
+		return err
+	}
+	l := &pdfLayout{f: f, fonts: fonts}
+	songPage := make([]int, len(ss))
+	for j, s := range ss {
+		songPage[j] = len(l.pages)
+		l.appendSong(s)
+	}
+
+	pages := l.pages
+	if toc {
+		tp := f.tocPages(ss, songPage, fonts)
+		for j := range songPage {
+			songPage[j] += len(tp)
+		}
+		pages = append(tp, pages...)
+	}
+	if fonts.regular == nil {
+		if err := checkPdfCharacters(pages); err != nil {
+			return err
+		}
+	}
+	return f.render(w, pages, songPage, title, fonts)
+}
+
+// FormatSong writes the song as a PDF document.
+func (f *PdfFormatter) FormatSong(w io.Writer, s *Song) error {
+	return f.format(w, Songs{s}, false, s.Title())
+}
+
+// FormatSongs writes the songs as a single PDF document.
+// Each song begins on a new page. Two or more songs are preceded by
+// a table of contents with links to the songs.
+func (f *PdfFormatter) FormatSongs(w io.Writer, ss Songs) error {
+	return f.format(w, ss, len(ss) > 1, f.Title)
+}
+
+// Extension returns the ".pdf" extension.
+func (f *PdfFormatter) Extension() string { return ".pdf" }
+
+// MimeType returns the "application/pdf" MIME type.
+func (f *PdfFormatter) MimeType() string { return "application/pdf" }
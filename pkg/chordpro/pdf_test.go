@@ -0,0 +1,122 @@
+package chordpro
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/mmbros/chordpro/internal/pdf"
+)
+
+func Test_pdfChunks(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		n     int
+		want  []string
+	}{
+		{"short", "abc", 5, []string{"abc"}},
+		{"exact", "abcde", 5, []string{"abcde"}},
+		{"long", "abcdefg", 3, []string{"abc", "def", "g"}},
+		{"no limit", "abcdefg", 0, []string{"abcdefg"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := pdfChunks(tt.input, tt.n)
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected %q, got %q", tt.want, got)
+			}
+			for j := range got {
+				if got[j] != tt.want[j] {
+					t.Errorf("expected %q, got %q", tt.want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestPdfFormatter_FormatSongs(t *testing.T) {
+	ss := ParseText("{t:One}[C]do{ns}{t:Two}[D]re{ns}{t:Three}[E]mi")
+
+	f := &PdfFormatter{Columns: 2, FontSize: 10}
+	var buf bytes.Buffer
+	if err := f.FormatSongs(&buf, ss); err != nil {
+		t.Fatalf("unexpected error %q", err.Error())
+	}
+	data := buf.Bytes()
+
+	if !bytes.HasPrefix(data, []byte("%PDF-")) {
+		t.Errorf("missing PDF header")
+	}
+	// one page for the table of contents and one page for each song
+	if got := bytes.Count(data, []byte("/Type /Page ")); got != 4 {
+		t.Errorf("pages: expected %d, got %d", 4, got)
+	}
+	// links from the table of contents
+	if got := bytes.Count(data, []byte("/Subtype /Link")); got != 3 {
+		t.Errorf("links: expected %d, got %d", 3, got)
+	}
+}
+
+func TestPdfFormatter_pagination(t *testing.T) {
+	var sb bytes.Buffer
+	sb.WriteString("{t:Long}\n")
+	for j := 0; j < 200; j++ {
+		sb.WriteString("[C]la la la [G]la la\n\n")
+	}
+
+	for _, cols := range []int{1, 2} {
+		f := &PdfFormatter{Columns: cols, FontSize: 10}
+		fonts, _ := f.loadFonts()
+		l := &pdfLayout{f: f, fonts: fonts}
+		l.appendSong(ParseText(sb.String())[0])
+		if len(l.pages) < 2 {
+			t.Errorf("columns %d: expected many pages, got %d", cols, len(l.pages))
+		}
+		for j, p := range l.pages {
+			for _, r := range p.rows {
+				if r.y < pdfMarginBottom-r.row.size {
+					t.Errorf("columns %d: page %d: row under the bottom margin", cols, j+1)
+				}
+			}
+		}
+	}
+}
+
+func TestPdfFormatter_FontFile(t *testing.T) {
+	ss := ParseText("{t:Perché}[C]日本")
+
+	f := &PdfFormatter{}
+	if err := f.FormatSongs(&bytes.Buffer{}, ss); !errors.Is(err, ErrPdfCharacter) || !strings.Contains(err.Error(), "'日'") {
+		t.Errorf("expected %v for '日', got %v", ErrPdfCharacter, err)
+	}
+	if err := f.FormatSongs(&bytes.Buffer{}, ParseText("{t:Perché}[C]“do” – re")); err != nil {
+		t.Errorf("unexpected error %q", err.Error())
+	}
+
+	f = &PdfFormatter{FontFile: "pdf_test.go"}
+	if err := f.FormatSongs(&bytes.Buffer{}, ss); !errors.Is(err, pdf.ErrInvalidFont) {
+		t.Errorf("expected %v, got %v", pdf.ErrInvalidFont, err)
+	}
+	f = &PdfFormatter{FontFile: "missing.ttf"}
+	if err := f.FormatSongs(&bytes.Buffer{}, ss); !os.IsNotExist(err) {
+		t.Errorf("expected not exist error, got %v", err)
+	}
+
+	const font = "/usr/share/fonts/truetype/dejavu/DejaVuSansMono.ttf"
+	if _, err := os.Stat(font); err != nil {
+		t.Skip("font not found: " + font)
+	}
+	f = &PdfFormatter{FontFile: font}
+	var buf bytes.Buffer
+	if err := f.FormatSongs(&buf, ss); err != nil {
+		t.Fatalf("unexpected error %q", err.Error())
+	}
+	for _, want := range []string{"+DejaVuSansMono /Encoding /Identity-H", "/FontFile2 "} {
+		if !bytes.Contains(buf.Bytes(), []byte(want)) {
+			t.Errorf("expected %q in the document", want)
+		}
+	}
+}
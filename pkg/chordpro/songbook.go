@@ -0,0 +1,206 @@
+package chordpro
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// DefaultSongbookTitle is the default title of the songbooks.
+const DefaultSongbookTitle = "Songbook"
+
+func init() {
+	RegisterFormatter("songbook", func() Formatter { return &SongbookFormatter{} })
+}
+
+// songbookCSS is the stylesheet of the songbook sections,
+// added to the one of the theme.
+const songbookCSS = `.cover { text-align: center; padding: 8em 0; }
+.cover h1 { font-size: 3em; }
+.contents ol, .index ul { padding-left: 1.5em; }
+.index h3 { margin: 1em 0 0.3em; color: var(--accent); }
+.index li span { color: var(--muted); }
+a { color: var(--accent); text-decoration: none; }
+a.back { display: block; font-size: 0.8em; margin-top: -2em; }
+@media print {
+  .cover, .contents, .index { break-after: page; page-break-after: always; }
+  a.back { display: none; }
+}
+`
+
+// sortKey method returns the key used to sort the songs:
+// the sort title if present, the title otherwise.
+func (s *Song) sortKey() string {
+	t := s.SortTitle()
+	if t == "" {
+		t = s.Title()
+	}
+	return strings.ToLower(t)
+}
+
+// SortSongs sorts the songs by their sort title,
+// falling back to the title for the songs without it.
+// The songs with the same key keep their order.
+func SortSongs(ss Songs) {
+	sort.SliceStable(ss, func(i, j int) bool {
+		return ss[i].sortKey() < ss[j].sortKey()
+	})
+}
+
+// SongbookFormatter is the Formatter of the "songbook" format.
+// It writes all the songs in a single html document, with a cover,
+// a table of contents and the indexes by title and by artist.
+// Every entry links to the anchor of its song, and every song
+// has a back-link to the table of contents.
+// The songs are written in the given order: use SortSongs
+// to sort them by title.
+type SongbookFormatter struct {
+	Title string    // title of the songbook, DefaultSongbookTitle if empty
+	Body  Formatter // formatter of the songs, HtmlFormatter if nil
+	Theme string    // name of the theme, DefaultTheme if empty
+	CSS   string    // custom stylesheet added after the one of the theme
+}
+
+// songAnchor function returns the anchor of the j-th song.
+func songAnchor(j int) string {
+	return fmt.Sprintf("song-%d", j+1)
+}
+
+// indexLetter function returns the letter of the index group of the text.
+func indexLetter(s string) string {
+	for _, r := range s {
+		if unicode.IsLetter(r) {
+			return string(unicode.ToUpper(r))
+		}
+		if unicode.IsDigit(r) {
+			return "#"
+		}
+	}
+	return "#"
+}
+
+func (f *SongbookFormatter) appendCover(w io.Writer, title string, ss Songs) {
+	fmt.Fprintln(w, `<section class="cover">`)
+	fmt.Fprintf(w, "<h1>%s</h1>\n", html.EscapeString(title))
+	fmt.Fprintf(w, "<p>%d songs</p>\n", len(ss))
+	fmt.Fprintln(w, `</section>`)
+}
+
+func (f *SongbookFormatter) appendContents(w io.Writer, ss Songs) {
+	fmt.Fprintln(w, `<section class="contents" id="contents">`)
+	fmt.Fprintln(w, "<h2>Contents</h2>")
+	fmt.Fprintln(w, "<ol>")
+	for j, s := range ss {
+		fmt.Fprintf(w, "<li><a href=\"#%s\">%s</a></li>\n", songAnchor(j), html.EscapeString(s.Title()))
+	}
+	fmt.Fprintln(w, "</ol>")
+	fmt.Fprintln(w, `<p><a href="#index-titles">Index by title</a> &middot; <a href="#index-artists">Index by artist</a></p>`)
+	fmt.Fprintln(w, `</section>`)
+}
+
+// songbookEntry is an entry of an index of the songbook.
+type songbookEntry struct {
+	key    string // sort key
+	text   string // text of the link
+	note   string // text after the link
+	anchor string
+}
+
+func (f *SongbookFormatter) appendIndex(w io.Writer, id, title string, entries []songbookEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].key < entries[j].key
+	})
+
+	fmt.Fprintf(w, "<section class=\"index\" id=\"%s\">\n", id)
+	fmt.Fprintf(w, "<h2>%s</h2>\n", title)
+	letter := ""
+	for _, e := range entries {
+		if l := indexLetter(e.key); l != letter {
+			if letter != "" {
+				fmt.Fprintln(w, "</ul>")
+			}
+			letter = l
+			fmt.Fprintf(w, "<h3>%s</h3>\n<ul>\n", html.EscapeString(letter))
+		}
+		fmt.Fprintf(w, "<li><a href=\"#%s\">%s</a>", e.anchor, html.EscapeString(e.text))
+		if e.note != "" {
+			fmt.Fprintf(w, " <span>%s</span>", html.EscapeString(e.note))
+		}
+		fmt.Fprintln(w, "</li>")
+	}
+	if letter != "" {
+		fmt.Fprintln(w, "</ul>")
+	}
+	fmt.Fprintln(w, `<a class="back" href="#contents">&uarr; Contents</a>`)
+	fmt.Fprintln(w, `</section>`)
+}
+
+// songArtist function returns the artist of the song,
+// or its subtitle if the artist is missing.
+func songArtist(s *Song) string {
+	if a := s.Artist(); a != "" {
+		return a
+	}
+	return s.SubTitle()
+}
+
+// FormatSong writes a songbook with the song only.
+func (f *SongbookFormatter) FormatSong(w io.Writer, s *Song) error {
+	return f.FormatSongs(w, Songs{s})
+}
+
+// FormatSongs writes the songbook of the songs.
+func (f *SongbookFormatter) FormatSongs(w io.Writer, ss Songs) error {
+	css, err := ThemeCSS(f.Theme)
+	if err != nil {
+		return fmt.Errorf("%w: %q (valid themes: %s)", err, f.Theme, strings.Join(ThemeNames(), ", "))
+	}
+	body := f.Body
+	if body == nil {
+		body = HtmlFormatter{}
+	}
+	title := f.Title
+	if title == "" {
+		title = DefaultSongbookTitle
+	}
+
+	appendHTMLHead(w, title, css, songbookCSS, f.CSS)
+	f.appendCover(w, title, ss)
+	f.appendContents(w, ss)
+
+	var byTitle, byArtist []songbookEntry
+	for j, s := range ss {
+		anchor := songAnchor(j)
+		artist := songArtist(s)
+		byTitle = append(byTitle, songbookEntry{s.sortKey(), s.Title(), artist, anchor})
+		if artist != "" {
+			byArtist = append(byArtist, songbookEntry{strings.ToLower(artist) + "\x00" + s.sortKey(), artist, s.Title(), anchor})
+		}
+
+		fmt.Fprintf(w, "<article id=\"%s\">\n", anchor)
+		if _, ok := body.(*TemplateFormatter); !ok {
+			appendSongHeader(w, s)
+		}
+		if err := body.FormatSong(w, s); err != nil {
+			return err
+		}
+		fmt.Fprintln(w, `<a class="back" href="#contents">&uarr; Contents</a>`)
+		fmt.Fprintln(w, "</article>")
+	}
+
+	f.appendIndex(w, "index-titles", "Index by title", byTitle)
+	f.appendIndex(w, "index-artists", "Index by artist", byArtist)
+
+	fmt.Fprintln(w, "</body>")
+	_, err = fmt.Fprintln(w, "</html>")
+	return err
+}
+
+// Extension returns the ".html" extension.
+func (*SongbookFormatter) Extension() string { return ".html" }
+
+// MimeType returns the "text/html" MIME type.
+func (*SongbookFormatter) MimeType() string { return "text/html" }
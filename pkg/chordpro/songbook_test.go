@@ -0,0 +1,100 @@
+package chordpro
+
+import (
+	"strings"
+	"testing"
+)
+
+const songbookSrc = `{title: The Wall}
+{sorttitle: Wall}
+{artist: Pink Floyd}
+[G]la
+{new_song}
+{title: Yesterday}
+{artist: The Beatles}
+[F]la
+{new_song}
+{title: Angie}
+{subtitle: Rolling Stones}
+[Am]la
+`
+
+func Test_SortSongs(t *testing.T) {
+	ss := ParseText(songbookSrc)
+	SortSongs(ss)
+
+	expected := []string{"Angie", "The Wall", "Yesterday"}
+	if len(ss) != len(expected) {
+		t.Fatalf("expected %d songs, got %d", len(expected), len(ss))
+	}
+	for j, s := range ss {
+		if s.Title() != expected[j] {
+			t.Errorf("song %d: expected %q, got %q", j, expected[j], s.Title())
+		}
+	}
+}
+
+func TestSongbookFormatter_FormatSongs(t *testing.T) {
+	ss := ParseText(songbookSrc)
+	SortSongs(ss)
+
+	var sb strings.Builder
+	f := &SongbookFormatter{Title: "Gig & Co"}
+	if err := f.FormatSongs(&sb, ss); err != nil {
+		t.Fatalf("unexpected error %q", err.Error())
+	}
+	got := sb.String()
+
+	// the sections must be in this order
+	want := []string{
+		"<title>Gig &amp; Co</title>",
+		`<section class="cover">`,
+		"<p>3 songs</p>",
+		`<section class="contents" id="contents">`,
+		`<li><a href="#song-1">Angie</a></li>`,
+		`<li><a href="#song-2">The Wall</a></li>`,
+		`<li><a href="#song-3">Yesterday</a></li>`,
+		`<article id="song-1">`,
+		`<a class="back" href="#contents">`,
+		`<article id="song-3">`,
+		`<section class="index" id="index-titles">`,
+		"<h3>A</h3>",
+		`<li><a href="#song-1">Angie</a> <span>Rolling Stones</span></li>`,
+		"<h3>W</h3>",
+		`<li><a href="#song-2">The Wall</a> <span>Pink Floyd</span></li>`,
+		"<h3>Y</h3>",
+		`<section class="index" id="index-artists">`,
+		"<h3>P</h3>",
+		`<li><a href="#song-2">Pink Floyd</a> <span>The Wall</span></li>`,
+		"<h3>R</h3>",
+		"<h3>T</h3>",
+		`<li><a href="#song-3">The Beatles</a> <span>Yesterday</span></li>`,
+		"</html>\n",
+	}
+	pos := 0
+	for _, w := range want {
+		j := strings.Index(got[pos:], w)
+		if j < 0 {
+			t.Fatalf("expected %q after position %d, got %q", w, pos, got)
+		}
+		pos += j + len(w)
+	}
+}
+
+func Test_indexLetter(t *testing.T) {
+	tests := []struct {
+		s    string
+		want string
+	}{
+		{"angie", "A"},
+		{"'round midnight", "R"},
+		{"99 luftballons", "#"},
+		{"", "#"},
+		{"été", "É"},
+	}
+	for _, tt := range tests {
+		if got := indexLetter(tt.s); got != tt.want {
+			t.Errorf("%q: expected %q, got %q", tt.s, tt.want, got)
+		}
+	}
+}
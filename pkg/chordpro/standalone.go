@@ -0,0 +1,199 @@
+package chordpro
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"sort"
+	"strings"
+)
+
+// DefaultTheme is the name of the default theme of the standalone html documents.
+const DefaultTheme = "light"
+
+// ErrUnknownTheme is returned when the requested theme doesn't exist.
+var ErrUnknownTheme = errors.New("unknown theme")
+
+func init() {
+	RegisterFormatter("standalone", func() Formatter {
+		return &StandaloneFormatter{Body: HtmlFormatter{}}
+	})
+}
+
+// baseCSS is the stylesheet shared by all the themes.
+// The colors and the sizes are set by the variables of each theme.
+const baseCSS = `body {
+  margin: 0 auto;
+  max-width: 50em;
+  padding: 1em;
+  background: var(--bg);
+  color: var(--fg);
+  font-family: var(--font);
+  font-size: var(--size);
+  line-height: 1.2;
+}
+.song-header h1 { margin-bottom: 0.2em; }
+.song-header p { margin: 0.2em 0; color: var(--muted); }
+.chord-sheet { margin: 1em 0 3em; }
+.chord-sheet > div, .chord-sheet > pre { margin: 0 0 1em; }
+.row { margin: 0.3em 0; }
+.column { display: inline-block; vertical-align: bottom; }
+.chord { display: block; min-height: 1.2em; color: var(--chord); font-weight: bold; text-decoration: none; }
+.lyrics { display: block; font-style: normal; white-space: pre; }
+.chorus { border-left: 3px solid var(--accent); padding-left: 0.8em; }
+.bridge { padding-left: 1em; }
+.chorusref { font-weight: bold; color: var(--accent); }
+.comment { font-family: var(--font); font-style: italic; color: var(--muted); white-space: pre-wrap; }
+.tablature { font-size: 0.9em; overflow-x: auto; }
+.error { color: #c00; }
+`
+
+// themes maps the theme names to their variables and rules.
+var themes = map[string]string{
+	"light": `:root {
+  --bg: #fff; --fg: #222; --muted: #666; --chord: #c33; --accent: #36c;
+  --font: Georgia, serif; --size: 16px;
+}
+`,
+	"dark": `:root {
+  --bg: #1e1e1e; --fg: #ddd; --muted: #999; --chord: #f9a34b; --accent: #6ab0f3;
+  --font: Georgia, serif; --size: 16px;
+}
+`,
+	"print": `:root {
+  --bg: #fff; --fg: #000; --muted: #333; --chord: #000; --accent: #000;
+  --font: "Times New Roman", serif; --size: 12pt;
+}
+body { max-width: none; padding: 0; }
+.chord-sheet > div, .chord-sheet > pre { break-inside: avoid; page-break-inside: avoid; }
+.chord-sheet { break-after: page; page-break-after: always; }
+@page { margin: 2cm; }
+`,
+	"large-print": `:root {
+  --bg: #fff; --fg: #000; --muted: #333; --chord: #b00; --accent: #000;
+  --font: Verdana, sans-serif; --size: 24px;
+}
+body { max-width: 60em; line-height: 1.4; }
+.chord { font-size: 1.1em; }
+`,
+}
+
+// ThemeNames returns the sorted names of the themes of the standalone html documents.
+func ThemeNames() []string {
+	names := make([]string, 0, len(themes))
+	for name := range themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ThemeCSS returns the stylesheet of the theme.
+// It returns ErrUnknownTheme if the theme doesn't exist.
+func ThemeCSS(theme string) (string, error) {
+	if theme == "" {
+		theme = DefaultTheme
+	}
+	css, ok := themes[strings.ToLower(theme)]
+	if !ok {
+		return "", ErrUnknownTheme
+	}
+	return css + baseCSS, nil
+}
+
+// appendHTMLHead function writes the beginning of an html document,
+// up to the opening body tag, with the given stylesheets.
+func appendHTMLHead(w io.Writer, title string, css ...string) {
+	fmt.Fprintln(w, "<!DOCTYPE html>")
+	fmt.Fprintln(w, `<html lang="en">`)
+	fmt.Fprintln(w, "<head>")
+	fmt.Fprintln(w, `<meta charset="utf-8">`)
+	fmt.Fprintln(w, `<meta name="viewport" content="width=device-width, initial-scale=1">`)
+	fmt.Fprintf(w, "<title>%s</title>\n", html.EscapeString(title))
+	for _, c := range css {
+		if c != "" {
+			fmt.Fprintf(w, "<style>\n%s\n</style>\n", strings.TrimRight(c, "\n"))
+		}
+	}
+	fmt.Fprintln(w, "</head>")
+	fmt.Fprintln(w, "<body>")
+}
+
+// StandaloneFormatter is the Formatter of the "standalone" format.
+// It writes a complete html document, with the stylesheet of the theme,
+// and each song with a header with title, artist, album and year,
+// followed by the song written by the Body formatter.
+// The header is omitted if the Body is a *TemplateFormatter,
+// whose "meta" template writes the metadata.
+type StandaloneFormatter struct {
+	Body  Formatter // formatter of the songs, like HtmlFormatter or *TemplateFormatter
+	Theme string    // name of the theme, DefaultTheme if empty
+	CSS   string    // custom stylesheet added after the one of the theme
+}
+
+// appendSongHeader function writes the header of the song
+// with title, subtitle, artist, album and year.
+func appendSongHeader(w io.Writer, s *Song) {
+	fmt.Fprintln(w, `<header class="song-header">`)
+	fmt.Fprintf(w, "<h1>%s</h1>\n", html.EscapeString(s.Title()))
+	if v := s.SubTitle(); v != "" {
+		fmt.Fprintf(w, "<p class=\"subtitle\">%s</p>\n", html.EscapeString(v))
+	}
+	if v := s.Artist(); v != "" {
+		fmt.Fprintf(w, "<p class=\"artist\">%s</p>\n", html.EscapeString(v))
+	}
+	album, year := s.Album(), s.Year()
+	switch {
+	case album != "" && year != "":
+		fmt.Fprintf(w, "<p class=\"album\">%s (%s)</p>\n", html.EscapeString(album), html.EscapeString(year))
+	case album != "":
+		fmt.Fprintf(w, "<p class=\"album\">%s</p>\n", html.EscapeString(album))
+	case year != "":
+		fmt.Fprintf(w, "<p class=\"year\">%s</p>\n", html.EscapeString(year))
+	}
+	fmt.Fprintln(w, `</header>`)
+}
+
+// FormatSong writes the song as a complete html document.
+func (f *StandaloneFormatter) FormatSong(w io.Writer, s *Song) error {
+	return f.FormatSongs(w, Songs{s})
+}
+
+// FormatSongs writes the songs as a complete html document.
+// The title of the document is the title of the first song.
+func (f *StandaloneFormatter) FormatSongs(w io.Writer, ss Songs) error {
+	css, err := ThemeCSS(f.Theme)
+	if err != nil {
+		return fmt.Errorf("%w: %q (valid themes: %s)", err, f.Theme, strings.Join(ThemeNames(), ", "))
+	}
+	body := f.Body
+	if body == nil {
+		body = HtmlFormatter{}
+	}
+	title := ""
+	if len(ss) > 0 {
+		title = ss[0].Title()
+	}
+
+	appendHTMLHead(w, title, css, f.CSS)
+	for _, s := range ss {
+		fmt.Fprintln(w, "<article>")
+		if _, ok := body.(*TemplateFormatter); !ok {
+			appendSongHeader(w, s)
+		}
+		if err := body.FormatSong(w, s); err != nil {
+			return err
+		}
+		fmt.Fprintln(w, "</article>")
+	}
+	fmt.Fprintln(w, "</body>")
+	_, err = fmt.Fprintln(w, "</html>")
+	return err
+}
+
+// Extension returns the ".html" extension.
+func (*StandaloneFormatter) Extension() string { return ".html" }
+
+// MimeType returns the "text/html" MIME type.
+func (*StandaloneFormatter) MimeType() string { return "text/html" }
@@ -0,0 +1,72 @@
+package chordpro
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStandaloneFormatter_FormatSong(t *testing.T) {
+	song := ParseText("{title: A <B>}\n{artist: Art}\n{album: Alb}\n{year: 1999}\n\n[G]la\n")[0]
+
+	tests := []struct {
+		name   string
+		f      *StandaloneFormatter
+		want   []string
+		absent []string
+	}{
+		{
+			name: "default",
+			f:    &StandaloneFormatter{},
+			want: []string{
+				"<!DOCTYPE html>",
+				"<title>A &lt;B&gt;</title>",
+				"--bg: #fff;",
+				`<p class="artist">Art</p>`,
+				`<p class="album">Alb (1999)</p>`,
+				`<div class="chord-sheet">`,
+				"</html>\n",
+			},
+		},
+		{
+			name: "dark with css",
+			f:    &StandaloneFormatter{Body: HtmlFormatter{}, Theme: "Dark", CSS: ".chord { color: red; }"},
+			want: []string{"--bg: #1e1e1e;", ".chord { color: red; }"},
+		},
+		{
+			name:   "template body",
+			f:      &StandaloneFormatter{Body: &TemplateFormatter{}},
+			want:   []string{`<h1 class="title">A &lt;B&gt;</h1>`},
+			absent: []string{`<header class="song-header">`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sb strings.Builder
+			if err := tt.f.FormatSong(&sb, song); err != nil {
+				t.Fatalf("unexpected error %q", err.Error())
+			}
+			got := sb.String()
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("expected %q in output, got %q", want, got)
+				}
+			}
+			for _, absent := range tt.absent {
+				if strings.Contains(got, absent) {
+					t.Errorf("expected no %q in output, got %q", absent, got)
+				}
+			}
+		})
+	}
+}
+
+func TestThemeCSS(t *testing.T) {
+	for _, name := range ThemeNames() {
+		if _, err := ThemeCSS(name); err != nil {
+			t.Errorf("%q: unexpected error %q", name, err.Error())
+		}
+	}
+	if _, err := ThemeCSS("unknown"); err != ErrUnknownTheme {
+		t.Errorf("expected %v, got %v", ErrUnknownTheme, err)
+	}
+}
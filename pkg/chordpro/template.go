@@ -0,0 +1,193 @@
+package chordpro
+
+import (
+	"html/template"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func init() {
+	RegisterFormatter("template", func() Formatter { return &TemplateFormatter{} })
+}
+
+// TemplateNames are the names of the templates used by the TemplateFormatter.
+// Each template can be replaced by a "<name>.html" file in the template folder.
+//   - "song" is executed with the *Song;
+//   - "meta" is executed with the *Song, to write its metadata;
+//   - "paragraph" is executed with each *Paragraph;
+//   - "line" is executed with each *Line;
+//   - "pair" is executed with each *ChordLyricPair.
+var TemplateNames = []string{"song", "meta", "paragraph", "line", "pair"}
+
+// defaultTemplates are the templates used when the template folder
+// doesn't contain a replacement.
+// They write the same markup of the "html" format, with the metadata.
+const defaultTemplates = `
+{{- define "song" -}}
+<div class="chord-sheet">
+{{template "meta" .}}
+{{- range .Paragraphs}}{{template "paragraph" .}}{{end}}
+{{- with .Err}}<div class="error">{{.}}</div>
+{{end -}}
+</div>
+{{end -}}
+
+{{- define "meta" -}}
+{{with .Title}}<h1 class="title">{{.}}</h1>
+{{end -}}
+{{with .SubTitle}}<h2 class="subtitle">{{.}}</h2>
+{{end -}}
+{{with .Artist}}<div class="artist">{{.}}</div>
+{{end -}}
+{{end -}}
+
+{{- define "paragraph" -}}
+{{- $class := class .}}
+{{- if or (eq $class "tablature") (eq $class "comment") -}}
+<pre class="{{$class}}">
+{{range .Lines}}{{range .Pairs}}{{.Lyric}}{{end}}
+{{end}}</pre>
+{{else if eq $class "chorusref" -}}
+<div class="chorusref">{{with .Label}}{{.}}{{else}}Chorus{{end}}</div>
+{{else if eq $class "custom" -}}
+{{node .}}
+{{- else -}}
+<div class="{{$class}}">
+{{with .Label}}<div class="label">{{.}}</div>
+{{end -}}
+{{range .Lines}}{{template "line" .}}{{end -}}
+</div>
+{{end -}}
+{{end -}}
+
+{{- define "line" -}}
+<div class="row">
+{{range .Pairs}}{{template "pair" .}}{{end}}
+</div>
+{{end -}}
+
+{{- define "pair" -}}
+<span class="column"><u class="chord">{{chord .Chord}}</u><i class="lyrics">{{nbsp .Lyric}}</i></span>
+{{- if wordEnd .Lyric}}
+{{end}}
+{{- end -}}
+`
+
+// TemplateFormatter is the Formatter of the "template" format.
+// The songs are written by html/template templates:
+// the default ones, or the ones found in the Dir folder.
+//
+// Besides the standard functions, the templates can use:
+//   - chord: the chord symbol without square brackets;
+//   - transpose: the chord transposed by the given semitones, like {{transpose 2 .Chord}};
+//   - class: the html class of a paragraph, like "verse" or "chorus";
+//   - node: the custom node of a paragraph, by the renderer of the "html" format;
+//   - nbsp: the text, or a non breaking space if it is blank;
+//   - wordEnd: true if the lyric ends a word, to separate the pairs of different words;
+//   - safe: the text as trusted html, not escaped;
+//   - escape: the html escaped text, not escaped again;
+//   - trim, lower, upper: the strings functions.
+type TemplateFormatter struct {
+	Dir string // folder of the user templates, or empty for the default ones
+
+	tmpl *template.Template
+}
+
+func (f *TemplateFormatter) funcs() template.FuncMap {
+	return template.FuncMap{
+		"chord": func(chord string) string {
+			return trimDelim(chord)
+		},
+		"transpose": func(semitones int, chord string) string {
+			return TransposeChord(chord, semitones)
+		},
+		"class": func(p *Paragraph) string {
+			return paragraphClass(p.ParagraphType)
+		},
+		"node": func(p *Paragraph) template.HTML {
+			return template.HTML(renderNode("html", p))
+		},
+		"nbsp": func(s string) template.HTML {
+			s = strings.TrimSpace(s)
+			if s == "" {
+				return template.HTML("&nbsp;")
+			}
+			return template.HTML(template.HTMLEscapeString(s))
+		},
+		"wordEnd": func(s string) bool {
+			return strings.TrimSpace(s) == "" || strings.HasSuffix(s, " ")
+		},
+		"safe": func(s string) template.HTML { return template.HTML(s) },
+		"escape": func(s string) template.HTML {
+			return template.HTML(template.HTMLEscapeString(s))
+		},
+		"trim":  strings.TrimSpace,
+		"lower": strings.ToLower,
+		"upper": strings.ToUpper,
+	}
+}
+
+// Load parses the templates.
+// It is called by FormatSong and FormatSongs, if not already done,
+// and it can be called before to check the user templates.
+func (f *TemplateFormatter) Load() error {
+	tmpl, err := template.New("").Funcs(f.funcs()).Parse(defaultTemplates)
+	if err != nil {
+		return err
+	}
+
+	if f.Dir != "" {
+		info, err := os.Stat(f.Dir)
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return &os.PathError{Op: "open", Path: f.Dir, Err: os.ErrInvalid}
+		}
+
+		for _, name := range TemplateNames {
+			data, err := ioutil.ReadFile(filepath.Join(f.Dir, name+".html"))
+			if os.IsNotExist(err) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if _, err = tmpl.New(name).Parse(string(data)); err != nil {
+				return err
+			}
+		}
+	}
+
+	f.tmpl = tmpl
+	return nil
+}
+
+// FormatSong writes the song executing the "song" template.
+func (f *TemplateFormatter) FormatSong(w io.Writer, s *Song) error {
+	return f.FormatSongs(w, Songs{s})
+}
+
+// FormatSongs writes the songs executing the "song" template for each song.
+func (f *TemplateFormatter) FormatSongs(w io.Writer, ss Songs) error {
+	if f.tmpl == nil {
+		if err := f.Load(); err != nil {
+			return err
+		}
+	}
+	for _, s := range ss {
+		if err := f.tmpl.ExecuteTemplate(w, "song", s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Extension returns the ".html" extension.
+func (*TemplateFormatter) Extension() string { return ".html" }
+
+// MimeType returns the "text/html" MIME type.
+func (*TemplateFormatter) MimeType() string { return "text/html" }
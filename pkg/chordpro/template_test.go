@@ -0,0 +1,92 @@
+package chordpro
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTemplateFormatter_FormatSong(t *testing.T) {
+	src := `{title: A & B}
+
+{start_of_chorus: Refrain}
+[G]Amazing [C]gra[D]ce
+{end_of_chorus}
+
+{chorus: Refrain}
+`
+	song := ParseText(src)[0]
+
+	dir, err := ioutil.TempDir("", "chordpro-template")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	err = ioutil.WriteFile(filepath.Join(dir, "pair.html"), []byte(`[{{chord .Chord}}|{{transpose -2 .Chord}}|{{.Lyric}}]`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(filepath.Join(dir, "meta.html"), []byte(`<h1>{{escape .Title}}</h1>`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		f    *TemplateFormatter
+		want []string
+	}{
+		{
+			name: "default",
+			f:    &TemplateFormatter{},
+			want: []string{
+				`<h1 class="title">A &amp; B</h1>`,
+				`<div class="chorus">`,
+				`<div class="label">Refrain</div>`,
+				"<span class=\"column\"><u class=\"chord\">G</u><i class=\"lyrics\">Amazing</i></span>\n<span",
+				`<u class="chord">C</u><i class="lyrics">gra</i></span><span`,
+				`<div class="chorusref">Refrain</div>`,
+			},
+		},
+		{
+			name: "user",
+			f:    &TemplateFormatter{Dir: dir},
+			want: []string{`<h1>A &amp; B</h1>`, `<div class="row">`, `[G|[F]|Amazing ]`, `[D|[C]|ce]`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sb strings.Builder
+			if err := tt.f.FormatSong(&sb, song); err != nil {
+				t.Fatalf("unexpected error %q", err.Error())
+			}
+			got := sb.String()
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("expected %q in output, got %q", want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestTemplateFormatter_Load(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chordpro-template")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	err = ioutil.WriteFile(filepath.Join(dir, "line.html"), []byte(`{{range .Pairs}`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := (&TemplateFormatter{Dir: dir}).Load(); err == nil {
+		t.Errorf("expected error for invalid template, got nil")
+	}
+	if err := (&TemplateFormatter{Dir: filepath.Join(dir, "missing")}).Load(); err == nil {
+		t.Errorf("expected error for missing folder, got nil")
+	}
+}
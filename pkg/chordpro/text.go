@@ -0,0 +1,296 @@
+package chordpro
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DefaultTextWidth is the default wrap width of the TextFormatter.
+const DefaultTextWidth = 80
+
+func init() {
+	RegisterFormatter("text", func() Formatter { return &TextFormatter{Width: DefaultTextWidth} })
+}
+
+// TextFormatter is the Formatter of the "text" format.
+// Each line is printed as two monospaced rows, with the chords
+// aligned above the corresponding lyrics.
+type TextFormatter struct {
+	// Width is the maximum number of columns of a row.
+	// Longer lines are wrapped. Zero or negative means no wrap.
+	Width int
+}
+
+// textRow is a chords row and the corresponding lyrics row.
+type textRow struct {
+	chords string
+	lyrics string
+}
+
+// textSegment is a part of a line that is never split on wrapping.
+type textSegment struct {
+	chord string
+	lyric string
+}
+
+func runeLen(s string) int {
+	return utf8.RuneCountInString(s)
+}
+
+// splitWords splits s after each sequence of spaces,
+// so that the concatenation of the result is s.
+func splitWords(s string) []string {
+	var words []string
+	start := 0
+	inSpace := false
+	for j, r := range s {
+		if r == ' ' {
+			inSpace = true
+		} else if inSpace {
+			words = append(words, s[start:j])
+			start = j
+			inSpace = false
+		}
+	}
+	return append(words, s[start:])
+}
+
+// textSegments splits the pairs of the line into word segments.
+// The chord of the pair is assigned to the first segment.
+func textSegments(lin *Line) []textSegment {
+	var segs []textSegment
+	for _, pair := range lin.Pairs {
+		for j, word := range splitWords(pair.Lyric) {
+			seg := textSegment{lyric: word}
+			if j == 0 {
+				seg.chord = trimDelim(pair.Chord)
+			}
+			segs = append(segs, seg)
+		}
+	}
+	return segs
+}
+
+// textLine function returns the rows of the line wrapped at the given width.
+func textLine(lin *Line, width int) []textRow {
+	var rows []textRow
+	var chords, lyrics strings.Builder
+	var hasChords, hasLyrics bool
+	col := 0
+
+	flush := func() {
+		row := textRow{}
+		if hasChords {
+			row.chords = strings.TrimRightFunc(chords.String(), unicode.IsSpace)
+		}
+		if hasLyrics {
+			row.lyrics = strings.TrimRightFunc(lyrics.String(), unicode.IsSpace)
+		}
+		rows = append(rows, row)
+		chords.Reset()
+		lyrics.Reset()
+		hasChords, hasLyrics = false, false
+		col = 0
+	}
+
+	segs := textSegments(lin)
+	for j, seg := range segs {
+		cw := runeLen(seg.chord)
+		if cw > 0 && j < len(segs)-1 {
+			// keep a space between consecutive chords
+			cw++
+		}
+		segWidth := func(lyric string) int {
+			if w := runeLen(lyric); w > cw {
+				return w
+			}
+			return cw
+		}
+
+		lyric := seg.lyric
+		w := segWidth(lyric)
+		if width > 0 && col > 0 && col+w > width {
+			flush()
+		}
+		if col == 0 {
+			// don't begin a row with spaces
+			lyric = strings.TrimLeft(lyric, " ")
+			w = segWidth(lyric)
+		}
+
+		// padding of the lyric: a dash if the word goes on in the next segment
+		padding := " "
+		if lyric != "" && !strings.HasSuffix(lyric, " ") && j < len(segs)-1 {
+			if next := segs[j+1].lyric; next != "" && next[0] != ' ' {
+				padding = "-"
+			}
+		}
+
+		chords.WriteString(seg.chord)
+		chords.WriteString(strings.Repeat(" ", w-runeLen(seg.chord)))
+		lyrics.WriteString(lyric)
+		lyrics.WriteString(strings.Repeat(padding, w-runeLen(lyric)))
+
+		hasChords = hasChords || seg.chord != ""
+		hasLyrics = hasLyrics || strings.TrimSpace(lyric) != ""
+		col += w
+	}
+	if col > 0 || len(rows) == 0 {
+		flush()
+	}
+	return rows
+}
+
+// isBlankLine function reports whether the line has neither chords nor lyrics.
+func isBlankLine(lin *Line) bool {
+	for _, pair := range lin.Pairs {
+		if pair.Chord != "" || strings.TrimSpace(pair.Lyric) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// isBlank function reports whether the paragraph has neither chords nor lyrics.
+func isBlank(p *Paragraph) bool {
+	for _, lin := range p.Lines {
+		if !isBlankLine(lin) {
+			return false
+		}
+	}
+	return true
+}
+
+// trimBlankLines function returns the lines without
+// the leading and trailing blank lines.
+func trimBlankLines(lines []*Line) []*Line {
+	for len(lines) > 0 && isBlankLine(lines[0]) {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && isBlankLine(lines[len(lines)-1]) {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// paragraphHeader function returns the section label of the paragraph.
+// It returns an empty string for unlabeled verses and for
+// paragraphs without section.
+func paragraphHeader(p *Paragraph) string {
+	switch p.ParagraphType {
+	case Chorus, Bridge:
+		if p.Label == "" {
+			return p.ParagraphType.String()
+		}
+	case Comment, ChorusRef:
+		return ""
+	}
+	return p.Label
+}
+
+func (f *TextFormatter) appendLine(sb *strings.Builder, lin *Line) {
+	for _, row := range textLine(lin, f.Width) {
+		if row.chords != "" {
+			fmt.Fprintln(sb, row.chords)
+		}
+		if row.lyrics != "" || row.chords == "" {
+			fmt.Fprintln(sb, row.lyrics)
+		}
+	}
+}
+
+func (f *TextFormatter) appendParagraph(sb *strings.Builder, p *Paragraph) {
+	if h := paragraphHeader(p); h != "" {
+		fmt.Fprintf(sb, "%s:\n", h)
+	}
+
+	switch p.ParagraphType {
+	case Tab:
+		for _, lin := range p.Lines {
+			for _, pair := range lin.Pairs {
+				sb.WriteString(pair.Lyric)
+			}
+			fmt.Fprintln(sb)
+		}
+	case Comment:
+		for _, lin := range p.Lines {
+			var txt strings.Builder
+			for _, pair := range lin.Pairs {
+				txt.WriteString(pair.Lyric)
+			}
+			fmt.Fprintf(sb, "(%s)\n", strings.TrimSpace(txt.String()))
+		}
+	case ChorusRef:
+		label := p.Label
+		if label == "" {
+			label = "Chorus"
+		}
+		fmt.Fprintf(sb, "(%s)\n", label)
+	case Custom:
+		sb.WriteString(renderNode("text", p))
+	default:
+		for _, lin := range trimBlankLines(p.Lines) {
+			f.appendLine(sb, lin)
+		}
+	}
+}
+
+func (f *TextFormatter) appendSong(sb *strings.Builder, s *Song) {
+	var header bool
+
+	if t := s.Title(); t != "" {
+		fmt.Fprintln(sb, t)
+		header = true
+	}
+	for _, st := range []string{s.SubTitle(), s.Artist()} {
+		if st != "" {
+			fmt.Fprintln(sb, st)
+			header = true
+		}
+	}
+
+	for _, p := range s.Paragraphs {
+		if p.ParagraphType != ChorusRef && isBlank(p) && renderNode("text", p) == "" {
+			continue
+		}
+		if header {
+			fmt.Fprintln(sb)
+		}
+		f.appendParagraph(sb, p)
+		header = true
+	}
+
+	if s.Err != nil {
+		fmt.Fprintf(sb, "\nerror: %s\n", s.Err.Error())
+	}
+}
+
+// FormatSong writes the song as plain text.
+func (f *TextFormatter) FormatSong(w io.Writer, s *Song) error {
+	var sb strings.Builder
+	f.appendSong(&sb, s)
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// FormatSongs writes the songs as plain text, separated by two empty lines.
+func (f *TextFormatter) FormatSongs(w io.Writer, ss Songs) error {
+	var sb strings.Builder
+	for j, s := range ss {
+		if j > 0 {
+			fmt.Fprint(&sb, "\n\n")
+		}
+		f.appendSong(&sb, s)
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// Extension returns the ".txt" extension.
+func (f *TextFormatter) Extension() string { return ".txt" }
+
+// MimeType returns the "text/plain" MIME type.
+func (f *TextFormatter) MimeType() string { return "text/plain" }
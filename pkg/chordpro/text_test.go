@@ -0,0 +1,96 @@
+package chordpro
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_textLine(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		width int
+		want  []textRow
+	}{
+		{
+			name:  "aligned",
+			input: "[C]Hello [G]world",
+			want:  []textRow{{"C     G", "Hello world"}},
+		},
+		{
+			name:  "wide chord in word",
+			input: "Be[C#m7]yond",
+			want:  []textRow{{"  C#m7", "Beyond"}},
+		},
+		{
+			name:  "wide chord in word with padding",
+			input: "[C]Be[C#m7]y[D]ond",
+			want:  []textRow{{"C C#m7 D", "Bey----ond"}},
+		},
+		{
+			name:  "lyrics only",
+			input: "no chords here",
+			want:  []textRow{{"", "no chords here"}},
+		},
+		{
+			name:  "chords only",
+			input: "[Am] [G]",
+			want:  []textRow{{"Am G", ""}},
+		},
+		{
+			name:  "wrap",
+			input: "[C]one two [G]three four",
+			width: 10,
+			want:  []textRow{{"C", "one two"}, {"G", "three four"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lin := ParseText(tt.input)[0].Paragraphs[0].Lines[0]
+			got := textLine(lin, tt.width)
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected %q, got %q", tt.want, got)
+			}
+			for j := range got {
+				if got[j] != tt.want[j] {
+					t.Errorf("row #%d: expected %q, got %q", j, tt.want[j], got[j])
+				}
+			}
+		})
+	}
+}
+
+func TestTextFormatter_FormatSong(t *testing.T) {
+	src := `{t:Come Together}
+{st:Beatles}
+{soc}
+[Dm]Here come old flat top
+{eoc}
+{c:Play riff}
+{sot}
+e|---0---|
+{eot}
+{chorus}`
+
+	want := `Come Together
+Beatles
+
+Chorus:
+Dm
+Here come old flat top
+
+(Play riff)
+
+e|---0---|
+
+(Chorus)
+`
+	var sb strings.Builder
+	f := &TextFormatter{Width: DefaultTextWidth}
+	if err := f.FormatSong(&sb, ParseText(src)[0]); err != nil {
+		t.Fatalf("unexpected error %q", err.Error())
+	}
+	if got := sb.String(); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}